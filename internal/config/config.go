@@ -15,6 +15,10 @@ type Config struct {
 	Server struct {
 		Port int    `mapstructure:"port"`
 		Host string `mapstructure:"host"`
+		// 客户端IP (登录防爆破、审计日志) 的来源：只信任这些代理转发的 X-Forwarded-For / X-Real-IP，为空时直接使用连接的对端地址
+		TrustedProxies []string `mapstructure:"trusted_proxies"`
+		// 由平台或反向代理覆盖写入的客户端IP请求头 (如 X-Real-IP、CF-Connecting-IP)，设置后优先使用且不校验来源，为空时不使用
+		TrustedPlatform string `mapstructure:"trusted_platform"`
 	} `mapstructure:"server"`
 
 	// 数据库设置
@@ -37,11 +41,23 @@ type Config struct {
 
 	// JWT 配置
 	JWT struct {
-		Secret          string        `mapstructure:"secret"`             // JWT 密钥
-		AdminExpiresIn  time.Duration `mapstructure:"admin_expires_in"`   // Token 有效期
-		SignInExpiresIn time.Duration `mapstructure:"sign_in_expires_in"` // Token 有效期
+		Secret           string        `mapstructure:"secret"`             // JWT 密钥
+		AdminExpiresIn   time.Duration `mapstructure:"admin_expires_in"`   // 访问 Token 有效期
+		RefreshExpiresIn time.Duration `mapstructure:"refresh_expires_in"` // 刷新 Token 有效期
+		SignInExpiresIn  time.Duration `mapstructure:"sign_in_expires_in"` // Token 有效期
 	} `mapstructure:"jwt"`
 
+	// 登录防爆破配置
+	LoginProtection struct {
+		UserMaxFailures int           `mapstructure:"user_max_failures"` // 同一用户名在同一 IP 连续失败多少次后开始锁定
+		IPMaxFailures   int           `mapstructure:"ip_max_failures"`   // 同一 IP 连续失败多少次后开始锁定
+		FailureWindow   time.Duration `mapstructure:"failure_window"`    // 失败计数的保留时长 (期间无新失败则清零)
+		BaseLockout     time.Duration `mapstructure:"base_lockout"`      // 首次锁定时长，之后每多失败一次翻倍
+		MaxLockout      time.Duration `mapstructure:"max_lockout"`       // 最长锁定时长
+		RateLimit       float64       `mapstructure:"rate_limit"`        // 登录接口全局限流：每秒请求数
+		RateBurst       int           `mapstructure:"rate_burst"`        // 登录接口全局限流：突发请求数
+	} `mapstructure:"login_protection"`
+
 	LogFile string `mapstructure:"log_file"`
 
 	Admin struct {
@@ -54,9 +70,79 @@ type Config struct {
 		AllowMethods []string `mapstructure:"allow_methods"`
 	} `mapstructure:"cors"`
 
+	// 签到动态码配置 (刷新周期使用 jwt.sign_in_expires_in)
+	SignIn struct {
+		CodeSkew      int    `mapstructure:"code_skew"`       // 允许的时钟偏差窗口数
+		QRBaseURL     string `mapstructure:"qr_base_url"`     // 二维码中签到页面的地址
+		MaxFailures   int    `mapstructure:"max_failures"`    // 同一活动同一手机号签到/签退动态码连续错误多少次后锁定
+		IPMaxFailures int    `mapstructure:"ip_max_failures"` // 同一 IP 签到/签退动态码连续错误多少次后锁定
+	} `mapstructure:"sign_in"`
+
+	// 签退与考勤配置
+	Attendance struct {
+		LateThreshold       time.Duration `mapstructure:"late_threshold"`        // 活动开始后多久签到算迟到
+		EarlyLeaveThreshold time.Duration `mapstructure:"early_leave_threshold"` // 活动结束前多久签退算早退
+		SignOutGrace        time.Duration `mapstructure:"sign_out_grace"`        // 活动结束后仍可签退的时长
+		SignOutQRBaseURL    string        `mapstructure:"sign_out_qr_base_url"`  // 二维码中签退页面的地址
+	} `mapstructure:"attendance"`
+
+	// 参与证明模板，未配置的项使用默认值
+	Certificate struct {
+		Title     string `mapstructure:"title"`      // 证明标题
+		Body      string `mapstructure:"body"`       // 正文模板 (text/template)，可使用的字段见 service/certificate_service.go
+		Issuer    string `mapstructure:"issuer"`     // 落款单位
+		VerifyURL string `mapstructure:"verify_url"` // 证明上印的查验页面地址
+	} `mapstructure:"certificate"`
+
+	// 通知配置：报名、候补递补、活动变更和取消时通知报名者 (目前只有邮件渠道)
+	Notification struct {
+		Enabled      bool          `mapstructure:"enabled"`       // 是否启用，未启用时不生成通知
+		PollInterval time.Duration `mapstructure:"poll_interval"` // 发送任务检查队列的间隔
+		BatchSize    int           `mapstructure:"batch_size"`    // 每次最多领取的通知数
+		MaxAttempts  int           `mapstructure:"max_attempts"`  // 最多尝试发送的次数，用尽后标记为失败
+		RetryBackoff time.Duration `mapstructure:"retry_backoff"` // 首次重试的等待时间，之后每次翻倍 (最长 1 小时)
+
+		// SMTP 服务器，host 为空时使用进程内的 SMTP 替身 (邮件只写入日志，用于本地开发)
+		SMTP struct {
+			Host        string `mapstructure:"host"`
+			Port        int    `mapstructure:"port"`
+			Username    string `mapstructure:"username"`
+			Password    string `mapstructure:"password"`
+			From        string `mapstructure:"from"`         // 发件人，如 "活动报名系统 <noreply@example.com>"
+			ImplicitTLS bool   `mapstructure:"implicit_tls"` // 465 端口等直接使用 TLS 的服务器设为 true
+		} `mapstructure:"smtp"`
+
+		// 各事件的消息模板 (text/template)，键为 registered、promoted、activity_updated、activity_cancelled，
+		// 未配置的事件使用默认模板，可使用的字段见 service/notification_template.go
+		Templates map[string]NotificationTemplate `mapstructure:"templates"`
+	} `mapstructure:"notification"`
+
+	// 报名相关配置
+	Registration struct {
+		CancelCutoff time.Duration `mapstructure:"cancel_cutoff"` // 活动开始前多久停止自助取消
+	} `mapstructure:"registration"`
+
+	// 快速报名配置 (活动开启 fast_registration 后生效)
+	FastRegistration struct {
+		BatchSize         int           `mapstructure:"batch_size"`         // 每批最多落库的预占记录数
+		PollTimeout       time.Duration `mapstructure:"poll_timeout"`       // 落库任务等待队列的最长时间
+		ReconcileInterval time.Duration `mapstructure:"reconcile_interval"` // Redis 名额与数据库人数的校对间隔
+	} `mapstructure:"fast_registration"`
+
+	// 幂等键配置 (报名、签到接口的 Idempotency-Key 请求头)
+	Idempotency struct {
+		TTL time.Duration `mapstructure:"ttl"` // 首次响应的保留时长，窗口期内相同请求直接重放
+	} `mapstructure:"idempotency"`
+
 	ActivityStatusUpdateInterval time.Duration `mapstructure:"activity_status_update_interval"`
 }
 
+// NotificationTemplate 通知的标题和正文模板
+type NotificationTemplate struct {
+	Subject string `mapstructure:"subject"`
+	Body    string `mapstructure:"body"`
+}
+
 // GlobalConfig 是程序的全局配置实例
 var GlobalConfig Config
 
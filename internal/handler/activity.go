@@ -1,17 +1,18 @@
 package handler
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"log/slog"
+	"math"
 	"net/http"
 	"strconv"
-	"time" // 引入 time 以便使用 ActivityResponse 结构体
+	"time"
 
 	// 注意：这里需要替换为你项目的实际导入路径
-	"github.com/frozenf1sh/gostudent/internal/config"
 	"github.com/frozenf1sh/gostudent/internal/model"
 	"github.com/frozenf1sh/gostudent/internal/service"
-	"github.com/frozenf1sh/gostudent/pkg/redis"
 	"github.com/frozenf1sh/gostudent/pkg/utils"
 	"github.com/gin-gonic/gin"
 )
@@ -21,10 +22,34 @@ type ActivityHandler interface {
 	CreateActivity(c *gin.Context)
 	ListActivities(c *gin.Context)
 	GetActivityByID(c *gin.Context)
+	// 公开接口：只展示已发布的活动，且不包含内部字段
+	ListPublicActivities(c *gin.Context)
+	GetPublicActivityByID(c *gin.Context)
 	UpdateActivity(c *gin.Context)
 	DeleteActivity(c *gin.Context)
 	PublishActivity(c *gin.Context)
+	// 状态流转
+	CloseRegistration(c *gin.Context)
+	ReopenRegistration(c *gin.Context)
+	CancelActivity(c *gin.Context)
 	GetSignInToken(c *gin.Context)
+	GetSignOutToken(c *gin.Context)
+	RotateDisplayKey(c *gin.Context)
+	// 协办组织者
+	SetCoOrganizers(c *gin.Context)
+	ListCoOrganizers(c *gin.Context)
+	// 报名表自定义字段
+	GetRegistrationForm(c *gin.Context)
+	UpdateRegistrationForm(c *gin.Context)
+	// 报名资格规则与报名名单
+	GetEligibility(c *gin.Context)
+	UpdateEligibility(c *gin.Context)
+	UploadAllowlist(c *gin.Context)
+	// 抽签开奖记录
+	GetLotteryDraw(c *gin.Context)
+	// 定时发布
+	ListScheduledPublishes(c *gin.Context)
+	CancelScheduledPublish(c *gin.Context)
 }
 
 type activityHandlerImpl struct {
@@ -65,16 +90,83 @@ func toActivityResponse(activity *model.Activity) model.ActivityResponse {
 		StartTime:            activity.StartTime,
 		EndTime:              activity.EndTime,
 		Location:             activity.Location,
+		RegistrationOpensAt:  activity.RegistrationOpensAt,
 		RegistrationDeadline: activity.RegistrationDeadline,
+		RegistrationState:    toRegistrationState(activity, time.Now()),
 		MaxParticipants:      activity.MaxParticipants,
 		RegisteredCount:      activity.RegisteredCount,
+		FastRegistration:     activity.FastRegistration,
+		RequiresApproval:     activity.RequiresApproval,
+		Lottery:              activity.Lottery,
+		LotteryWeights:       activity.LotteryWeights,
+		LotteryDrawnAt:       activity.LotteryDrawnAt,
+		CreditType:           activity.CreditType,
+		CreditValue:          activity.CreditValue,
+		CreditMinMinutes:     activity.CreditMinMinutes,
+		CreditsGrantedAt:     activity.CreditsGrantedAt,
 		Status:               activity.Status,
+		PublishAt:            activity.PublishAt,
+		PublishError:         activity.PublishError,
+		CancelReason:         activity.CancelReason,
+		CancelledAt:          activity.CancelledAt,
 		LiveURL:              activity.LiveURL,
 		AttachmentURL:        activity.AttachmentURL,
+		FormSchema:           activity.FormSchema,
+		Eligibility:          activity.Eligibility,
 		CreatedAt:            activity.CreatedAt,
 	}
 }
 
+// toPublicActivityResponse 将 model.Activity 转换为公开接口使用的 DTO
+func toPublicActivityResponse(activity *model.Activity) model.PublicActivityResponse {
+	return model.PublicActivityResponse{
+		ID:                   activity.ID,
+		Title:                activity.Title,
+		Type:                 activity.Type,
+		Description:          activity.Description,
+		StartTime:            activity.StartTime,
+		EndTime:              activity.EndTime,
+		Location:             activity.Location,
+		RegistrationOpensAt:  activity.RegistrationOpensAt,
+		RegistrationDeadline: activity.RegistrationDeadline,
+		RegistrationState:    toRegistrationState(activity, time.Now()),
+		MaxParticipants:      activity.MaxParticipants,
+		RegisteredCount:      activity.RegisteredCount,
+		RequiresApproval:     activity.RequiresApproval,
+		Lottery:              activity.Lottery,
+		LotteryDrawnAt:       activity.LotteryDrawnAt,
+		CreditType:           activity.CreditType,
+		CreditValue:          activity.CreditValue,
+		CreditMinMinutes:     activity.CreditMinMinutes,
+		Status:               activity.Status,
+		LiveURL:              activity.LiveURL,
+		AttachmentURL:        activity.AttachmentURL,
+		FormSchema:           activity.FormSchema,
+		Eligibility:          activity.Eligibility,
+	}
+}
+
+// toRegistrationState 计算活动当前的报名阶段和倒计时 (秒数向上取整)
+func toRegistrationState(activity *model.Activity, now time.Time) model.RegistrationStateResponse {
+	state := model.RegistrationStateResponse{State: activity.RegistrationStateAt(now)}
+	switch state.State {
+	case model.RegistrationStateUpcoming:
+		state.OpensInSeconds = int64(math.Ceil(activity.RegistrationOpensAt.Sub(now).Seconds()))
+	case model.RegistrationStateOpen:
+		state.ClosesInSeconds = int64(math.Ceil(activity.RegistrationDeadline.Sub(now).Seconds()))
+	}
+	return state
+}
+
+// toAdminBriefList 将管理员列表转换为简要信息 DTO
+func toAdminBriefList(admins []*model.Admin) []model.AdminBriefResponse {
+	list := make([]model.AdminBriefResponse, len(admins))
+	for i, admin := range admins {
+		list[i] = model.AdminBriefResponse{ID: admin.ID, Username: admin.Username, Role: admin.Role}
+	}
+	return list
+}
+
 // toActivityResponseList 将 []*model.Activity 转换为 []model.ActivityResponse DTO 列表
 func toActivityResponseList(activities []*model.Activity) []model.ActivityResponse {
 	list := make([]model.ActivityResponse, len(activities))
@@ -108,7 +200,19 @@ func (h *activityHandlerImpl) CreateActivity(c *gin.Context) {
 	activity, err := h.svc.CreateActivity(c, adminID, &req)
 	if err != nil {
 		slog.Error("Failed to create activity", "admin_id", adminID, "error", err)
-		utils.Error(c, http.StatusInternalServerError, "创建活动失败: "+err.Error())
+		if errors.Is(err, service.ErrLotteryWithApproval) {
+			utils.Error(c, http.StatusBadRequest, "抽签报名不能与报名审核同时开启")
+		} else if errors.Is(err, service.ErrInvalidLotteryWeights) {
+			utils.Error(c, http.StatusBadRequest, "抽签学院权重必须大于 0 且不超过 100")
+		} else if errors.Is(err, service.ErrInvalidRegistrationOpen) {
+			utils.Error(c, http.StatusBadRequest, "报名开放时间必须早于报名截止时间")
+		} else if errors.Is(err, service.ErrInvalidCreditSettings) {
+			utils.Error(c, http.StatusBadRequest, "学分设置无效：学分数需在 0 到 1000 之间，发放学分时须指定有效的学分类型，最低参与时长不能为负数")
+		} else if errors.Is(err, service.ErrInvalidPublishAt) {
+			utils.Error(c, http.StatusBadRequest, "定时发布时间必须晚于当前时间且早于报名截止时间")
+		} else {
+			utils.Error(c, http.StatusInternalServerError, "创建活动失败: "+err.Error())
+		}
 		return
 	}
 
@@ -116,7 +220,73 @@ func (h *activityHandlerImpl) CreateActivity(c *gin.Context) {
 	utils.Success(c, toActivityResponse(activity))
 }
 
-// ListActivities 获取活动列表 (Admin 或 Public 接口)
+// ListPublicActivities 获取公开活动列表 (Public 接口)
+// @Summary 获取公开活动列表
+// @Description 只返回已发布、已截止报名和已结束的活动
+// @Tags Activity
+// @Produce json
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页大小" default(10)
+// @Param title query string false "活动名称关键词过滤"
+// @Param status query string false "活动状态过滤"
+// @Success 200 {object} gin.H{list=[]model.PublicActivityResponse,total=int} "活动列表和总数"
+// @Router /activities [get]
+func (h *activityHandlerImpl) ListPublicActivities(c *gin.Context) {
+	var params model.ListActivitiesParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		utils.Error(c, http.StatusBadRequest, "查询参数格式错误: "+err.Error())
+		return
+	}
+
+	list, total, err := h.svc.ListPublicActivities(c, &params)
+	if err != nil {
+		slog.Error("Failed to list public activities", "error", err, "params", params)
+		utils.Error(c, http.StatusInternalServerError, "查询活动列表失败: "+err.Error())
+		return
+	}
+
+	responseList := make([]model.PublicActivityResponse, len(list))
+	for i, activity := range list {
+		responseList[i] = toPublicActivityResponse(activity)
+	}
+	utils.Success(c, gin.H{
+		"list":  responseList,
+		"total": total,
+		"page":  params.Page,
+	})
+}
+
+// GetPublicActivityByID 获取公开活动详情 (Public 接口)
+// @Summary 获取公开活动详情
+// @Description 草稿和已取消的活动返回 404
+// @Tags Activity
+// @Produce json
+// @Param activity_id path int true "活动ID"
+// @Success 200 {object} model.PublicActivityResponse "活动详情"
+// @Failure 404 {object} gin.H "活动不存在"
+// @Router /activities/{activity_id} [get]
+func (h *activityHandlerImpl) GetPublicActivityByID(c *gin.Context) {
+	activityID, err := strconv.ParseUint(c.Param("activity_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "活动ID格式错误")
+		return
+	}
+
+	activity, err := h.svc.GetPublicActivityByID(c, uint(activityID))
+	if err != nil {
+		if errors.Is(err, service.ErrActivityNotFound) {
+			utils.Error(c, http.StatusNotFound, "活动不存在")
+		} else {
+			slog.Error("Failed to get public activity", "id", activityID, "error", err)
+			utils.Error(c, http.StatusInternalServerError, "查询活动失败: "+err.Error())
+		}
+		return
+	}
+
+	utils.Success(c, toPublicActivityResponse(activity))
+}
+
+// ListActivities 获取活动列表 (Admin 接口)
 // @Summary 获取活动列表
 // @Tags Activity
 // @Produce json
@@ -125,7 +295,7 @@ func (h *activityHandlerImpl) CreateActivity(c *gin.Context) {
 // @Param title query string false "活动名称关键词过滤"
 // @Param status query string false "活动状态过滤"
 // @Success 200 {object} gin.H{list=[]model.ActivityResponse,total=int} "活动列表和总数" // 修正 Swagger
-// @Router /activities [get]
+// @Router /admin/activities [get]
 func (h *activityHandlerImpl) ListActivities(c *gin.Context) {
 	var params model.ListActivitiesParams
 
@@ -154,14 +324,14 @@ func (h *activityHandlerImpl) ListActivities(c *gin.Context) {
 	})
 }
 
-// GetActivityByID 获取活动详情 (Admin & Public)
+// GetActivityByID 获取活动详情 (Admin 接口)
 // @Summary 获取活动详情
 // @Tags Activity
 // @Produce json
 // @Param activity_id path int true "活动ID"
 // @Success 200 {object} model.ActivityResponse "活动详情" // 修正 Swagger
 // @Failure 404 {object} gin.H "活动不存在"
-// @Router /activities/{activity_id} [get]
+// @Router /admin/activities/{activity_id} [get]
 func (h *activityHandlerImpl) GetActivityByID(c *gin.Context) {
 	activityIDStr := c.Param("activity_id")
 	activityID, err := strconv.ParseUint(activityIDStr, 10, 64)
@@ -215,7 +385,25 @@ func (h *activityHandlerImpl) UpdateActivity(c *gin.Context) {
 		// 检查特定的业务错误
 		if errors.Is(err, service.ErrActivityNotFound) {
 			utils.Error(c, http.StatusNotFound, "活动不存在")
-		} else {
+		} else if errors.Is(err, service.ErrFastRegistrationLocked) {
+			utils.Error(c, http.StatusConflict, "活动发布后不能修改快速报名设置")
+		} else if errors.Is(err, service.ErrApprovalSettingLocked) {
+			utils.Error(c, http.StatusConflict, "活动发布后不能修改报名审核设置")
+		} else if errors.Is(err, service.ErrLotterySettingLocked) {
+			utils.Error(c, http.StatusConflict, "活动发布后不能修改抽签设置")
+		} else if errors.Is(err, service.ErrLotteryWithApproval) {
+			utils.Error(c, http.StatusBadRequest, "抽签报名不能与报名审核同时开启")
+		} else if errors.Is(err, service.ErrInvalidLotteryWeights) {
+			utils.Error(c, http.StatusBadRequest, "抽签学院权重必须大于 0 且不超过 100")
+		} else if errors.Is(err, service.ErrInvalidRegistrationOpen) {
+			utils.Error(c, http.StatusBadRequest, "报名开放时间必须早于报名截止时间")
+		} else if errors.Is(err, service.ErrInvalidCreditSettings) {
+			utils.Error(c, http.StatusBadRequest, "学分设置无效：学分数需在 0 到 1000 之间，发放学分时须指定有效的学分类型，最低参与时长不能为负数")
+		} else if errors.Is(err, service.ErrInvalidPublishAt) {
+			utils.Error(c, http.StatusBadRequest, "定时发布时间必须晚于当前时间且早于报名截止时间")
+		} else if errors.Is(err, service.ErrPublishScheduleLocked) {
+			utils.Error(c, http.StatusConflict, "只有草稿活动可以设置定时发布")
+		} else if !writeTransitionError(c, err) {
 			utils.Error(c, http.StatusInternalServerError, "更新活动失败: "+err.Error())
 		}
 		return
@@ -280,7 +468,11 @@ func (h *activityHandlerImpl) PublishActivity(c *gin.Context) {
 	err = h.svc.PublishActivity(c, uint(activityID))
 	if err != nil {
 		slog.Error("Failed to publish activity", "id", activityID, "error", err)
-		utils.Error(c, http.StatusInternalServerError, "发布活动失败: "+err.Error())
+		if errors.Is(err, service.ErrActivityNotFound) {
+			utils.Error(c, http.StatusNotFound, "活动不存在")
+		} else if !writeTransitionError(c, err) {
+			utils.Error(c, http.StatusInternalServerError, "发布活动失败: "+err.Error())
+		}
 		return
 	}
 
@@ -296,19 +488,305 @@ func (h *activityHandlerImpl) PublishActivity(c *gin.Context) {
 	utils.Success(c, toActivityResponse(publishedActivity))
 }
 
+// writeTransitionError 将状态流转错误转换为 409 响应，非流转错误返回 false
+func writeTransitionError(c *gin.Context, err error) bool {
+	var transitionErr *service.ActivityTransitionError
+	if !errors.As(err, &transitionErr) {
+		return false
+	}
+
+	var msg string
+	switch {
+	case errors.Is(err, service.ErrActivityAlreadyPublished):
+		msg = "活动已发布或已结束"
+	case errors.Is(err, service.ErrActivityIsRunning):
+		msg = "活动已开始，无法开放报名"
+	case errors.Is(err, service.ErrActivityRegistrationOver):
+		msg = "报名截止时间已过，请先修改报名截止时间"
+	case errors.Is(err, service.ErrCancelReasonRequired):
+		msg = "取消活动需要填写原因，请使用取消接口"
+	case errors.Is(err, service.ErrIllegalTransition):
+		msg = fmt.Sprintf("活动状态不允许从 %s 变更为 %s", transitionErr.From, transitionErr.To)
+	default:
+		msg = fmt.Sprintf("活动状态无法从 %s 变更为 %s: %v", transitionErr.From, transitionErr.To, transitionErr.Err)
+	}
+	utils.Error(c, http.StatusConflict, msg)
+	return true
+}
+
+// changeActivityStatus 状态流转接口的公共处理：解析ID、调用流转、返回最新活动详情
+func (h *activityHandlerImpl) changeActivityStatus(c *gin.Context, action string, transition func(id uint) error) {
+	activityID, err := strconv.ParseUint(c.Param("activity_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "活动ID格式错误")
+		return
+	}
+
+	if err := transition(uint(activityID)); err != nil {
+		slog.Error("Failed to change activity status", "id", activityID, "action", action, "error", err)
+		if errors.Is(err, service.ErrActivityNotFound) {
+			utils.Error(c, http.StatusNotFound, "活动不存在")
+		} else if !writeTransitionError(c, err) {
+			utils.Error(c, http.StatusInternalServerError, action+"失败: "+err.Error())
+		}
+		return
+	}
+
+	activity, err := h.svc.GetActivityByID(c, uint(activityID))
+	if err != nil {
+		utils.Success(c, gin.H{"message": action + "成功，但查询最新详情失败"})
+		return
+	}
+	utils.Success(c, toActivityResponse(activity))
+}
+
+// CloseRegistration godoc
+// @Summary 提前截止报名
+// @Description 将报名中 (PUBLISHED) 的活动变为已截止报名 (CLOSED)
+// @Tags Activity
+// @Produce json
+// @Param activity_id path int true "活动ID"
+// @Success 200 {object} model.ActivityResponse "最新活动详情"
+// @Failure 404 {object} gin.H "活动不存在"
+// @Failure 409 {object} gin.H "当前状态不允许该操作"
+// @Router /admin/activities/{activity_id}/close [post]
+func (h *activityHandlerImpl) CloseRegistration(c *gin.Context) {
+	h.changeActivityStatus(c, "截止报名", func(id uint) error {
+		return h.svc.CloseRegistration(c, id)
+	})
+}
+
+// ReopenRegistration godoc
+// @Summary 重新开放报名
+// @Description 将已截止报名 (CLOSED) 的活动重新开放 (PUBLISHED)，要求活动未开始且报名截止时间未过
+// @Tags Activity
+// @Produce json
+// @Param activity_id path int true "活动ID"
+// @Success 200 {object} model.ActivityResponse "最新活动详情"
+// @Failure 404 {object} gin.H "活动不存在"
+// @Failure 409 {object} gin.H "当前状态不允许该操作"
+// @Router /admin/activities/{activity_id}/reopen [post]
+func (h *activityHandlerImpl) ReopenRegistration(c *gin.Context) {
+	h.changeActivityStatus(c, "重新开放报名", func(id uint) error {
+		return h.svc.ReopenRegistration(c, id)
+	})
+}
+
+// CancelActivity godoc
+// @Summary 取消活动
+// @Description 取消未结束的活动并记录原因，取消后不能再报名和签到，也不能再修改
+// @Tags Activity
+// @Accept json
+// @Produce json
+// @Param activity_id path int true "活动ID"
+// @Param request body model.CancelActivityRequest true "取消原因"
+// @Success 200 {object} model.ActivityResponse "最新活动详情"
+// @Failure 404 {object} gin.H "活动不存在"
+// @Failure 409 {object} gin.H "当前状态不允许该操作"
+// @Router /admin/activities/{activity_id}/cancel [post]
+func (h *activityHandlerImpl) CancelActivity(c *gin.Context) {
+	var req model.CancelActivityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "请求参数错误: "+err.Error())
+		return
+	}
+	h.changeActivityStatus(c, "取消活动", func(id uint) error {
+		return h.svc.CancelActivity(c, id, req.Reason)
+	})
+}
+
 // GetSignInToken godoc
-// @Summary 生成活动签到Token
-// @Description 为特定活动生成临时签到Token（有效时间30秒，活动期间可获取）
+// @Summary 获取活动签到动态码
+// @Description 返回当前时间窗口的签到动态码及二维码内容，供大屏展示（活动期间可获取）。
+// @Description 公开接口需通过 display_key 参数提供活动的大屏展示密钥，管理员接口无需提供。
 // @Tags Activity
 // @Produce json
 // @Param activity_id path int true "活动ID"
-// @Success 200 {object} gin.H "生成成功，返回签到Token"
+// @Param display_key query string false "大屏展示密钥 (公开接口必填)"
+// @Success 200 {object} model.SignInCodeResponse "当前签到动态码"
 // @Failure 400 {object} gin.H "活动ID格式错误"
+// @Failure 401 {object} gin.H "展示密钥无效"
 // @Failure 404 {object} gin.H "活动不存在"
-// @Failure 403 {object} gin.H "不在活动时间范围内，无法获取签到Token"
-// @Failure 500 {object} gin.H "生成Token失败"
-// @Router /api/v1/activities/{activity_id}/signin-token [get]
+// @Failure 403 {object} gin.H "不在活动时间范围内，无法获取签到动态码"
+// @Failure 500 {object} gin.H "生成动态码失败"
+// @Router /activities/{activity_id}/signin-token [get]
+// @Router /admin/activities/{activity_id}/signin-token [get]
 func (h *activityHandlerImpl) GetSignInToken(c *gin.Context) {
+	h.writeAttendanceCode(c, h.svc.GetSignInCode, service.ErrSignInNotAvailable, "当前时间不在活动时间范围内，无法获取签到动态码")
+}
+
+// GetSignOutToken godoc
+// @Summary 获取活动签退动态码
+// @Description 返回当前时间窗口的签退动态码及二维码内容，供大屏展示（活动开始后至结束后 attendance.sign_out_grace 内可获取）。
+// @Description 公开接口需通过 display_key 参数提供活动的大屏展示密钥，管理员接口无需提供。
+// @Tags Activity
+// @Produce json
+// @Param activity_id path int true "活动ID"
+// @Param display_key query string false "大屏展示密钥 (公开接口必填)"
+// @Success 200 {object} model.SignInCodeResponse "当前签退动态码"
+// @Failure 400 {object} gin.H "活动ID格式错误"
+// @Failure 401 {object} gin.H "展示密钥无效"
+// @Failure 404 {object} gin.H "活动不存在"
+// @Failure 403 {object} gin.H "不在可签退的时间范围内"
+// @Failure 500 {object} gin.H "生成动态码失败"
+// @Router /activities/{activity_id}/signout-token [get]
+// @Router /admin/activities/{activity_id}/signout-token [get]
+func (h *activityHandlerImpl) GetSignOutToken(c *gin.Context) {
+	h.writeAttendanceCode(c, h.svc.GetSignOutCode, service.ErrSignOutNotAvailable, "当前时间不在可签退的时间范围内，无法获取签退动态码")
+}
+
+// writeAttendanceCode 校验展示密钥后返回签到或签退动态码
+func (h *activityHandlerImpl) writeAttendanceCode(c *gin.Context, getCode func(ctx context.Context, id uint) (*model.SignInCodeResponse, error), unavailable error, unavailableMsg string) {
+	activityIDStr := c.Param("activity_id")
+	activityID, err := strconv.ParseUint(activityIDStr, 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "活动ID格式错误")
+		return
+	}
+
+	// 1. 未经过管理员认证的请求必须携带大屏展示密钥
+	if _, err := getAdminIDFromContext(c); err != nil {
+		if err := h.svc.VerifyDisplayKey(c, uint(activityID), c.Query("display_key")); err != nil {
+			if errors.Is(err, service.ErrActivityNotFound) {
+				utils.Error(c, http.StatusNotFound, "活动不存在")
+				return
+			}
+			utils.Error(c, http.StatusUnauthorized, "展示密钥无效")
+			return
+		}
+	}
+
+	// 2. 生成当前时间窗口的动态码
+	code, err := getCode(c, uint(activityID))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrActivityNotFound):
+			utils.Error(c, http.StatusNotFound, "活动不存在")
+		case errors.Is(err, unavailable):
+			utils.Error(c, http.StatusForbidden, unavailableMsg)
+		default:
+			utils.Error(c, http.StatusInternalServerError, "生成动态码失败: "+err.Error())
+		}
+		return
+	}
+
+	utils.Success(c, code)
+}
+
+// RotateDisplayKey godoc
+// @Summary 重新生成大屏展示密钥
+// @Description 生成新的展示密钥并使旧密钥失效，明文只在此接口返回一次
+// @Tags Activity
+// @Produce json
+// @Param activity_id path int true "活动ID"
+// @Success 200 {object} model.DisplayKeyResponse "新的展示密钥"
+// @Failure 404 {object} gin.H "活动不存在"
+// @Router /admin/activities/{activity_id}/display-key [post]
+func (h *activityHandlerImpl) RotateDisplayKey(c *gin.Context) {
+	activityIDStr := c.Param("activity_id")
+	activityID, err := strconv.ParseUint(activityIDStr, 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "活动ID格式错误")
+		return
+	}
+
+	displayKey, err := h.svc.RotateDisplayKey(c, uint(activityID))
+	if err != nil {
+		if errors.Is(err, service.ErrActivityNotFound) {
+			utils.Error(c, http.StatusNotFound, "活动不存在")
+			return
+		}
+		slog.Error("Failed to rotate display key", "id", activityID, "error", err)
+		utils.Error(c, http.StatusInternalServerError, "生成展示密钥失败: "+err.Error())
+		return
+	}
+
+	utils.Success(c, model.DisplayKeyResponse{ActivityID: uint(activityID), DisplayKey: displayKey})
+}
+
+// SetCoOrganizers godoc
+// @Summary 设置活动协办组织者
+// @Description 整体替换活动的协办组织者，协办组织者与创建者拥有相同的修改权限
+// @Tags Activity
+// @Accept json
+// @Produce json
+// @Param activity_id path int true "活动ID"
+// @Param request body model.SetCoOrganizersRequest true "协办组织者ID列表"
+// @Success 200 {object} []model.AdminBriefResponse "设置后的协办组织者"
+// @Failure 400 {object} gin.H "请求参数错误或账号不是组织者"
+// @Failure 404 {object} gin.H "活动不存在"
+// @Router /admin/activities/{activity_id}/co-organizers [put]
+func (h *activityHandlerImpl) SetCoOrganizers(c *gin.Context) {
+	activityIDStr := c.Param("activity_id")
+	activityID, err := strconv.ParseUint(activityIDStr, 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "活动ID格式错误")
+		return
+	}
+
+	var req model.SetCoOrganizersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "请求参数错误: "+err.Error())
+		return
+	}
+
+	admins, err := h.svc.SetCoOrganizers(c, uint(activityID), req.AdminIDs)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrActivityNotFound):
+			utils.Error(c, http.StatusNotFound, "活动不存在")
+		case errors.Is(err, service.ErrInvalidCoOrganizer):
+			utils.Error(c, http.StatusBadRequest, "协办组织者必须是已存在的组织者账号")
+		default:
+			slog.Error("Failed to set co-organizers", "id", activityID, "error", err)
+			utils.Error(c, http.StatusInternalServerError, "设置协办组织者失败: "+err.Error())
+		}
+		return
+	}
+
+	utils.Success(c, toAdminBriefList(admins))
+}
+
+// ListCoOrganizers godoc
+// @Summary 查询活动协办组织者
+// @Tags Activity
+// @Produce json
+// @Param activity_id path int true "活动ID"
+// @Success 200 {object} []model.AdminBriefResponse "协办组织者列表"
+// @Failure 404 {object} gin.H "活动不存在"
+// @Router /admin/activities/{activity_id}/co-organizers [get]
+func (h *activityHandlerImpl) ListCoOrganizers(c *gin.Context) {
+	activityIDStr := c.Param("activity_id")
+	activityID, err := strconv.ParseUint(activityIDStr, 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "活动ID格式错误")
+		return
+	}
+
+	admins, err := h.svc.ListCoOrganizers(c, uint(activityID))
+	if err != nil {
+		if errors.Is(err, service.ErrActivityNotFound) {
+			utils.Error(c, http.StatusNotFound, "活动不存在")
+			return
+		}
+		slog.Error("Failed to list co-organizers", "id", activityID, "error", err)
+		utils.Error(c, http.StatusInternalServerError, "查询协办组织者失败: "+err.Error())
+		return
+	}
+
+	utils.Success(c, toAdminBriefList(admins))
+}
+
+// GetRegistrationForm godoc
+// @Summary 查询活动报名表
+// @Description 返回活动报名表的自定义字段 (姓名、手机号、学院之外)
+// @Tags Activity
+// @Produce json
+// @Param activity_id path int true "活动ID"
+// @Success 200 {object} model.FormSchema "报名表自定义字段"
+// @Failure 404 {object} gin.H "活动不存在"
+// @Router /admin/activities/{activity_id}/form [get]
+func (h *activityHandlerImpl) GetRegistrationForm(c *gin.Context) {
 	activityIDStr := c.Param("activity_id")
 	activityID, err := strconv.ParseUint(activityIDStr, 10, 64)
 	if err != nil {
@@ -316,34 +794,300 @@ func (h *activityHandlerImpl) GetSignInToken(c *gin.Context) {
 		return
 	}
 
-	// 1. 获取活动信息
 	activity, err := h.svc.GetActivityByID(c, uint(activityID))
+	if err != nil {
+		utils.Error(c, http.StatusNotFound, "活动不存在")
+		return
+	}
+
+	fields := activity.FormSchema
+	if fields == nil {
+		fields = model.FormSchema{}
+	}
+	utils.Success(c, fields)
+}
+
+// UpdateRegistrationForm godoc
+// @Summary 设置活动报名表
+// @Description 整体替换活动报名表的自定义字段，只能在草稿状态修改
+// @Tags Activity
+// @Accept json
+// @Produce json
+// @Param activity_id path int true "活动ID"
+// @Param request body model.UpdateFormSchemaRequest true "报名表自定义字段"
+// @Success 200 {object} model.FormSchema "设置后的报名表自定义字段"
+// @Failure 400 {object} gin.H "请求参数错误或字段定义不合法"
+// @Failure 404 {object} gin.H "活动不存在"
+// @Failure 409 {object} gin.H "活动已发布，不能修改报名表"
+// @Router /admin/activities/{activity_id}/form [put]
+func (h *activityHandlerImpl) UpdateRegistrationForm(c *gin.Context) {
+	activityIDStr := c.Param("activity_id")
+	activityID, err := strconv.ParseUint(activityIDStr, 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "活动ID格式错误")
+		return
+	}
+
+	var req model.UpdateFormSchemaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "请求参数错误: "+err.Error())
+		return
+	}
+
+	fields, err := h.svc.UpdateFormSchema(c, uint(activityID), req.Fields)
+	if err != nil {
+		var formErr *service.FormValidationError
+		switch {
+		case errors.As(err, &formErr):
+			utils.Error(c, http.StatusBadRequest, "报名表字段定义不合法: "+formErr.Error())
+		case errors.Is(err, service.ErrActivityNotFound):
+			utils.Error(c, http.StatusNotFound, "活动不存在")
+		case errors.Is(err, service.ErrFormSchemaLocked):
+			utils.Error(c, http.StatusConflict, "活动发布后不能修改报名表")
+		default:
+			slog.Error("Failed to update registration form", "id", activityID, "error", err)
+			utils.Error(c, http.StatusInternalServerError, "设置报名表失败: "+err.Error())
+		}
+		return
+	}
+
+	if fields == nil {
+		fields = model.FormSchema{}
+	}
+	utils.Success(c, fields)
+}
+
+// maxAllowlistUploadSize 报名名单 CSV 文件的大小上限
+const maxAllowlistUploadSize = 2 << 20
+
+// GetEligibility godoc
+// @Summary 查询活动报名资格规则
+// @Description 返回学院限制、学院名额、是否只允许名单内报名，以及报名名单的条目数
+// @Tags Activity
+// @Produce json
+// @Param activity_id path int true "活动ID"
+// @Success 200 {object} model.EligibilityResponse "报名资格规则"
+// @Failure 404 {object} gin.H "活动不存在"
+// @Router /admin/activities/{activity_id}/eligibility [get]
+func (h *activityHandlerImpl) GetEligibility(c *gin.Context) {
+	activityIDStr := c.Param("activity_id")
+	activityID, err := strconv.ParseUint(activityIDStr, 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "活动ID格式错误")
+		return
+	}
+
+	resp, err := h.svc.GetEligibility(c, uint(activityID))
 	if err != nil {
 		if errors.Is(err, service.ErrActivityNotFound) {
 			utils.Error(c, http.StatusNotFound, "活动不存在")
 			return
 		}
-		utils.Error(c, http.StatusInternalServerError, "获取活动信息失败: "+err.Error())
+		utils.Error(c, http.StatusInternalServerError, "查询报名资格规则失败: "+err.Error())
 		return
 	}
+	utils.Success(c, resp)
+}
 
-	// 2. 检查是否在活动时间范围内
-	now := time.Now()
-	if now.Before(activity.StartTime) || now.After(activity.EndTime) {
-		utils.Error(c, http.StatusForbidden, "当前时间不在活动时间范围内，无法获取签到Token")
+// UpdateEligibility godoc
+// @Summary 设置活动报名资格规则
+// @Description 整体替换报名资格规则，只对之后的报名生效；活动结束或取消后不能修改
+// @Tags Activity
+// @Accept json
+// @Produce json
+// @Param activity_id path int true "活动ID"
+// @Param request body model.EligibilityRules true "报名资格规则"
+// @Success 200 {object} model.EligibilityResponse "设置后的报名资格规则"
+// @Failure 400 {object} gin.H "请求参数错误或规则不合法"
+// @Failure 404 {object} gin.H "活动不存在"
+// @Failure 409 {object} gin.H "活动已结束或已取消"
+// @Router /admin/activities/{activity_id}/eligibility [put]
+func (h *activityHandlerImpl) UpdateEligibility(c *gin.Context) {
+	activityIDStr := c.Param("activity_id")
+	activityID, err := strconv.ParseUint(activityIDStr, 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "活动ID格式错误")
 		return
 	}
 
-	// 3. 生成并存储签到Token到Redis
-	token, err := redis.GenerateAndStoreToken(
-		c.Request.Context(),
-		uint(activityID),
-		config.GlobalConfig.JWT.SignInExpiresIn,
-	)
+	var req model.EligibilityRules
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "请求参数错误: "+err.Error())
+		return
+	}
+
+	if err := h.svc.UpdateEligibility(c, uint(activityID), &req); err != nil {
+		var rulesErr *service.EligibilityRulesError
+		switch {
+		case errors.As(err, &rulesErr):
+			utils.Error(c, http.StatusBadRequest, "报名资格规则不合法: "+rulesErr.Message)
+		case errors.Is(err, service.ErrActivityNotFound):
+			utils.Error(c, http.StatusNotFound, "活动不存在")
+		case errors.Is(err, service.ErrEligibilityLocked):
+			utils.Error(c, http.StatusConflict, "活动已结束或已取消，不能修改报名资格规则")
+		default:
+			slog.Error("Failed to update eligibility rules", "id", activityID, "error", err)
+			utils.Error(c, http.StatusInternalServerError, "设置报名资格规则失败: "+err.Error())
+		}
+		return
+	}
+
+	resp, err := h.svc.GetEligibility(c, uint(activityID))
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "设置成功但查询最新数据失败: "+err.Error())
+		return
+	}
+	utils.Success(c, resp)
+}
+
+// UploadAllowlist godoc
+// @Summary 上传活动报名名单
+// @Description 上传 CSV 文件整体替换报名名单，表头需包含 phone (手机号) 或 student_id (学号) 列
+// @Tags Activity
+// @Accept multipart/form-data
+// @Produce json
+// @Param activity_id path int true "活动ID"
+// @Param file formData file true "报名名单 CSV 文件"
+// @Success 200 {object} model.EligibilityResponse "替换后的报名资格规则"
+// @Failure 400 {object} gin.H "文件缺失或格式错误"
+// @Failure 404 {object} gin.H "活动不存在"
+// @Failure 413 {object} gin.H "文件过大"
+// @Router /admin/activities/{activity_id}/allowlist [put]
+func (h *activityHandlerImpl) UploadAllowlist(c *gin.Context) {
+	activityIDStr := c.Param("activity_id")
+	activityID, err := strconv.ParseUint(activityIDStr, 10, 64)
 	if err != nil {
-		utils.Error(c, http.StatusInternalServerError, "生成签到Token失败: "+err.Error())
+		utils.Error(c, http.StatusBadRequest, "活动ID格式错误")
 		return
 	}
 
-	utils.Success(c, gin.H{"token": token})
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "请上传报名名单文件 (file)")
+		return
+	}
+	if fileHeader.Size > maxAllowlistUploadSize {
+		utils.Error(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("报名名单文件不能超过 %d MB", maxAllowlistUploadSize>>20))
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "读取报名名单文件失败")
+		return
+	}
+	defer file.Close()
+
+	entries, err := service.ParseAllowlistCSV(file)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "报名名单格式错误: "+err.Error())
+		return
+	}
+
+	if err := h.svc.ReplaceAllowlist(c, uint(activityID), entries); err != nil {
+		if errors.Is(err, service.ErrActivityNotFound) {
+			utils.Error(c, http.StatusNotFound, "活动不存在")
+			return
+		}
+		slog.Error("Failed to replace allowlist", "id", activityID, "error", err)
+		utils.Error(c, http.StatusInternalServerError, "上传报名名单失败: "+err.Error())
+		return
+	}
+
+	resp, err := h.svc.GetEligibility(c, uint(activityID))
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "上传成功但查询最新数据失败: "+err.Error())
+		return
+	}
+	utils.Success(c, resp)
+}
+
+// GetLotteryDraw godoc
+// @Summary 查询抽签开奖记录
+// @Description 返回抽签活动的随机种子、算法版本、学院权重和按抽签顺序排列的结果，可用于复核抽签过程
+// @Tags Activity
+// @Produce json
+// @Param activity_id path int true "活动ID"
+// @Success 200 {object} model.LotteryDraw "开奖记录"
+// @Failure 404 {object} gin.H "活动不存在或尚未开奖"
+// @Failure 409 {object} gin.H "活动未开启抽签报名"
+// @Router /admin/activities/{activity_id}/lottery [get]
+func (h *activityHandlerImpl) GetLotteryDraw(c *gin.Context) {
+	activityIDStr := c.Param("activity_id")
+	activityID, err := strconv.ParseUint(activityIDStr, 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "活动ID格式错误")
+		return
+	}
+
+	draw, err := h.svc.GetLotteryDraw(c, uint(activityID))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrActivityNotFound):
+			utils.Error(c, http.StatusNotFound, "活动不存在")
+		case errors.Is(err, service.ErrNotLotteryActivity):
+			utils.Error(c, http.StatusConflict, "活动未开启抽签报名")
+		case errors.Is(err, service.ErrLotteryNotDrawn):
+			utils.Error(c, http.StatusNotFound, "活动尚未开奖")
+		default:
+			utils.Error(c, http.StatusInternalServerError, "查询开奖记录失败: "+err.Error())
+		}
+		return
+	}
+	utils.Success(c, draw)
+}
+
+// ListScheduledPublishes godoc
+// @Summary 查询定时发布的活动
+// @Description 按发布时间列出设置了定时发布的草稿活动，发布失败的活动 publish_error 为失败原因
+// @Tags Activity
+// @Produce json
+// @Success 200 {object} []model.ActivityResponse "定时发布的活动列表"
+// @Router /admin/activities/scheduled-publishes [get]
+func (h *activityHandlerImpl) ListScheduledPublishes(c *gin.Context) {
+	activities, err := h.svc.ListScheduledPublishes(c)
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "查询定时发布的活动失败: "+err.Error())
+		return
+	}
+
+	utils.Success(c, toActivityResponseList(activities))
+}
+
+// CancelScheduledPublish godoc
+// @Summary 取消定时发布
+// @Description 取消草稿活动的定时发布，同时清除上次定时发布失败的原因
+// @Tags Activity
+// @Produce json
+// @Param activity_id path int true "活动ID"
+// @Success 200 {object} model.ActivityResponse "最新的活动详情"
+// @Failure 404 {object} gin.H "活动不存在"
+// @Failure 409 {object} gin.H "活动未设置定时发布"
+// @Router /admin/activities/{activity_id}/publish-schedule [delete]
+func (h *activityHandlerImpl) CancelScheduledPublish(c *gin.Context) {
+	activityIDStr := c.Param("activity_id")
+	activityID, err := strconv.ParseUint(activityIDStr, 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "活动ID格式错误")
+		return
+	}
+
+	if err := h.svc.CancelScheduledPublish(c, uint(activityID)); err != nil {
+		switch {
+		case errors.Is(err, service.ErrActivityNotFound):
+			utils.Error(c, http.StatusNotFound, "活动不存在")
+		case errors.Is(err, service.ErrPublishNotScheduled):
+			utils.Error(c, http.StatusConflict, "活动未设置定时发布")
+		default:
+			slog.Error("Failed to cancel scheduled publish", "id", activityID, "error", err)
+			utils.Error(c, http.StatusInternalServerError, "取消定时发布失败: "+err.Error())
+		}
+		return
+	}
+
+	activity, err := h.svc.GetActivityByID(c, uint(activityID))
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "取消成功但查询最新数据失败: "+err.Error())
+		return
+	}
+	utils.Success(c, toActivityResponse(activity))
 }
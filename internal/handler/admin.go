@@ -4,7 +4,9 @@ import (
 	"errors"
 	"log/slog"
 	"net/http"
+	"strconv"
 
+	"github.com/frozenf1sh/gostudent/internal/middleware"
 	"github.com/frozenf1sh/gostudent/internal/model"
 	"github.com/frozenf1sh/gostudent/internal/service"
 	"github.com/frozenf1sh/gostudent/pkg/utils"
@@ -14,7 +16,17 @@ import (
 // AdminHandler 接口定义管理员操作的 API 方法
 type AdminHandler interface {
 	Login(c *gin.Context)
-	// GetAdminInfo(c *gin.Context) // 例如: 获取当前登录管理员信息
+	Refresh(c *gin.Context)
+	Logout(c *gin.Context)
+	// 当前登录管理员
+	GetMe(c *gin.Context)
+	ChangeMyPassword(c *gin.Context)
+	// 账号管理 (超级管理员)
+	CreateAdmin(c *gin.Context)
+	ListAdmins(c *gin.Context)
+	UpdateAdminStatus(c *gin.Context)
+	ResetAdminPassword(c *gin.Context)
+	DeleteAdmin(c *gin.Context)
 }
 
 type adminHandlerImpl struct {
@@ -36,6 +48,7 @@ func NewAdminHandler(svc service.AdminService) AdminHandler {
 // @Success 200 {object} model.LoginResponse
 // @Failure 400 {object} gin.H "请求参数错误"
 // @Failure 401 {object} gin.H "用户名或密码错误"
+// @Failure 429 {object} gin.H "登录失败次数过多或请求过于频繁，响应头 Retry-After 为需等待的秒数"
 // @Router /admin/login [post]
 func (h *adminHandlerImpl) Login(c *gin.Context) {
 	var req model.AdminLoginRequest
@@ -47,13 +60,19 @@ func (h *adminHandlerImpl) Login(c *gin.Context) {
 	}
 
 	// 2. 调用 Service 层业务逻辑
-	token, err := h.svc.Login(c, &req)
+	resp, err := h.svc.Login(c, &req, c.ClientIP())
 
 	// 3. 处理业务逻辑错误
 	if err != nil {
-		slog.Error("Admin login failed", "username", req.Username, "error", err)
-		if errors.Is(err, service.ErrInvalidPassword) { // 假设 Service 定义了该错误
+		slog.Error("Admin login failed", "username", req.Username, "ip", c.ClientIP(), "error", err)
+		var lockedErr *service.LoginLockedError
+		if errors.As(err, &lockedErr) {
+			middleware.SetRetryAfter(c, lockedErr.RetryAfter)
+			utils.Error(c, http.StatusTooManyRequests, "登录失败次数过多，请稍后再试")
+		} else if errors.Is(err, service.ErrInvalidPassword) { // 假设 Service 定义了该错误
 			utils.Error(c, http.StatusUnauthorized, "用户名或密码错误")
+		} else if errors.Is(err, service.ErrAdminDisabled) {
+			utils.Error(c, http.StatusForbidden, "管理员账号已停用")
 		} else {
 			utils.Error(c, http.StatusInternalServerError, "登录失败: "+err.Error())
 		}
@@ -61,5 +80,341 @@ func (h *adminHandlerImpl) Login(c *gin.Context) {
 	}
 
 	// 4. 返回成功响应
-	utils.Success(c, model.AdminLoginResponse{Token: token, Username: req.Username})
+	utils.Success(c, resp)
+}
+
+// Refresh godoc
+// @Summary 刷新访问令牌
+// @Description 使用刷新令牌换取新的访问令牌和刷新令牌，旧刷新令牌立即失效；重复使用旧刷新令牌会吊销整个会话
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param request body model.RefreshTokenRequest true "刷新令牌"
+// @Success 200 {object} model.AdminLoginResponse
+// @Failure 401 {object} gin.H "刷新令牌无效、过期或已被重用"
+// @Router /admin/refresh [post]
+func (h *adminHandlerImpl) Refresh(c *gin.Context) {
+	var req model.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "请求参数错误: "+err.Error())
+		return
+	}
+
+	resp, err := h.svc.Refresh(c, req.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidRefreshToken):
+			utils.Error(c, http.StatusUnauthorized, "刷新令牌无效或已过期")
+		case errors.Is(err, service.ErrRefreshTokenReused):
+			utils.Error(c, http.StatusUnauthorized, "刷新令牌已被使用，会话已吊销，请重新登录")
+		case errors.Is(err, service.ErrAdminDisabled):
+			utils.Error(c, http.StatusForbidden, "管理员账号已停用")
+		default:
+			slog.Error("Failed to refresh token", "error", err)
+			utils.Error(c, http.StatusInternalServerError, "刷新令牌失败: "+err.Error())
+		}
+		return
+	}
+
+	utils.Success(c, resp)
+}
+
+// Logout godoc
+// @Summary 管理员注销
+// @Description 当前访问令牌立即失效，同一次登录签发的刷新令牌一并吊销
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} gin.H "注销成功"
+// @Router /admin/logout [post]
+func (h *adminHandlerImpl) Logout(c *gin.Context) {
+	jti := c.GetString(middleware.ContextKeyTokenID)
+	familyID := c.GetString(middleware.ContextKeyTokenFamily)
+	expiresAt := c.GetTime(middleware.ContextKeyTokenExpiresAt)
+
+	if err := h.svc.Logout(c, jti, familyID, expiresAt); err != nil {
+		slog.Error("Failed to logout", "error", err)
+		utils.Error(c, http.StatusInternalServerError, "注销失败: "+err.Error())
+		return
+	}
+
+	utils.Success(c, gin.H{"message": "注销成功"})
+}
+
+// toAdminResponse 将 model.Admin 转换为 model.AdminResponse DTO
+func toAdminResponse(admin *model.Admin) model.AdminResponse {
+	return model.AdminResponse{
+		ID:        admin.ID,
+		Username:  admin.Username,
+		Role:      admin.Role,
+		Disabled:  admin.Disabled,
+		CreatedAt: admin.CreatedAt,
+	}
+}
+
+// parseAdminIDParam 解析路径参数 :admin_id
+func parseAdminIDParam(c *gin.Context) (uint, bool) {
+	adminID, err := strconv.ParseUint(c.Param("admin_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "管理员ID格式错误")
+		return 0, false
+	}
+	return uint(adminID), true
+}
+
+// GetMe godoc
+// @Summary 获取当前登录管理员信息
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} model.AdminResponse
+// @Router /admin/me [get]
+func (h *adminHandlerImpl) GetMe(c *gin.Context) {
+	adminID, err := getAdminIDFromContext(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, "未登录或Token无效")
+		return
+	}
+
+	admin, err := h.svc.GetByID(c, adminID)
+	if err != nil {
+		utils.Error(c, http.StatusNotFound, "管理员不存在")
+		return
+	}
+
+	utils.Success(c, toAdminResponse(admin))
+}
+
+// ChangeMyPassword godoc
+// @Summary 修改自己的密码
+// @Description 需要提供旧密码
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param request body model.ChangePasswordRequest true "修改密码请求"
+// @Success 200 {object} gin.H "修改成功"
+// @Failure 400 {object} gin.H "请求参数错误或旧密码错误"
+// @Router /admin/me/password [put]
+func (h *adminHandlerImpl) ChangeMyPassword(c *gin.Context) {
+	adminID, err := getAdminIDFromContext(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, "未登录或Token无效")
+		return
+	}
+
+	var req model.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "请求参数错误: "+err.Error())
+		return
+	}
+
+	if err := h.svc.ChangePassword(c, adminID, req.OldPassword, req.NewPassword); err != nil {
+		if errors.Is(err, service.ErrOldPasswordMismatch) {
+			utils.Error(c, http.StatusBadRequest, "旧密码错误")
+			return
+		}
+		slog.Error("Failed to change password", "admin_id", adminID, "error", err)
+		utils.Error(c, http.StatusInternalServerError, "修改密码失败: "+err.Error())
+		return
+	}
+
+	utils.Success(c, gin.H{"message": "密码修改成功"})
+}
+
+// CreateAdmin godoc
+// @Summary 创建管理员账号
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param request body model.CreateAdminRequest true "创建管理员请求"
+// @Success 200 {object} model.AdminResponse "创建的管理员"
+// @Failure 400 {object} gin.H "请求参数错误或角色无效"
+// @Failure 409 {object} gin.H "用户名已存在"
+// @Router /admin/admins [post]
+func (h *adminHandlerImpl) CreateAdmin(c *gin.Context) {
+	var req model.CreateAdminRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "请求参数错误: "+err.Error())
+		return
+	}
+
+	admin, err := h.svc.CreateAdmin(c, req.Username, req.Password, req.Role)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidRole):
+			utils.Error(c, http.StatusBadRequest, "角色无效")
+		case errors.Is(err, service.ErrUsernameTaken):
+			utils.Error(c, http.StatusConflict, "用户名已存在")
+		default:
+			slog.Error("Failed to create admin", "username", req.Username, "error", err)
+			utils.Error(c, http.StatusInternalServerError, "创建管理员失败: "+err.Error())
+		}
+		return
+	}
+
+	utils.Success(c, toAdminResponse(admin))
+}
+
+// ListAdmins godoc
+// @Summary 查询管理员列表
+// @Tags Admin
+// @Produce json
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页大小" default(10)
+// @Param username query string false "用户名关键词"
+// @Param role query string false "角色"
+// @Param disabled query bool false "是否停用"
+// @Success 200 {object} gin.H{list=[]model.AdminResponse,total=int} "管理员列表和总数"
+// @Router /admin/admins [get]
+func (h *adminHandlerImpl) ListAdmins(c *gin.Context) {
+	params := &model.ListAdminsParams{}
+	if err := c.ShouldBindQuery(params); err != nil {
+		utils.Error(c, http.StatusBadRequest, "查询参数错误: "+err.Error())
+		return
+	}
+
+	// 设置默认分页参数
+	if params.Page <= 0 {
+		params.Page = 1
+	}
+	if params.PageSize <= 0 || params.PageSize > 100 {
+		params.PageSize = 10
+	}
+
+	admins, total, err := h.svc.ListAdmins(c, params)
+	if err != nil {
+		slog.Error("Failed to list admins", "params", params, "error", err)
+		utils.Error(c, http.StatusInternalServerError, "查询管理员列表失败: "+err.Error())
+		return
+	}
+
+	list := make([]model.AdminResponse, len(admins))
+	for i, admin := range admins {
+		list[i] = toAdminResponse(admin)
+	}
+	utils.Success(c, gin.H{
+		"list":  list,
+		"total": total,
+		"page":  params.Page,
+	})
+}
+
+// UpdateAdminStatus godoc
+// @Summary 停用或启用管理员账号
+// @Description 停用后该账号无法登录，已签发的 Token 也会立即失效
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param admin_id path int true "管理员ID"
+// @Param request body model.UpdateAdminStatusRequest true "账号状态"
+// @Success 200 {object} gin.H "更新成功"
+// @Failure 400 {object} gin.H "不能停用自己"
+// @Failure 404 {object} gin.H "管理员不存在"
+// @Router /admin/admins/{admin_id}/status [put]
+func (h *adminHandlerImpl) UpdateAdminStatus(c *gin.Context) {
+	targetID, ok := parseAdminIDParam(c)
+	if !ok {
+		return
+	}
+	operatorID, err := getAdminIDFromContext(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, "未登录或Token无效")
+		return
+	}
+
+	var req model.UpdateAdminStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "请求参数错误: "+err.Error())
+		return
+	}
+
+	if err := h.svc.SetDisabled(c, operatorID, targetID, *req.Disabled); err != nil {
+		switch {
+		case errors.Is(err, service.ErrCannotModifySelf):
+			utils.Error(c, http.StatusBadRequest, "不能停用自己的账号")
+		case errors.Is(err, service.ErrAdminNotFound):
+			utils.Error(c, http.StatusNotFound, "管理员不存在")
+		default:
+			slog.Error("Failed to update admin status", "admin_id", targetID, "error", err)
+			utils.Error(c, http.StatusInternalServerError, "更新账号状态失败: "+err.Error())
+		}
+		return
+	}
+
+	utils.Success(c, gin.H{"message": "账号状态更新成功"})
+}
+
+// ResetAdminPassword godoc
+// @Summary 重置管理员密码
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param admin_id path int true "管理员ID"
+// @Param request body model.ResetAdminPasswordRequest true "新密码"
+// @Success 200 {object} gin.H "重置成功"
+// @Failure 404 {object} gin.H "管理员不存在"
+// @Router /admin/admins/{admin_id}/password [put]
+func (h *adminHandlerImpl) ResetAdminPassword(c *gin.Context) {
+	targetID, ok := parseAdminIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req model.ResetAdminPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "请求参数错误: "+err.Error())
+		return
+	}
+
+	if err := h.svc.ResetPassword(c, targetID, req.NewPassword); err != nil {
+		if errors.Is(err, service.ErrAdminNotFound) {
+			utils.Error(c, http.StatusNotFound, "管理员不存在")
+			return
+		}
+		slog.Error("Failed to reset admin password", "admin_id", targetID, "error", err)
+		utils.Error(c, http.StatusInternalServerError, "重置密码失败: "+err.Error())
+		return
+	}
+
+	utils.Success(c, gin.H{"message": "密码重置成功"})
+}
+
+// DeleteAdmin godoc
+// @Summary 删除管理员账号
+// @Description 不能删除自己和默认超级管理员 (ID 为 1)；仍创建有活动的账号不能删除，可改为停用
+// @Tags Admin
+// @Produce json
+// @Param admin_id path int true "管理员ID"
+// @Success 200 {object} gin.H "删除成功"
+// @Failure 400 {object} gin.H "不能删除自己或默认超级管理员"
+// @Failure 404 {object} gin.H "管理员不存在"
+// @Failure 409 {object} gin.H "账号仍创建有活动"
+// @Router /admin/admins/{admin_id} [delete]
+func (h *adminHandlerImpl) DeleteAdmin(c *gin.Context) {
+	targetID, ok := parseAdminIDParam(c)
+	if !ok {
+		return
+	}
+	operatorID, err := getAdminIDFromContext(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, "未登录或Token无效")
+		return
+	}
+
+	if err := h.svc.DeleteAdmin(c, operatorID, targetID); err != nil {
+		switch {
+		case errors.Is(err, service.ErrCannotModifySelf):
+			utils.Error(c, http.StatusBadRequest, "不能删除自己的账号")
+		case errors.Is(err, service.ErrCannotDeleteDefault):
+			utils.Error(c, http.StatusBadRequest, "不能删除默认超级管理员账号，可修改其密码")
+		case errors.Is(err, service.ErrAdminNotFound):
+			utils.Error(c, http.StatusNotFound, "管理员不存在")
+		case errors.Is(err, service.ErrAdminHasActivities):
+			utils.Error(c, http.StatusConflict, "该账号仍创建有活动，无法删除，可改为停用")
+		default:
+			slog.Error("Failed to delete admin", "admin_id", targetID, "error", err)
+			utils.Error(c, http.StatusInternalServerError, "删除管理员失败: "+err.Error())
+		}
+		return
+	}
+
+	utils.Success(c, gin.H{"message": "管理员删除成功"})
 }
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/frozenf1sh/gostudent/internal/model"
+	"github.com/frozenf1sh/gostudent/internal/service"
+	"github.com/frozenf1sh/gostudent/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// AuditHandler 审计日志查询接口
+type AuditHandler interface {
+	ListAuditLogs(c *gin.Context)
+}
+
+type auditHandlerImpl struct {
+	svc service.AuditService
+}
+
+// NewAuditHandler 创建 AuditHandler 实例
+func NewAuditHandler(svc service.AuditService) AuditHandler {
+	return &auditHandlerImpl{svc: svc}
+}
+
+// toAuditLogResponse 将 model.AuditLog 转换为 model.AuditLogResponse DTO
+func toAuditLogResponse(log *model.AuditLog) model.AuditLogResponse {
+	diff := json.RawMessage(log.Diff)
+	if !json.Valid(diff) {
+		diff = json.RawMessage("{}")
+	}
+	return model.AuditLogResponse{
+		ID:         log.ID,
+		AdminID:    log.AdminID,
+		Action:     log.Action,
+		TargetType: log.TargetType,
+		TargetID:   log.TargetID,
+		Diff:       diff,
+		IP:         log.IP,
+		RequestID:  log.RequestID,
+		CreatedAt:  log.CreatedAt,
+	}
+}
+
+// ListAuditLogs godoc
+// @Summary 查询审计日志
+// @Description 按操作者、操作类型、操作对象和时间范围过滤，按时间倒序返回
+// @Tags Audit
+// @Produce json
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页大小" default(20)
+// @Param admin_id query int false "操作者ID"
+// @Param action query string false "操作类型"
+// @Param target_type query string false "操作对象类型 (activity/registration/admin/credit)"
+// @Param target_id query int false "操作对象ID"
+// @Param date_from query string false "开始时间 (RFC3339)"
+// @Param date_to query string false "结束时间 (RFC3339)"
+// @Success 200 {object} gin.H{list=[]model.AuditLogResponse,total=int} "审计日志列表和总数"
+// @Router /admin/audit-logs [get]
+func (h *auditHandlerImpl) ListAuditLogs(c *gin.Context) {
+	params := &model.ListAuditLogsParams{}
+	if err := c.ShouldBindQuery(params); err != nil {
+		utils.Error(c, http.StatusBadRequest, "查询参数错误: "+err.Error())
+		return
+	}
+
+	// 设置默认分页参数
+	if params.Page <= 0 {
+		params.Page = 1
+	}
+	if params.PageSize <= 0 || params.PageSize > 100 {
+		params.PageSize = 20
+	}
+
+	logs, total, err := h.svc.ListAuditLogs(c, params)
+	if err != nil {
+		slog.Error("Failed to list audit logs", "params", params, "error", err)
+		utils.Error(c, http.StatusInternalServerError, "查询审计日志失败: "+err.Error())
+		return
+	}
+
+	list := make([]model.AuditLogResponse, len(logs))
+	for i, log := range logs {
+		list[i] = toAuditLogResponse(log)
+	}
+	utils.Success(c, gin.H{
+		"list":  list,
+		"total": total,
+		"page":  params.Page,
+	})
+}
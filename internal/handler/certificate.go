@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/frozenf1sh/gostudent/internal/model"
+	"github.com/frozenf1sh/gostudent/internal/service"
+	"github.com/frozenf1sh/gostudent/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// CertificateHandler 活动参与证明的批量下载和公开查验接口
+type CertificateHandler interface {
+	DownloadCertificates(c *gin.Context)
+	VerifyCertificate(c *gin.Context)
+}
+
+type certificateHandlerImpl struct {
+	svc service.CertificateService
+}
+
+// NewCertificateHandler 创建 CertificateHandler 实例
+func NewCertificateHandler(svc service.CertificateService) CertificateHandler {
+	return &certificateHandlerImpl{svc: svc}
+}
+
+// DownloadCertificates godoc
+// @Summary 批量下载参与证明
+// @Description 为已结束活动中已签到的报名签发参与证明 (已签发的不会重新生成验证码)，渲染为 PDF 并打包为 zip 下载
+// @Tags Certificate
+// @Produce application/zip
+// @Param activity_id path int true "活动ID"
+// @Success 200 {file} file "参与证明压缩包"
+// @Failure 404 {object} gin.H "活动不存在或没有已签到的报名"
+// @Failure 409 {object} gin.H "活动尚未结束"
+// @Router /admin/activities/{activity_id}/certificates [get]
+func (h *certificateHandlerImpl) DownloadCertificates(c *gin.Context) {
+	activityID, err := strconv.ParseUint(c.Param("activity_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "活动ID格式错误")
+		return
+	}
+
+	certificates, err := h.svc.IssueCertificates(c, uint(activityID))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrActivityNotFound):
+			utils.Error(c, http.StatusNotFound, "活动不存在")
+		case errors.Is(err, service.ErrCertificateNotAvailable):
+			utils.Error(c, http.StatusConflict, "活动结束后才能签发参与证明")
+		default:
+			slog.Error("Failed to issue certificates", "activity_id", activityID, "error", err)
+			utils.Error(c, http.StatusInternalServerError, "签发参与证明失败: "+err.Error())
+		}
+		return
+	}
+	if len(certificates) == 0 {
+		utils.Error(c, http.StatusNotFound, "该活动没有已签到的报名")
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="certificates-%d.zip"`, activityID))
+	if err := h.svc.WriteCertificatesZip(c.Writer, certificates); err != nil {
+		slog.Error("Failed to write certificates", "activity_id", activityID, "error", err)
+		if !c.Writer.Written() {
+			// 尚未输出内容 (如模板配置错误)，仍可返回错误响应
+			c.Writer.Header().Del("Content-Type")
+			c.Writer.Header().Del("Content-Disposition")
+			utils.Error(c, http.StatusInternalServerError, "生成参与证明失败: "+err.Error())
+		}
+	}
+}
+
+// VerifyCertificate godoc
+// @Summary 查验参与证明
+// @Description 通过证明上的验证码查验参与证明的真伪，验证码不区分大小写
+// @Tags Certificate
+// @Produce json
+// @Param code path string true "验证码"
+// @Success 200 {object} model.CertificateVerificationResponse "证明信息"
+// @Failure 404 {object} gin.H "证明不存在"
+// @Router /certificates/{code} [get]
+func (h *certificateHandlerImpl) VerifyCertificate(c *gin.Context) {
+	certificate, err := h.svc.VerifyCertificate(c, c.Param("code"))
+	if err != nil {
+		if errors.Is(err, service.ErrCertificateNotFound) {
+			utils.Error(c, http.StatusNotFound, "证明不存在，请检查验证码")
+			return
+		}
+		slog.Error("Failed to verify certificate", "error", err)
+		utils.Error(c, http.StatusInternalServerError, "查验参与证明失败: "+err.Error())
+		return
+	}
+
+	utils.Success(c, model.CertificateVerificationResponse{
+		Code:               certificate.Code,
+		ParticipantName:    certificate.ParticipantName,
+		ParticipantCollege: certificate.ParticipantCollege,
+		ActivityTitle:      certificate.ActivityTitle,
+		ActivityStartTime:  certificate.ActivityStartTime,
+		AttendedSeconds:    certificate.AttendedSeconds,
+		IssuedAt:           certificate.CreatedAt,
+	})
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/frozenf1sh/gostudent/internal/model"
+	"github.com/frozenf1sh/gostudent/internal/service"
+	"github.com/frozenf1sh/gostudent/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// CreditHandler 学分查询和手动调整接口
+type CreditHandler interface {
+	GetStudentCredits(c *gin.Context)
+	AdjustCredits(c *gin.Context)
+}
+
+type creditHandlerImpl struct {
+	svc service.CreditService
+}
+
+// NewCreditHandler 创建 CreditHandler 实例
+func NewCreditHandler(svc service.CreditService) CreditHandler {
+	return &creditHandlerImpl{svc: svc}
+}
+
+// GetStudentCredits godoc
+// @Summary 查询学生学分
+// @Description 按手机号或学号查询学生在各活动中获得的学分，返回按学分类型汇总的总数和流水 (按时间倒序)
+// @Tags Credit
+// @Produce json
+// @Param phone query string false "学生手机号"
+// @Param student_id query string false "学生学号"
+// @Success 200 {object} model.CreditSummaryResponse "学分汇总及流水"
+// @Failure 400 {object} gin.H "未填写手机号或学号"
+// @Router /admin/credits [get]
+func (h *creditHandlerImpl) GetStudentCredits(c *gin.Context) {
+	params := &model.CreditQueryParams{}
+	if err := c.ShouldBindQuery(params); err != nil {
+		utils.Error(c, http.StatusBadRequest, "查询参数错误: "+err.Error())
+		return
+	}
+
+	summary, err := h.svc.GetStudentCredits(c, params)
+	if err != nil {
+		if errors.Is(err, service.ErrCreditStudentRequired) {
+			utils.Error(c, http.StatusBadRequest, "请填写手机号或学号")
+			return
+		}
+		slog.Error("Failed to get student credits", "params", params, "error", err)
+		utils.Error(c, http.StatusInternalServerError, "查询学分失败: "+err.Error())
+		return
+	}
+	utils.Success(c, summary)
+}
+
+// AdjustCredits godoc
+// @Summary 手动调整学分
+// @Description 为学生追加一条学分调整流水 (负数表示扣减)，必须填写原因，操作记录审计日志
+// @Tags Credit
+// @Accept json
+// @Produce json
+// @Param request body model.CreditAdjustmentRequest true "学分调整请求"
+// @Success 200 {object} model.CreditEntry "追加的调整流水"
+// @Failure 400 {object} gin.H "请求参数错误、学分类型无效或调整数额无效"
+// @Router /admin/credits/adjustments [post]
+func (h *creditHandlerImpl) AdjustCredits(c *gin.Context) {
+	var req model.CreditAdjustmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "请求参数错误: "+err.Error())
+		return
+	}
+
+	entry, err := h.svc.AdjustCredits(c, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrCreditStudentRequired):
+			utils.Error(c, http.StatusBadRequest, "请填写手机号或学号")
+		case errors.Is(err, service.ErrInvalidCreditType):
+			utils.Error(c, http.StatusBadRequest, "学分类型无效")
+		case errors.Is(err, service.ErrInvalidCreditAmount):
+			utils.Error(c, http.StatusBadRequest, "调整数额必须非零，且绝对值不超过 1000")
+		case errors.Is(err, service.ErrCreditReasonRequired):
+			utils.Error(c, http.StatusBadRequest, "请填写调整原因")
+		default:
+			slog.Error("Failed to adjust credits", "phone", req.Phone, "student_id", req.StudentID, "error", err)
+			utils.Error(c, http.StatusInternalServerError, "调整学分失败: "+err.Error())
+		}
+		return
+	}
+	utils.Success(c, entry)
+}
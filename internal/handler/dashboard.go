@@ -38,13 +38,15 @@ func (h *dashboardHandlerImpl) GetDashboardData(c *gin.Context) {
 	h.db.WithContext(ctx).Model(&model.Activity{}).
 		Where("status = ?", model.ActivityStatusPublished).Count(&publishedActivities)
 
+	// 已取消的报名记录只做留档，不计入统计
 	var totalRegistrations int64
-	h.db.WithContext(ctx).Model(&model.Registration{}).Count(&totalRegistrations)
+	h.db.WithContext(ctx).Model(&model.Registration{}).
+		Where("status <> ?", model.RegistrationStatusCancelled).Count(&totalRegistrations)
 
 	var todayRegistrations int64
 	today := time.Now().Format("2006-01-02")
 	h.db.WithContext(ctx).Model(&model.Registration{}).
-		Where("DATE(registered_at) = ?", today).Count(&todayRegistrations)
+		Where("DATE(registered_at) = ? AND status <> ?", today, model.RegistrationStatusCancelled).Count(&todayRegistrations)
 
 	resp := model.DashboardResponse{
 		TotalActivities:     totalActivities,
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/frozenf1sh/gostudent/internal/model"
+	"github.com/frozenf1sh/gostudent/internal/service"
+	"github.com/frozenf1sh/gostudent/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// NotificationHandler 通知队列查询和重试接口
+type NotificationHandler interface {
+	ListNotifications(c *gin.Context)
+	RetryNotification(c *gin.Context)
+}
+
+type notificationHandlerImpl struct {
+	svc service.NotificationService
+}
+
+// NewNotificationHandler 创建 NotificationHandler 实例
+func NewNotificationHandler(svc service.NotificationService) NotificationHandler {
+	return &notificationHandlerImpl{svc: svc}
+}
+
+// ListNotifications godoc
+// @Summary 查询通知
+// @Description 按发送状态、事件、活动和收件人过滤，按创建时间倒序返回，可用于排查发送失败的通知
+// @Tags Notification
+// @Produce json
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页大小" default(20)
+// @Param status query string false "发送状态 (PENDING/SENT/FAILED)"
+// @Param event query string false "事件 (REGISTERED/PROMOTED/ACTIVITY_UPDATED/ACTIVITY_CANCELLED)"
+// @Param activity_id query int false "活动ID"
+// @Param recipient query string false "收件人"
+// @Success 200 {object} gin.H{list=[]model.Notification,total=int} "通知列表和总数"
+// @Router /admin/notifications [get]
+func (h *notificationHandlerImpl) ListNotifications(c *gin.Context) {
+	params := &model.ListNotificationsParams{}
+	if err := c.ShouldBindQuery(params); err != nil {
+		utils.Error(c, http.StatusBadRequest, "查询参数错误: "+err.Error())
+		return
+	}
+
+	// 设置默认分页参数
+	if params.Page <= 0 {
+		params.Page = 1
+	}
+	if params.PageSize <= 0 || params.PageSize > 100 {
+		params.PageSize = 20
+	}
+
+	notifications, total, err := h.svc.ListNotifications(c, params)
+	if err != nil {
+		slog.Error("Failed to list notifications", "params", params, "error", err)
+		utils.Error(c, http.StatusInternalServerError, "查询通知失败: "+err.Error())
+		return
+	}
+	utils.Success(c, gin.H{
+		"list":  notifications,
+		"total": total,
+		"page":  params.Page,
+	})
+}
+
+// RetryNotification godoc
+// @Summary 重试发送失败的通知
+// @Description 将重试次数用尽的通知重置为等待发送，由发送任务在下一轮发送，操作记录审计日志
+// @Tags Notification
+// @Produce json
+// @Param notification_id path int true "通知ID"
+// @Success 200 {object} model.Notification "重新排队的通知"
+// @Failure 404 {object} gin.H "通知不存在"
+// @Failure 409 {object} gin.H "通知不是发送失败状态"
+// @Router /admin/notifications/{notification_id}/retry [post]
+func (h *notificationHandlerImpl) RetryNotification(c *gin.Context) {
+	notificationID, err := strconv.ParseUint(c.Param("notification_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "通知ID格式错误")
+		return
+	}
+
+	notification, err := h.svc.RetryNotification(c, uint(notificationID))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotificationNotFound):
+			utils.Error(c, http.StatusNotFound, "通知不存在")
+		case errors.Is(err, service.ErrNotificationNotFailed):
+			utils.Error(c, http.StatusConflict, "只能重试发送失败的通知")
+		default:
+			slog.Error("Failed to retry notification", "notification_id", notificationID, "error", err)
+			utils.Error(c, http.StatusInternalServerError, "重试通知失败: "+err.Error())
+		}
+		return
+	}
+	utils.Success(c, notification)
+}
@@ -2,13 +2,15 @@ package handler
 
 import (
 	"errors"
+	"fmt"
 	"log/slog"
+	"mime/multipart"
 	"net/http"
 	"strconv"
 
+	"github.com/frozenf1sh/gostudent/internal/middleware"
 	"github.com/frozenf1sh/gostudent/internal/model"
 	"github.com/frozenf1sh/gostudent/internal/service"
-	"github.com/frozenf1sh/gostudent/pkg/redis"
 	"github.com/frozenf1sh/gostudent/pkg/utils"
 	"github.com/gin-gonic/gin"
 )
@@ -17,10 +19,28 @@ import (
 type RegistrationHandler interface {
 	Register(c *gin.Context)
 	ListRegistrations(c *gin.Context)
+	ExportRegistrations(c *gin.Context)
 	SignIn(c *gin.Context) // 签到功能 (目前禁用)
+	SignOut(c *gin.Context)
+	// 参与者自助取消报名
+	CancelRegistration(c *gin.Context)
 	GetRegistrationByID(c *gin.Context)
-	// 管理员更新签到状态
+	// 管理员更新签到状态 (单条 / 批量)
 	AdminUpdateSignInStatus(c *gin.Context)
+	BulkUpdateSignInStatus(c *gin.Context)
+	// 管理员移除报名记录
+	AdminRemoveRegistration(c *gin.Context)
+	// 候补队列查看与调整
+	ListWaitlist(c *gin.Context)
+	ReorderWaitlist(c *gin.Context)
+	// 报名审核
+	ApproveRegistrations(c *gin.Context)
+	RejectRegistrations(c *gin.Context)
+	// 参与者查询自己的报名状态
+	GetMyRegistrationStatus(c *gin.Context)
+	// CSV 批量导入报名和签到
+	ImportRegistrations(c *gin.Context)
+	ImportSignIns(c *gin.Context)
 }
 
 type registrationHandlerImpl struct {
@@ -32,6 +52,42 @@ func NewRegistrationHandler(svc service.RegistrationService) RegistrationHandler
 	return &registrationHandlerImpl{svc: svc}
 }
 
+// toRegistrationResponse 将 model.Registration 转换为 model.RegistrationResponse DTO
+func toRegistrationResponse(registration *model.Registration) model.RegistrationResponse {
+	return model.RegistrationResponse{
+		ID:                   registration.ID,
+		ActivityID:           registration.ActivityID,
+		ParticipantName:      registration.ParticipantName,
+		ParticipantPhone:     registration.ParticipantPhone,
+		ParticipantCollege:   registration.ParticipantCollege,
+		ParticipantStudentID: registration.ParticipantStudentID,
+		ParticipantEmail:     registration.ParticipantEmail,
+		RegisteredAt:         registration.RegisteredAt,
+		Status:               registration.Status,
+		WaitlistPosition:     registration.WaitlistPosition,
+		IsSignedIn:           registration.IsSignedIn,
+		SignedInAt:           registration.SignedInAt,
+		IsLate:               registration.IsLate,
+		SignedOutAt:          registration.SignedOutAt,
+		LeftEarly:            registration.LeftEarly,
+		AttendedSeconds:      registration.AttendedSeconds,
+		CancelledAt:          registration.CancelledAt,
+		ReviewedAt:           registration.ReviewedAt,
+		ReviewReason:         registration.ReviewReason,
+		Answers:              registration.Answers,
+		ManageToken:          registration.ManageToken,
+	}
+}
+
+// toRegistrationResponseList 将 []*model.Registration 转换为 DTO 列表
+func toRegistrationResponseList(registrations []*model.Registration) []model.RegistrationResponse {
+	list := make([]model.RegistrationResponse, len(registrations))
+	for i, registration := range registrations {
+		list[i] = toRegistrationResponse(registration)
+	}
+	return list
+}
+
 // Register godoc
 // @Summary 参与者报名活动
 // @Description 用户通过活动ID和个人信息进行报名
@@ -40,9 +96,10 @@ func NewRegistrationHandler(svc service.RegistrationService) RegistrationHandler
 // @Produce json
 // @Param activity_id path int true "活动ID"
 // @Param request body model.CreateRegistrationRequest true "报名请求"
-// @Success 200 {object} model.RegistrationResponse "报名成功，返回报名记录"
+// @Success 200 {object} model.RegistrationResponse "报名成功，返回报名记录 (人数已满时 status 为 WAITLISTED，需要审核的活动为 PENDING；快速报名活动的记录异步写入，id 为 0)"
 // @Failure 400 {object} gin.H "请求参数错误或活动ID格式错误"
-// @Failure 409 {object} gin.H "重复报名或人数已满"
+// @Failure 403 {object} gin.H "报名尚未开始、已截止或不符合报名条件"
+// @Failure 409 {object} gin.H "重复报名"
 // @Failure 500 {object} gin.H "内部系统错误"
 // @Router /activities/{activity_id}/register [post]
 func (h *registrationHandlerImpl) Register(c *gin.Context) {
@@ -69,21 +126,88 @@ func (h *registrationHandlerImpl) Register(c *gin.Context) {
 			utils.Error(c, http.StatusConflict, "您已报名该活动，请勿重复操作")
 			return
 		}
+		if errors.Is(err, service.ErrRegistrationRejected) {
+			utils.Error(c, http.StatusConflict, "您的报名申请未通过审核，不能重新报名")
+			return
+		}
 		if errors.Is(err, service.ErrRegistrationMaxed) {
 			utils.Error(c, http.StatusConflict, "抱歉，该活动报名人数已满")
 			return
 		}
+		if errors.Is(err, service.ErrRegistrationNotOpenYet) {
+			utils.Error(c, http.StatusForbidden, "该活动报名尚未开始")
+			return
+		}
 		if errors.Is(err, service.ErrRegistrationNotOpen) {
 			utils.Error(c, http.StatusForbidden, "该活动报名未开始或已截止")
 			return
 		}
+		var formErr *service.FormValidationError
+		if errors.As(err, &formErr) {
+			utils.Error(c, http.StatusBadRequest, "报名表填写有误: "+formErr.Message)
+			return
+		}
+		var eligibilityErr *service.EligibilityError
+		if errors.As(err, &eligibilityErr) {
+			utils.Error(c, http.StatusForbidden, "不符合报名条件: "+eligibilityErr.Message)
+			return
+		}
 		// 其他错误（如活动不存在、数据库错误）
 		utils.Error(c, http.StatusInternalServerError, "报名失败: "+err.Error())
 		return
 	}
 
-	// 报名成功，返回报名记录
-	utils.Success(c, registration)
+	// 报名成功，返回报名记录 (名额已满时为候补记录)
+	utils.Success(c, toRegistrationResponse(registration))
+}
+
+// CancelRegistration godoc
+// @Summary 参与者取消报名
+// @Description 参与者凭报名手机号和报名成功时返回的 manage_token 取消报名，记录会保留为已取消状态
+// @Tags Registration
+// @Accept json
+// @Produce json
+// @Param activity_id path int true "活动ID"
+// @Param request body model.CancelRegistrationRequest true "取消报名请求"
+// @Success 200 {object} gin.H "取消成功"
+// @Failure 400 {object} gin.H "请求参数错误"
+// @Failure 403 {object} gin.H "手机号或凭证错误，或已过取消截止时间"
+// @Failure 409 {object} gin.H "报名已取消"
+// @Router /activities/{activity_id}/register [delete]
+func (h *registrationHandlerImpl) CancelRegistration(c *gin.Context) {
+	activityIDStr := c.Param("activity_id")
+	activityID, err := strconv.ParseUint(activityIDStr, 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "活动ID格式错误")
+		return
+	}
+
+	var req model.CancelRegistrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "请求参数错误: "+err.Error())
+		return
+	}
+
+	if err := h.svc.CancelRegistration(c, uint(activityID), req.Phone, req.Token); err != nil {
+		switch {
+		case errors.Is(err, service.ErrActivityNotFound):
+			utils.Error(c, http.StatusNotFound, "活动不存在")
+		case errors.Is(err, service.ErrInvalidManageToken):
+			utils.Error(c, http.StatusForbidden, "手机号或凭证错误")
+		case errors.Is(err, service.ErrCancellationClosed):
+			utils.Error(c, http.StatusForbidden, "已过取消报名截止时间")
+		case errors.Is(err, service.ErrRegistrationCancelled):
+			utils.Error(c, http.StatusConflict, "该报名已取消，请勿重复操作")
+		case errors.Is(err, service.ErrRegistrationRejected):
+			utils.Error(c, http.StatusConflict, "该报名申请未通过审核，无需取消")
+		default:
+			slog.Error("Failed to cancel registration", "activity_id", activityID, "error", err)
+			utils.Error(c, http.StatusInternalServerError, "取消报名失败: "+err.Error())
+		}
+		return
+	}
+
+	utils.Success(c, gin.H{"message": "报名已取消"})
 }
 
 // ListRegistrations godoc
@@ -162,6 +286,65 @@ func (h *registrationHandlerImpl) ListRegistrations(c *gin.Context) {
 	})
 }
 
+// ExportRegistrations godoc
+// @Summary 导出活动报名表
+// @Description 按与报名列表相同的过滤条件导出活动的全部报名记录 (不分页)，包含签到、签退信息和报名表自定义字段的答案，响应以流式输出；仅超级管理员和活动的组织者可以导出
+// @Tags Registration
+// @Security ApiKeyAuth
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param activity_id path int true "活动ID"
+// @Param format query string false "导出格式 (csv/xlsx)" default(csv)
+// @Param status query string false "报名状态"
+// @Param phone query string false "参与者手机号"
+// @Param is_signed_in query bool false "签到状态"
+// @Param is_late query bool false "是否迟到"
+// @Param left_early query bool false "是否早退"
+// @Success 200 {file} file "报名表文件"
+// @Failure 400 {object} gin.H "请求参数错误或导出格式无效"
+// @Failure 404 {object} gin.H "活动不存在"
+// @Router /admin/activities/{activity_id}/registrations/export [get]
+func (h *registrationHandlerImpl) ExportRegistrations(c *gin.Context) {
+	params := &model.ListRegistrationsParams{}
+	if err := c.ShouldBindQuery(params); err != nil {
+		utils.Error(c, http.StatusBadRequest, "查询参数错误: "+err.Error())
+		return
+	}
+	activityID, err := strconv.ParseUint(c.Param("activity_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "活动ID格式错误")
+		return
+	}
+	params.ActivityID = uint(activityID)
+
+	format := model.RegistrationExportFormat(c.DefaultQuery("format", string(model.RegistrationExportCSV)))
+	contentType := "text/csv; charset=utf-8"
+	if format == model.RegistrationExportXLSX {
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="registrations-%d.%s"`, activityID, format))
+
+	if err := h.svc.ExportRegistrations(c, params, format, c.Writer); err != nil {
+		if c.Writer.Written() {
+			// 已开始输出文件，只能中断响应
+			slog.Error("Failed to export registrations", "params", params, "error", err)
+			return
+		}
+		c.Writer.Header().Del("Content-Type")
+		c.Writer.Header().Del("Content-Disposition")
+		switch {
+		case errors.Is(err, service.ErrInvalidExportFormat):
+			utils.Error(c, http.StatusBadRequest, "导出格式无效，只支持 csv 和 xlsx")
+		case errors.Is(err, service.ErrActivityNotFound):
+			utils.Error(c, http.StatusNotFound, "活动不存在")
+		default:
+			slog.Error("Failed to export registrations", "params", params, "error", err)
+			utils.Error(c, http.StatusInternalServerError, "导出报名表失败: "+err.Error())
+		}
+	}
+}
+
 // GetRegistrationByID godoc
 // @Summary 获取单条报名记录详情
 // @Description 管理员根据报名记录ID获取详情
@@ -192,28 +375,20 @@ func (h *registrationHandlerImpl) GetRegistrationByID(c *gin.Context) {
 	}
 
 	// 转换 model.Registration 为 model.RegistrationResponse DTO
-	response := model.RegistrationResponse{
-		ID:                 registration.ID,
-		ActivityID:         registration.ActivityID,
-		ParticipantName:    registration.ParticipantName,
-		ParticipantPhone:   registration.ParticipantPhone,
-		ParticipantCollege: registration.ParticipantCollege,
-		RegisteredAt:       registration.RegisteredAt,
-		IsSignedIn:         registration.IsSignedIn,
-	}
-
-	utils.Success(c, response)
+	utils.Success(c, toRegistrationResponse(registration))
 }
 
 // SignIn godoc
 // @Summary 参与者签到
-// @Description 参与者通过活动ID和手机号进行签到（此功能已禁用）
+// @Description 参与者扫描大屏二维码，凭手机号和二维码中的动态码签到
 // @Tags Registration
 // @Accept json
 // @Produce json
 // @Param activity_id path int true "活动ID"
 // @Param request body model.SignInRequest true "签到请求，包含手机号"
 // @Success 200 {object} gin.H "签到成功"
+// @Failure 401 {object} gin.H "签到码无效或已过期"
+// @Failure 429 {object} gin.H "动态码错误次数过多，暂时锁定"
 // @Router /activities/{activity_id}/signin [post]
 func (h *registrationHandlerImpl) SignIn(c *gin.Context) {
 	activityIDStr := c.Param("activity_id")
@@ -229,31 +404,25 @@ func (h *registrationHandlerImpl) SignIn(c *gin.Context) {
 		return
 	}
 
-	// 1. 验证Redis中的签到Token
-	isValid, err := redis.VerifyToken(
-		c.Request.Context(),
-		uint(activityID),
-		req.Token,
-	)
-	if err != nil {
-		utils.Error(c, http.StatusInternalServerError, "验证签到Token失败: "+err.Error())
-		return
-	}
-	if !isValid {
-		utils.Error(c, http.StatusUnauthorized, "签到Token无效或已过期")
-		return
-	}
-
-	// 2. 调用Service进行签到
-	err = h.svc.SignIn(c, uint(activityID), req.Phone, req.Token)
+	// 调用Service进行签到 (动态码在 Service 中校验)
+	err = h.svc.SignIn(c, uint(activityID), req.Phone, req.Token, c.ClientIP())
 	if err != nil {
 		slog.Error("Failed to sign in", "activity_id", activityID, "phone", req.Phone, "error", err)
+		if writeAttendanceLocked(c, err) {
+			return
+		}
+		if errors.Is(err, service.ErrInvalidSignInCode) {
+			utils.Error(c, http.StatusUnauthorized, "签到码无效或已过期")
+			return
+		}
 		// 根据不同错误类型返回不同状态码
 		switch err.Error() {
 		case "报名记录未找到或手机号错误":
 			utils.Error(c, http.StatusNotFound, err.Error())
 		case "您已签到，请勿重复操作":
 			utils.Error(c, http.StatusConflict, err.Error())
+		case "您的报名尚未确认，无法签到":
+			utils.Error(c, http.StatusForbidden, err.Error())
 		case "当前时间不在活动时间范围内，无法签到":
 			utils.Error(c, http.StatusForbidden, err.Error())
 		default:
@@ -265,6 +434,73 @@ func (h *registrationHandlerImpl) SignIn(c *gin.Context) {
 	utils.Success(c, gin.H{"message": "签到成功"})
 }
 
+// writeAttendanceLocked 动态码错误次数过多被锁定时返回 429，已处理时返回 true
+func writeAttendanceLocked(c *gin.Context, err error) bool {
+	var lockedErr *service.AttendanceLockedError
+	if !errors.As(err, &lockedErr) {
+		return false
+	}
+	middleware.SetRetryAfter(c, lockedErr.RetryAfter)
+	utils.Error(c, http.StatusTooManyRequests, "动态码错误次数过多，请稍后再试或联系签到人员")
+	return true
+}
+
+// SignOut godoc
+// @Summary 参与者签退
+// @Description 参与者扫描大屏签退二维码，凭手机号和二维码中的动态码签退，系统记录签退时间、是否早退和参与时长
+// @Tags Registration
+// @Accept json
+// @Produce json
+// @Param activity_id path int true "活动ID"
+// @Param request body model.SignOutRequest true "签退请求"
+// @Success 200 {object} gin.H "签退成功"
+// @Failure 401 {object} gin.H "签退码无效或已过期"
+// @Failure 403 {object} gin.H "不在可签退的时间范围内"
+// @Failure 404 {object} gin.H "活动或报名记录不存在"
+// @Failure 409 {object} gin.H "尚未签到或已签退"
+// @Failure 429 {object} gin.H "动态码错误次数过多，暂时锁定"
+// @Router /activities/{activity_id}/signout [post]
+func (h *registrationHandlerImpl) SignOut(c *gin.Context) {
+	activityIDStr := c.Param("activity_id")
+	activityID, err := strconv.ParseUint(activityIDStr, 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "活动ID格式错误")
+		return
+	}
+
+	var req model.SignOutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "请求参数错误: "+err.Error())
+		return
+	}
+
+	if err := h.svc.SignOut(c, uint(activityID), req.Phone, req.Token, c.ClientIP()); err != nil {
+		if writeAttendanceLocked(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, service.ErrActivityNotFound):
+			utils.Error(c, http.StatusNotFound, "活动不存在")
+		case errors.Is(err, service.ErrSignOutNotAvailable):
+			utils.Error(c, http.StatusForbidden, "当前时间不在可签退的时间范围内")
+		case errors.Is(err, service.ErrInvalidSignOutCode):
+			utils.Error(c, http.StatusUnauthorized, "签退码无效或已过期")
+		case errors.Is(err, service.ErrRegistrationNotFound):
+			utils.Error(c, http.StatusNotFound, "报名记录未找到或手机号错误")
+		case errors.Is(err, service.ErrNotSignedIn):
+			utils.Error(c, http.StatusConflict, "您尚未签到，无法签退")
+		case errors.Is(err, service.ErrAlreadySignedOut):
+			utils.Error(c, http.StatusConflict, "您已签退，请勿重复操作")
+		default:
+			slog.Error("Failed to sign out", "activity_id", activityID, "phone", req.Phone, "error", err)
+			utils.Error(c, http.StatusInternalServerError, "签退失败: "+err.Error())
+		}
+		return
+	}
+
+	utils.Success(c, gin.H{"message": "签退成功"})
+}
+
 // AdminUpdateSignInStatus godoc
 // @Summary 管理员更新报名记录的签到状态
 // @Description 管理员可以跳过token认证直接修改报名记录的签到状态
@@ -293,7 +529,7 @@ func (h *registrationHandlerImpl) AdminUpdateSignInStatus(c *gin.Context) {
 		return
 	}
 
-	err = h.svc.UpdateSignInStatusByAdmin(c.Request.Context(), uint(registrationID), req.IsSignedIn)
+	err = h.svc.UpdateSignInStatusByAdmin(c.Request.Context(), uint(registrationID), *req.IsSignedIn)
 	if err != nil {
 		if errors.Is(err, service.ErrRegistrationNotFound) {
 			utils.Error(c, http.StatusNotFound, "报名记录不存在")
@@ -305,3 +541,451 @@ func (h *registrationHandlerImpl) AdminUpdateSignInStatus(c *gin.Context) {
 
 	utils.Success(c, gin.H{"message": "签到状态更新成功"})
 }
+
+// BulkUpdateSignInStatus godoc
+// @Summary 管理员批量修改签到状态
+// @Description 按报名ID或手机号批量签到或取消签到，在一个事务中执行并返回每条的处理结果。
+// @Description 签到时所有记录使用同一个签到时间，只有已确认的报名可以签到；取消签到时清空签到时间和签退记录
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param activity_id path int true "活动ID"
+// @Param request body model.BulkUpdateSignInRequest true "批量修改签到状态请求"
+// @Success 200 {object} gin.H "每条的处理结果 (list) 和成功数 (succeeded)"
+// @Failure 400 {object} gin.H "请求参数错误"
+// @Failure 404 {object} gin.H "活动不存在"
+// @Failure 409 {object} gin.H "活动未发布或已取消"
+// @Security Bearer
+// @Router /admin/activities/{activity_id}/registrations/sign_in [put]
+func (h *registrationHandlerImpl) BulkUpdateSignInStatus(c *gin.Context) {
+	activityIDStr := c.Param("activity_id")
+	activityID, err := strconv.ParseUint(activityIDStr, 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "活动ID格式错误")
+		return
+	}
+
+	var req model.BulkUpdateSignInRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "请求参数错误: "+err.Error())
+		return
+	}
+	if len(req.RegistrationIDs) == 0 && len(req.Phones) == 0 {
+		utils.Error(c, http.StatusBadRequest, "请提供报名ID (registration_ids) 或手机号 (phones)")
+		return
+	}
+
+	results, err := h.svc.BulkUpdateSignInStatus(c, uint(activityID), &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrActivityNotFound):
+			utils.Error(c, http.StatusNotFound, "活动不存在")
+		case errors.Is(err, service.ErrSignInNotAllowed):
+			utils.Error(c, http.StatusConflict, "活动未发布或已取消，不能修改签到状态")
+		default:
+			slog.Error("Failed to bulk update sign-in status", "activity_id", activityID, "error", err)
+			utils.Error(c, http.StatusInternalServerError, "批量修改签到状态失败: "+err.Error())
+		}
+		return
+	}
+
+	succeeded := 0
+	for _, result := range results {
+		if result.Success {
+			succeeded++
+		}
+	}
+	utils.Success(c, gin.H{
+		"list":      results,
+		"succeeded": succeeded,
+	})
+}
+
+// AdminRemoveRegistration godoc
+// @Summary 管理员移除报名记录
+// @Description 将报名记录标记为已取消并释放名额，空出的名额由候补队列按顺序递补
+// @Tags Admin
+// @Produce json
+// @Param registration_id path int true "报名记录ID"
+// @Success 200 {object} gin.H "移除成功"
+// @Failure 404 {object} gin.H "报名记录不存在"
+// @Failure 500 {object} gin.H "内部系统错误"
+// @Security Bearer
+// @Router /admin/registrations/{registration_id} [delete]
+func (h *registrationHandlerImpl) AdminRemoveRegistration(c *gin.Context) {
+	registrationIDStr := c.Param("registration_id")
+	registrationID, err := strconv.ParseUint(registrationIDStr, 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "报名ID格式错误")
+		return
+	}
+
+	if err := h.svc.RemoveRegistrationByAdmin(c, uint(registrationID)); err != nil {
+		if errors.Is(err, service.ErrRegistrationNotFound) {
+			utils.Error(c, http.StatusNotFound, "报名记录不存在")
+			return
+		}
+		if errors.Is(err, service.ErrRegistrationCancelled) {
+			utils.Error(c, http.StatusConflict, "该报名已取消")
+			return
+		}
+		if errors.Is(err, service.ErrRegistrationRejected) {
+			utils.Error(c, http.StatusConflict, "该报名申请未通过审核")
+			return
+		}
+		slog.Error("Failed to remove registration", "id", registrationID, "error", err)
+		utils.Error(c, http.StatusInternalServerError, "移除报名记录失败: "+err.Error())
+		return
+	}
+
+	utils.Success(c, gin.H{"message": "报名记录已移除"})
+}
+
+// ListWaitlist godoc
+// @Summary 管理员查看活动候补队列
+// @Tags Admin
+// @Produce json
+// @Param activity_id path int true "活动ID"
+// @Success 200 {object} gin.H{list=[]model.RegistrationResponse,total=int} "按递补顺序排列的候补记录"
+// @Failure 404 {object} gin.H "活动不存在"
+// @Security Bearer
+// @Router /admin/activities/{activity_id}/waitlist [get]
+func (h *registrationHandlerImpl) ListWaitlist(c *gin.Context) {
+	activityIDStr := c.Param("activity_id")
+	activityID, err := strconv.ParseUint(activityIDStr, 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "活动ID格式错误")
+		return
+	}
+
+	list, err := h.svc.ListWaitlist(c, uint(activityID))
+	if err != nil {
+		if errors.Is(err, service.ErrActivityNotFound) {
+			utils.Error(c, http.StatusNotFound, "活动不存在")
+			return
+		}
+		slog.Error("Failed to list waitlist", "activity_id", activityID, "error", err)
+		utils.Error(c, http.StatusInternalServerError, "查询候补队列失败: "+err.Error())
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"list":  toRegistrationResponseList(list),
+		"total": len(list),
+	})
+}
+
+// ReorderWaitlist godoc
+// @Summary 管理员调整候补队列顺序
+// @Description 传入当前全部候补记录ID，按数组顺序重新排列递补次序
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param activity_id path int true "活动ID"
+// @Param request body model.ReorderWaitlistRequest true "新的候补顺序"
+// @Success 200 {object} gin.H{list=[]model.RegistrationResponse,total=int} "调整后的候补队列"
+// @Failure 400 {object} gin.H "请求参数错误或ID与候补队列不一致"
+// @Failure 404 {object} gin.H "活动不存在"
+// @Security Bearer
+// @Router /admin/activities/{activity_id}/waitlist [put]
+func (h *registrationHandlerImpl) ReorderWaitlist(c *gin.Context) {
+	activityIDStr := c.Param("activity_id")
+	activityID, err := strconv.ParseUint(activityIDStr, 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "活动ID格式错误")
+		return
+	}
+
+	var req model.ReorderWaitlistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "请求参数错误: "+err.Error())
+		return
+	}
+
+	if err := h.svc.ReorderWaitlist(c, uint(activityID), req.RegistrationIDs); err != nil {
+		switch {
+		case errors.Is(err, service.ErrActivityNotFound):
+			utils.Error(c, http.StatusNotFound, "活动不存在")
+		case errors.Is(err, service.ErrWaitlistMismatch):
+			utils.Error(c, http.StatusBadRequest, "报名ID与当前候补队列不一致")
+		default:
+			slog.Error("Failed to reorder waitlist", "activity_id", activityID, "error", err)
+			utils.Error(c, http.StatusInternalServerError, "调整候补队列失败: "+err.Error())
+		}
+		return
+	}
+
+	list, err := h.svc.ListWaitlist(c, uint(activityID))
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "调整成功但查询最新候补队列失败: "+err.Error())
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"list":  toRegistrationResponseList(list),
+		"total": len(list),
+	})
+}
+
+// ApproveRegistrations godoc
+// @Summary 管理员批量通过报名申请
+// @Description 在活动行锁内逐条审核，名额或学院名额已满的记录保持待审核状态，结果中说明原因
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param activity_id path int true "活动ID"
+// @Param request body model.ReviewRegistrationsRequest true "报名ID列表与审核意见"
+// @Success 200 {object} gin.H{list=[]model.ReviewResult,approved=int} "每条记录的审核结果"
+// @Failure 400 {object} gin.H "请求参数错误"
+// @Failure 404 {object} gin.H "活动不存在"
+// @Failure 409 {object} gin.H "活动当前状态不能审核"
+// @Security Bearer
+// @Router /admin/activities/{activity_id}/registrations/approve [post]
+func (h *registrationHandlerImpl) ApproveRegistrations(c *gin.Context) {
+	h.reviewRegistrations(c, true)
+}
+
+// RejectRegistrations godoc
+// @Summary 管理员批量拒绝报名申请
+// @Description 审核意见 (可选) 对参与者可见，被拒绝的手机号不能再次报名该活动
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param activity_id path int true "活动ID"
+// @Param request body model.ReviewRegistrationsRequest true "报名ID列表与审核意见"
+// @Success 200 {object} gin.H{list=[]model.ReviewResult,rejected=int} "每条记录的审核结果"
+// @Failure 400 {object} gin.H "请求参数错误"
+// @Failure 404 {object} gin.H "活动不存在"
+// @Failure 409 {object} gin.H "活动当前状态不能审核"
+// @Security Bearer
+// @Router /admin/activities/{activity_id}/registrations/reject [post]
+func (h *registrationHandlerImpl) RejectRegistrations(c *gin.Context) {
+	h.reviewRegistrations(c, false)
+}
+
+// reviewRegistrations 批量通过 / 拒绝的公共实现
+func (h *registrationHandlerImpl) reviewRegistrations(c *gin.Context, approve bool) {
+	activityIDStr := c.Param("activity_id")
+	activityID, err := strconv.ParseUint(activityIDStr, 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "活动ID格式错误")
+		return
+	}
+
+	var req model.ReviewRegistrationsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "请求参数错误: "+err.Error())
+		return
+	}
+
+	results, err := h.svc.ReviewRegistrations(c, uint(activityID), req.RegistrationIDs, approve, req.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrActivityNotFound):
+			utils.Error(c, http.StatusNotFound, "活动不存在")
+		case errors.Is(err, service.ErrReviewNotAllowed):
+			utils.Error(c, http.StatusConflict, "只能审核报名中或已截止报名的活动")
+		default:
+			slog.Error("Failed to review registrations", "activity_id", activityID, "approve", approve, "error", err)
+			utils.Error(c, http.StatusInternalServerError, "审核报名失败: "+err.Error())
+		}
+		return
+	}
+
+	succeeded := 0
+	for _, result := range results {
+		if result.Success {
+			succeeded++
+		}
+	}
+	key := "rejected"
+	if approve {
+		key = "approved"
+	}
+	utils.Success(c, gin.H{
+		"list": results,
+		key:    succeeded,
+	})
+}
+
+// GetMyRegistrationStatus godoc
+// @Summary 参与者查询报名状态
+// @Description 参与者凭报名手机号和 manage_token 查询报名状态 (待审核、已确认、候补、已拒绝、已取消) 及审核意见
+// @Tags Registration
+// @Accept json
+// @Produce json
+// @Param activity_id path int true "活动ID"
+// @Param request body model.RegistrationStatusRequest true "手机号与管理凭证"
+// @Success 200 {object} model.RegistrationStatusResponse "报名状态"
+// @Failure 400 {object} gin.H "请求参数错误"
+// @Failure 403 {object} gin.H "手机号或凭证错误"
+// @Router /activities/{activity_id}/register/status [post]
+func (h *registrationHandlerImpl) GetMyRegistrationStatus(c *gin.Context) {
+	activityIDStr := c.Param("activity_id")
+	activityID, err := strconv.ParseUint(activityIDStr, 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "活动ID格式错误")
+		return
+	}
+
+	var req model.RegistrationStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "请求参数错误: "+err.Error())
+		return
+	}
+
+	reg, err := h.svc.GetMyRegistration(c, uint(activityID), req.Phone, req.Token)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidManageToken) {
+			utils.Error(c, http.StatusForbidden, "手机号或凭证错误")
+			return
+		}
+		slog.Error("Failed to query registration status", "activity_id", activityID, "error", err)
+		utils.Error(c, http.StatusInternalServerError, "查询报名状态失败: "+err.Error())
+		return
+	}
+
+	utils.Success(c, model.RegistrationStatusResponse{
+		ActivityID:       reg.ActivityID,
+		Status:           reg.Status,
+		WaitlistPosition: reg.WaitlistPosition,
+		RegisteredAt:     reg.RegisteredAt,
+		ReviewedAt:       reg.ReviewedAt,
+		ReviewReason:     reg.ReviewReason,
+		IsSignedIn:       reg.IsSignedIn,
+		SignedOutAt:      reg.SignedOutAt,
+		CancelledAt:      reg.CancelledAt,
+	})
+}
+
+// maxImportUploadSize 报名和签到导入文件的大小上限
+const maxImportUploadSize = 2 << 20
+
+// ImportRegistrations godoc
+// @Summary 从 CSV 批量导入报名
+// @Description 上传 CSV 文件批量创建报名，表头需包含 name (姓名)、phone (手机号)、college (学院) 列，可选 student_id (学号) 列，
+// @Description 其余列按报名表字段的 key 或 label 作为自定义字段答案。每行按报名接口相同的规则校验，名额已满时进入候补队列。
+// @Description dry_run=true 时只返回每行的校验结果，不保存；atomic=true 时任意一行失败则全部不保存
+// @Tags Admin
+// @Accept multipart/form-data
+// @Produce json
+// @Param activity_id path int true "活动ID"
+// @Param file formData file true "报名 CSV 文件"
+// @Param dry_run query bool false "试运行"
+// @Param atomic query bool false "整体导入"
+// @Success 200 {object} model.ImportReport "导入结果"
+// @Failure 400 {object} gin.H "文件缺失或格式错误"
+// @Failure 403 {object} gin.H "活动不在报名中"
+// @Failure 404 {object} gin.H "活动不存在"
+// @Failure 413 {object} gin.H "文件过大"
+// @Security Bearer
+// @Router /admin/activities/{activity_id}/registrations/import [post]
+func (h *registrationHandlerImpl) ImportRegistrations(c *gin.Context) {
+	activityID, opts, file, ok := bindImportRequest(c)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	rows, err := service.ParseRegistrationImportCSV(file)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "导入文件格式错误: "+err.Error())
+		return
+	}
+
+	report, err := h.svc.ImportRegistrations(c, activityID, rows, opts)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrActivityNotFound):
+			utils.Error(c, http.StatusNotFound, "活动不存在")
+		case errors.Is(err, service.ErrRegistrationNotOpenYet):
+			utils.Error(c, http.StatusForbidden, "该活动报名尚未开始")
+		case errors.Is(err, service.ErrRegistrationNotOpen), errors.Is(err, service.ErrActivityRegistrationOver):
+			utils.Error(c, http.StatusForbidden, "该活动报名未开始或已截止")
+		default:
+			slog.Error("Failed to import registrations", "activity_id", activityID, "error", err)
+			utils.Error(c, http.StatusInternalServerError, "导入报名失败: "+err.Error())
+		}
+		return
+	}
+	utils.Success(c, report)
+}
+
+// ImportSignIns godoc
+// @Summary 从 CSV 批量导入签到
+// @Description 上传 CSV 文件按手机号批量标记签到，表头需包含 phone (手机号) 列，可选 signed_in_at (签到时间，如 2006-01-02 15:04:05) 列，
+// @Description 为空时使用导入时间。只有已确认的报名可以签到，已签到的行标记为重复。
+// @Description dry_run=true 时只返回每行的校验结果，不保存；atomic=true 时任意一行失败则全部不保存
+// @Tags Admin
+// @Accept multipart/form-data
+// @Produce json
+// @Param activity_id path int true "活动ID"
+// @Param file formData file true "签到 CSV 文件"
+// @Param dry_run query bool false "试运行"
+// @Param atomic query bool false "整体导入"
+// @Success 200 {object} model.ImportReport "导入结果"
+// @Failure 400 {object} gin.H "文件缺失或格式错误"
+// @Failure 404 {object} gin.H "活动不存在"
+// @Failure 409 {object} gin.H "活动未发布或已取消"
+// @Failure 413 {object} gin.H "文件过大"
+// @Security Bearer
+// @Router /admin/activities/{activity_id}/sign-ins/import [post]
+func (h *registrationHandlerImpl) ImportSignIns(c *gin.Context) {
+	activityID, opts, file, ok := bindImportRequest(c)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	rows, err := service.ParseSignInImportCSV(file)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "导入文件格式错误: "+err.Error())
+		return
+	}
+
+	report, err := h.svc.ImportSignIns(c, activityID, rows, opts)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrActivityNotFound):
+			utils.Error(c, http.StatusNotFound, "活动不存在")
+		case errors.Is(err, service.ErrSignInNotAllowed):
+			utils.Error(c, http.StatusConflict, "活动未发布或已取消，不能导入签到")
+		default:
+			slog.Error("Failed to import sign-ins", "activity_id", activityID, "error", err)
+			utils.Error(c, http.StatusInternalServerError, "导入签到失败: "+err.Error())
+		}
+		return
+	}
+	utils.Success(c, report)
+}
+
+// bindImportRequest 解析导入接口的活动ID、导入选项和上传的文件，失败时已写入错误响应
+func bindImportRequest(c *gin.Context) (uint, *model.ImportOptions, multipart.File, bool) {
+	activityID, err := strconv.ParseUint(c.Param("activity_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "活动ID格式错误")
+		return 0, nil, nil, false
+	}
+
+	var opts model.ImportOptions
+	if err := c.ShouldBindQuery(&opts); err != nil {
+		utils.Error(c, http.StatusBadRequest, "请求参数错误: "+err.Error())
+		return 0, nil, nil, false
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "请上传导入文件 (file)")
+		return 0, nil, nil, false
+	}
+	if fileHeader.Size > maxImportUploadSize {
+		utils.Error(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("导入文件不能超过 %d MB", maxImportUploadSize>>20))
+		return 0, nil, nil, false
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "读取导入文件失败")
+		return 0, nil, nil, false
+	}
+	return uint(activityID), &opts, file, true
+}
@@ -20,10 +20,10 @@ func GetCors() gin.HandlerFunc {
 
 		// **AllowHeaders**: 允许的请求头
 		// 通常需要允许 Content-Type、Authorization（用于 token/JWT）等
-		AllowHeaders: []string{"Origin", "Content-Type", "Authorization", "Accept"},
+		AllowHeaders: []string{"Origin", "Content-Type", "Authorization", "Accept", HeaderIdempotencyKey},
 
 		// **ExposeHeaders**: 允许浏览器访问的响应头（非必需）
-		ExposeHeaders: []string{"Content-Length"},
+		ExposeHeaders: []string{"Content-Length", "Retry-After", HeaderIdempotentReplayed},
 
 		// **AllowCredentials**: 是否允许携带 Cookie 或认证信息
 		// 如果设置为 true，AllowOrigins 中就不能使用通配符 "*"
@@ -0,0 +1,159 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/frozenf1sh/gostudent/pkg/redis"
+	"github.com/frozenf1sh/gostudent/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// HeaderIdempotencyKey 客户端生成的幂等键请求头
+	HeaderIdempotencyKey = "Idempotency-Key"
+	// HeaderIdempotentReplayed 响应为重放首次响应时设置的响应头
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+	maxIdempotentBodySize   = 1 << 20 // 携带幂等键的请求体上限
+	// idempotencyInFlightLease 首次请求处理期间占位记录的保留时长，应大于请求的最长处理时间；
+	// 进程在处理期间退出 (来不及释放幂等键) 时，客户端最多等待这么久即可用同一键重试
+	idempotencyInFlightLease = time.Minute
+)
+
+// responseRecorder 在写出响应的同时保存一份响应体，用于幂等重放
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency 幂等键中间件：请求携带 Idempotency-Key 时，首次响应在 Redis 中保留 ttl 时长
+// 窗口期内同一键、同一请求体的重试直接重放首次响应；同一键、不同请求体返回 422；
+// 首次请求仍在处理中返回 409 (占位记录只保留 idempotencyInFlightLease)。服务端错误 (5xx) 不保存，允许客户端用同一键重试。
+// 未携带请求头的请求不受影响；Redis 不可用时放行请求。ttl 未配置时默认 24 小时
+func Idempotency(ttl time.Duration) gin.HandlerFunc {
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+
+	return func(c *gin.Context) {
+		idempotencyKey := c.GetHeader(HeaderIdempotencyKey)
+		if idempotencyKey == "" {
+			c.Next()
+			return
+		}
+		if len(idempotencyKey) > maxIdempotencyKeyLength {
+			utils.Error(c, http.StatusBadRequest, "Idempotency-Key 过长")
+			c.Abort()
+			return
+		}
+
+		// 1. 读取请求体计算指纹，并放回供后续绑定
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxIdempotentBodySize))
+		if err != nil {
+			utils.Error(c, http.StatusRequestEntityTooLarge, "请求体过大或读取失败")
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		// 幂等键按接口 (方法 + 路径) 隔离，同一个键可以用于不同活动的报名
+		key := hashIdempotencyKey(c.Request.Method + " " + c.Request.URL.Path + "\n" + idempotencyKey)
+		fingerprint := requestFingerprint(body)
+
+		// 2. 占用幂等键
+		ctx := c.Request.Context()
+		record, err := redis.BeginIdempotentRequest(ctx, key, fingerprint, idempotencyInFlightLease)
+		if err != nil {
+			slog.Warn("幂等键检查失败，按普通请求处理", "err", err)
+			c.Next()
+			return
+		}
+		if record != nil {
+			replayIdempotentResponse(c, record, fingerprint)
+			return
+		}
+
+		// 3. 首次请求：执行并保存响应
+		// 客户端断开后仍需保存结果；处理失败 (5xx 或 panic) 时释放幂等键，允许用同一键重试
+		ctx = context.WithoutCancel(ctx)
+		saved := false
+		defer func() {
+			if saved {
+				return
+			}
+			if err := redis.AbortIdempotentRequest(ctx, key); err != nil {
+				slog.Warn("释放幂等键失败", "err", err)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		if recorder.Status() >= http.StatusInternalServerError {
+			return
+		}
+		err = redis.CompleteIdempotentRequest(ctx, key, &redis.IdempotentRecord{
+			Fingerprint: fingerprint,
+			Status:      recorder.Status(),
+			ContentType: recorder.Header().Get("Content-Type"),
+			Body:        recorder.body.Bytes(),
+		}, ttl)
+		if err != nil {
+			slog.Warn("保存幂等响应失败", "err", err)
+			return
+		}
+		saved = true
+	}
+}
+
+// replayIdempotentResponse 处理幂等键已存在的请求
+func replayIdempotentResponse(c *gin.Context, record *redis.IdempotentRecord, fingerprint string) {
+	defer c.Abort()
+	if record.Fingerprint != fingerprint {
+		utils.Error(c, http.StatusUnprocessableEntity, "该 Idempotency-Key 已用于内容不同的请求")
+		return
+	}
+	if !record.Completed {
+		SetRetryAfter(c, time.Second)
+		utils.Error(c, http.StatusConflict, "相同 Idempotency-Key 的请求正在处理中，请稍后重试")
+		return
+	}
+	c.Header(HeaderIdempotentReplayed, "true")
+	c.Data(record.Status, record.ContentType, record.Body)
+}
+
+// requestFingerprint 计算请求体指纹；JSON 请求体先规范化，字段顺序和空白不影响结果
+func requestFingerprint(body []byte) string {
+	var v any
+	if err := json.Unmarshal(body, &v); err == nil {
+		if canonical, err := json.Marshal(v); err == nil {
+			body = canonical
+		}
+	}
+	return hashIdempotencyKey(string(body))
+}
+
+func hashIdempotencyKey(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
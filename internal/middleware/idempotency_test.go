@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/frozenf1sh/gostudent/pkg/redis"
+	"github.com/gin-gonic/gin"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// 首次请求处理期间进程退出 (占位记录未释放) 时，幂等键在租期结束后即可重试，完成后的响应保留 ttl
+func TestIdempotencyInFlightLeaseExpires(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mr := miniredis.RunT(t)
+	redis.Client = goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+
+	calls := 0
+	r := gin.New()
+	r.POST("/register", Idempotency(24*time.Hour), func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusOK, gin.H{"calls": calls})
+	})
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/register", strings.NewReader(`{"phone":"13800138000"}`))
+		req.Header.Set(HeaderIdempotencyKey, "key-1")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// 模拟进程在处理首次请求时退出：只留下占位记录
+	key := hashIdempotencyKey(http.MethodPost + " /register\n" + "key-1")
+	if _, err := redis.BeginIdempotentRequest(context.Background(), key, requestFingerprint([]byte(`{"phone":"13800138000"}`)), idempotencyInFlightLease); err != nil {
+		t.Fatal(err)
+	}
+	if w := send(); w.Code != http.StatusConflict {
+		t.Fatalf("retry while in flight: status = %d, want 409", w.Code)
+	}
+
+	mr.FastForward(idempotencyInFlightLease + time.Second)
+	if w := send(); w.Code != http.StatusOK || calls != 1 {
+		t.Fatalf("retry after lease expired: status = %d, calls = %d", w.Code, calls)
+	}
+	if ttl := mr.TTL("idem:" + key); ttl < 23*time.Hour {
+		t.Errorf("completed record ttl = %s, want the full 24h", ttl)
+	}
+
+	// 完成后的重试直接重放
+	w := send()
+	if w.Code != http.StatusOK || w.Header().Get(HeaderIdempotentReplayed) != "true" || calls != 1 {
+		t.Errorf("replay: status = %d, replayed = %q, calls = %d", w.Code, w.Header().Get(HeaderIdempotentReplayed), calls)
+	}
+}
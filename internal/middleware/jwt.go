@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/frozenf1sh/gostudent/internal/service"
 	"github.com/frozenf1sh/gostudent/pkg/utils"
 	"github.com/gin-gonic/gin"
 )
@@ -14,18 +15,33 @@ import (
 // ContextKeyAdminID 用于存储 AdminID 的 Context Key
 const ContextKeyAdminID = "admin_id"
 
+// ContextKeyAdminRole 用于存储管理员角色的 Context Key
+const ContextKeyAdminRole = "admin_role"
+
+// ContextKeyTokenID 用于存储当前访问令牌 jti 的 Context Key
+const ContextKeyTokenID = "token_jti"
+
+// ContextKeyTokenFamily 用于存储当前访问令牌所属家族的 Context Key
+const ContextKeyTokenFamily = "token_family"
+
+// ContextKeyTokenExpiresAt 用于存储当前访问令牌过期时间的 Context Key
+const ContextKeyTokenExpiresAt = "token_expires_at"
+
 // Claims Data Key: 令牌类型
 const ClaimsDataKeyType = "type"
 
 // Claims Data Key: Admin ID
 const ClaimsDataKeyAdminID = "admin_id"
 
+// Claims Data Key: 令牌家族 ID
+const ClaimsDataKeyFamily = "fid"
+
 // Claim Data Value: 管理员令牌类型的值
 const ClaimTypeAdmin = "admin_login"
 
 // JWTAuthAdmin 是一个用于校验 Admin JWT Token 的 Gin 中间件
-// 职责: 校验签名、过期时间、令牌类型，并提取 AdminID
-func JWTAuthAdmin() gin.HandlerFunc {
+// 职责: 校验签名、过期时间、令牌类型及吊销状态，提取 AdminID，并从数据库加载管理员当前角色
+func JWTAuthAdmin(adminSvc service.AdminService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 1. 从 Header 中获取 Authorization: Bearer <token>
 		authHeader := c.GetHeader("Authorization")
@@ -76,8 +92,52 @@ func JWTAuthAdmin() gin.HandlerFunc {
 		// 转换为业务所需的 uint 类型
 		adminID := uint(adminIDFloat)
 
-		// 5. 将 AdminID 存储在 Context 中，供后续 Handler 使用
+		// 4.1 检查令牌是否已注销 (jti 黑名单) 或所属家族已被吊销
+		familyID, _ := claims.Data[ClaimsDataKeyFamily].(string)
+		revoked, err := adminSvc.IsTokenRevoked(c.Request.Context(), claims.ID, familyID)
+		if err != nil {
+			slog.Error("检查令牌吊销状态失败", "admin_id", adminID, "error", err)
+			utils.Error(c, http.StatusInternalServerError, "令牌校验失败")
+			c.Abort()
+			return
+		}
+		if revoked {
+			utils.Error(c, http.StatusUnauthorized, "Token 已失效，请重新登录")
+			c.Abort()
+			return
+		}
+
+		// 5. 加载管理员，确保账号仍然存在且未停用，并取得最新角色
+		admin, err := adminSvc.GetByID(c.Request.Context(), adminID)
+		if err != nil || admin == nil {
+			slog.Warn("JWT Token 对应的管理员不存在", "admin_id", adminID)
+			utils.Error(c, http.StatusUnauthorized, "管理员账号不存在")
+			c.Abort()
+			return
+		}
+
+		// 已停用的账号立即失去访问权限
+		if admin.Disabled {
+			utils.Error(c, http.StatusForbidden, "管理员账号已停用")
+			c.Abort()
+			return
+		}
+
+		// 6. 将 AdminID 和角色存储在 Context 中，供后续 Handler 使用
 		c.Set(ContextKeyAdminID, adminID)
+		c.Set(ContextKeyAdminRole, admin.Role)
+		c.Set(ContextKeyTokenID, claims.ID)
+		c.Set(ContextKeyTokenFamily, familyID)
+		if claims.ExpiresAt != nil {
+			c.Set(ContextKeyTokenExpiresAt, claims.ExpiresAt.Time)
+		}
+
+		// 7. 将操作者信息写入请求 Context，供 Service 层记录审计日志
+		c.Request = c.Request.WithContext(service.WithAuditActor(c.Request.Context(), service.AuditActor{
+			AdminID:   adminID,
+			IP:        c.ClientIP(),
+			RequestID: c.GetString(ContextKeyRequestID),
+		}))
 
 		// 可选：将完整的 claims 存储在 Context 中，供需要原始数据的业务层使用
 		// c.Set("jwt_claims_map", claims.Data)
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/frozenf1sh/gostudent/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// tokenBucket 简单的进程内令牌桶
+type tokenBucket struct {
+	mu       sync.Mutex
+	rate     float64 // 每秒补充的令牌数
+	capacity float64 // 桶容量 (允许的突发请求数)
+	tokens   float64
+	last     time.Time
+}
+
+// take 尝试取出一个令牌，失败时返回需要等待的时长
+func (b *tokenBucket) take(now time.Time) (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens = math.Min(b.capacity, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+	return false, wait
+}
+
+// RateLimit 全局令牌桶限流中间件，超出限制时返回 429 并设置 Retry-After
+// ratePerSecond 为每秒补充的令牌数，burst 为桶容量；未配置时默认每秒 5 次、突发 10 次
+func RateLimit(ratePerSecond float64, burst int) gin.HandlerFunc {
+	if ratePerSecond <= 0 {
+		ratePerSecond = 5
+	}
+	if burst <= 0 {
+		burst = 10
+	}
+	bucket := &tokenBucket{
+		rate:     ratePerSecond,
+		capacity: float64(burst),
+		tokens:   float64(burst),
+		last:     time.Now(),
+	}
+
+	return func(c *gin.Context) {
+		if ok, wait := bucket.take(time.Now()); !ok {
+			SetRetryAfter(c, wait)
+			utils.Error(c, http.StatusTooManyRequests, "请求过于频繁，请稍后再试")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// SetRetryAfter 以秒为单位 (向上取整) 设置 Retry-After 响应头
+func SetRetryAfter(c *gin.Context, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+}
@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/frozenf1sh/gostudent/internal/model"
+	"github.com/frozenf1sh/gostudent/internal/service"
+	"github.com/frozenf1sh/gostudent/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// ActivityIDResolver 从请求中解析出被操作的活动ID
+type ActivityIDResolver func(c *gin.Context) (uint, error)
+
+// paramError 路径参数格式错误，对应 400 响应
+type paramError string
+
+func (e paramError) Error() string { return string(e) }
+
+// ActivityAccessChecker 校验管理员能否修改某个活动、能否查看其报名记录 (由 ActivityService 实现)
+type ActivityAccessChecker interface {
+	CheckActivityAccess(ctx context.Context, adminID uint, role model.AdminRole, activityID uint) error
+	CheckRegistrationAccess(ctx context.Context, adminID uint, role model.AdminRole, activityID uint) error
+}
+
+// accessCheck ActivityAccessChecker 中的一种校验
+type accessCheck func(ctx context.Context, adminID uint, role model.AdminRole, activityID uint) error
+
+// RequireRoles 限制只有指定角色的管理员可以访问
+// 必须放在 JWTAuthAdmin 之后使用
+func RequireRoles(roles ...model.AdminRole) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, _ := c.Get(ContextKeyAdminRole)
+		adminRole, ok := role.(model.AdminRole)
+		if !ok || !slices.Contains(roles, adminRole) {
+			utils.Error(c, http.StatusForbidden, "当前角色无权访问该接口")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireActivityAccess 校验管理员对请求所操作的活动拥有修改权限
+// 组织者只能操作自己创建或协办的活动，必须放在 JWTAuthAdmin 之后使用
+func RequireActivityAccess(checker ActivityAccessChecker, resolve ActivityIDResolver) gin.HandlerFunc {
+	return requireAccess(checker.CheckActivityAccess, resolve, false)
+}
+
+// RequireRegistrationAccess 校验管理员可以查看请求所涉及活动的报名记录 (规则见 CheckRegistrationAccess)
+// 解析出的活动ID为 0 表示未指定活动 (跨活动查询)，只有超级管理员可以，必须放在 JWTAuthAdmin 之后使用
+func RequireRegistrationAccess(checker ActivityAccessChecker, resolve ActivityIDResolver) gin.HandlerFunc {
+	return requireAccess(checker.CheckRegistrationAccess, resolve, true)
+}
+
+// requireAccess 解析活动ID并执行权限校验，allowAllForSuper 为 true 时超级管理员可以不指定活动
+func requireAccess(check accessCheck, resolve ActivityIDResolver, allowAllForSuper bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		activityID, err := resolve(c)
+		if err != nil {
+			var pErr paramError
+			switch {
+			case errors.As(err, &pErr):
+				utils.Error(c, http.StatusBadRequest, pErr.Error())
+			case errors.Is(err, service.ErrRegistrationNotFound):
+				utils.Error(c, http.StatusNotFound, "报名记录不存在")
+			default:
+				slog.Error("解析活动ID失败", "error", err)
+				utils.Error(c, http.StatusInternalServerError, "查询报名记录失败")
+			}
+			c.Abort()
+			return
+		}
+
+		adminID, _ := c.Get(ContextKeyAdminID)
+		role, _ := c.Get(ContextKeyAdminRole)
+		id, _ := adminID.(uint)
+		adminRole, _ := role.(model.AdminRole)
+
+		if allowAllForSuper && activityID == 0 {
+			if adminRole != model.AdminRoleSuper {
+				utils.Error(c, http.StatusBadRequest, "请指定活动ID")
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if err := check(c.Request.Context(), id, adminRole, activityID); err != nil {
+			switch {
+			case errors.Is(err, service.ErrActivityNotFound):
+				utils.Error(c, http.StatusNotFound, "活动不存在")
+			case errors.Is(err, service.ErrActivityForbidden):
+				utils.Error(c, http.StatusForbidden, "只能操作自己创建或协办的活动")
+			case errors.Is(err, service.ErrRegistrationsForbidden):
+				utils.Error(c, http.StatusForbidden, "只能查看自己创建或协办的活动的报名记录，签到人员只能在活动签到期间查看")
+			default:
+				slog.Error("校验活动权限失败", "activity_id", activityID, "admin_id", id, "error", err)
+				utils.Error(c, http.StatusInternalServerError, "校验活动权限失败")
+			}
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// ActivityIDFromParam 从路径参数 :activity_id 中解析活动ID
+func ActivityIDFromParam() ActivityIDResolver {
+	return func(c *gin.Context) (uint, error) {
+		activityID, err := strconv.ParseUint(c.Param("activity_id"), 10, 64)
+		if err != nil {
+			return 0, paramError("活动ID格式错误")
+		}
+		return uint(activityID), nil
+	}
+}
+
+// ActivityIDFromQuery 从查询参数 activity_id 中解析活动ID，未填写时为 0
+func ActivityIDFromQuery() ActivityIDResolver {
+	return func(c *gin.Context) (uint, error) {
+		raw := c.Query("activity_id")
+		if raw == "" {
+			return 0, nil
+		}
+		activityID, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return 0, paramError("活动ID格式错误")
+		}
+		return uint(activityID), nil
+	}
+}
+
+// ActivityIDFromRegistration 从路径参数 :registration_id 查出报名记录所属的活动ID
+func ActivityIDFromRegistration(svc service.RegistrationService) ActivityIDResolver {
+	return func(c *gin.Context) (uint, error) {
+		registrationID, err := strconv.ParseUint(c.Param("registration_id"), 10, 64)
+		if err != nil {
+			return 0, paramError("报名ID格式错误")
+		}
+		reg, err := svc.GetRegistrationByID(c.Request.Context(), uint(registrationID))
+		if err != nil {
+			return 0, err
+		}
+		return reg.ActivityID, nil
+	}
+}
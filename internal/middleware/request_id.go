@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"github.com/frozenf1sh/gostudent/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// HeaderRequestID 请求ID的请求头/响应头名称
+const HeaderRequestID = "X-Request-ID"
+
+// ContextKeyRequestID 用于存储请求ID的 Context Key
+const ContextKeyRequestID = "request_id"
+
+// RequestID 为每个请求分配请求ID，优先沿用上游传入的值，并写回响应头
+// 请求ID会记录到审计日志中，便于和访问日志对照排查
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(HeaderRequestID)
+		if requestID == "" || len(requestID) > 64 {
+			requestID, _ = utils.GenerateRandomToken(16)
+		}
+		c.Set(ContextKeyRequestID, requestID)
+		c.Header(HeaderRequestID, requestID)
+		c.Next()
+	}
+}
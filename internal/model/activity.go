@@ -2,7 +2,7 @@ package model
 
 import "time"
 
-// 定义活动的 4 种状态
+// 定义活动的 5 种状态，状态流转规则见 service/activity_lifecycle.go
 type ActivityStatus string
 
 const (
@@ -10,6 +10,35 @@ const (
 	ActivityStatusPublished ActivityStatus = "PUBLISHED" // 已发布报名中
 	ActivityStatusClosed    ActivityStatus = "CLOSED"    // 已截止报名
 	ActivityStatusFinished  ActivityStatus = "FINISHED"  // 活动已结束
+	ActivityStatusCancelled ActivityStatus = "CANCELLED" // 活动已取消
+)
+
+// PublicActivityStatuses 公开接口可见的活动状态，草稿和已取消的活动不对外展示
+var PublicActivityStatuses = []ActivityStatus{
+	ActivityStatusPublished,
+	ActivityStatusClosed,
+	ActivityStatusFinished,
+}
+
+// IsPublicActivityStatus 判断该状态的活动是否对公开接口可见
+func IsPublicActivityStatus(status ActivityStatus) bool {
+	for _, s := range PublicActivityStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// RegistrationState 活动当前的报名阶段，由活动状态和报名时间计算得出，不持久化
+type RegistrationState string
+
+const (
+	RegistrationStateNotPublished RegistrationState = "NOT_PUBLISHED" // 活动未发布
+	RegistrationStateUpcoming     RegistrationState = "UPCOMING"      // 已发布，报名开放时间未到
+	RegistrationStateOpen         RegistrationState = "OPEN"          // 报名中
+	RegistrationStateClosed       RegistrationState = "CLOSED"        // 已截止报名或活动已结束
+	RegistrationStateCancelled    RegistrationState = "CANCELLED"     // 活动已取消
 )
 
 // 对应 'activities' 表，存储活动信息
@@ -24,10 +53,48 @@ type Activity struct {
 	Location    string         `gorm:"type:varchar(255);not null" json:"location"`              // 活动地点
 	Status      ActivityStatus `gorm:"type:varchar(20);not null;default:'DRAFT'" json:"status"` // 活动状态
 
+	// 定时发布 (仅 DRAFT 状态)：到达 PublishAt 后由定时任务发布，失败原因记录在 PublishError 中，不再自动重试
+	PublishAt    *time.Time `gorm:"index" json:"publish_at"`
+	PublishError string     `gorm:"type:varchar(500);not null;default:''" json:"publish_error"`
+
+	// 取消信息 (仅 CANCELLED 状态)
+	CancelReason string     `gorm:"type:varchar(500)" json:"cancel_reason"` // 取消原因
+	CancelledAt  *time.Time `json:"cancelled_at"`                           // 取消时间
+
 	// 报名相关
-	RegistrationDeadline time.Time `gorm:"not null" json:"registration_deadline"`      // 报名截止时间
-	MaxParticipants      int       `gorm:"not null;default:0" json:"max_participants"` // 人数上限 (0表示不限制)
-	RegisteredCount      int       `gorm:"not null;default:0" json:"registered_count"` // 已报名人数
+	RegistrationOpensAt  *time.Time `gorm:"null" json:"registration_opens_at"`          // 报名开放时间 (为空表示发布后立即开放)
+	RegistrationDeadline time.Time  `gorm:"not null" json:"registration_deadline"`      // 报名截止时间
+	MaxParticipants      int        `gorm:"not null;default:0" json:"max_participants"` // 人数上限 (0表示不限制)
+	RegisteredCount      int        `gorm:"not null;default:0" json:"registered_count"` // 已报名人数
+
+	// 快速报名：名额预加载到 Redis，报名请求原子预占名额后异步落库，适用于热门活动
+	FastRegistration bool `gorm:"not null;default:false" json:"fast_registration"`
+	PendingSeats     int  `gorm:"-" json:"-"` // 非持久化字段：已在 Redis 预占但尚未落库的名额数
+
+	// 报名审核：开启后报名先进入待审核状态，审核通过后才占用名额
+	RequiresApproval bool `gorm:"not null;default:false" json:"requires_approval"`
+
+	// 抽签报名：报名期间只登记，报名截止时按学院权重随机抽取中签者，未中签者按抽签顺序进入候补队列
+	Lottery        bool               `gorm:"not null;default:false" json:"lottery"`
+	LotteryWeights map[string]float64 `gorm:"type:json;serializer:json" json:"lottery_weights"` // 学院权重，未列出的学院权重为 1
+	LotteryDrawnAt *time.Time         `gorm:"null" json:"lottery_drawn_at"`                     // 开奖时间，开奖后按先到先得处理新报名
+
+	// 学分：活动结束时为满足考勤条件的报名者发放 (CreditValue 为 0 表示不发放)
+	CreditType       CreditType `gorm:"type:varchar(32)" json:"credit_type"`
+	CreditValue      float64    `gorm:"type:decimal(8,2);not null;default:0" json:"credit_value"`
+	CreditMinMinutes int        `gorm:"not null;default:0" json:"credit_min_minutes"` // 最低参与时长 (分钟)，为 0 时签到即可
+	CreditsGrantedAt *time.Time `gorm:"null" json:"credits_granted_at"`               // 学分发放时间
+
+	// 报名表自定义字段 (姓名、手机号、学院之外)，只能在草稿状态修改
+	FormSchema FormSchema `gorm:"type:json;serializer:json" json:"form_schema"`
+
+	// 报名资格规则 (学院、学院名额、报名名单)，为空表示不限制
+	Eligibility *EligibilityRules `gorm:"type:json;serializer:json" json:"eligibility"`
+
+	// 签到、签退动态码：每个活动独立的密钥，以及大屏展示端使用的展示密钥 (只存哈希)
+	SignInSecret   string `gorm:"type:varchar(64)" json:"-"`
+	SignOutSecret  string `gorm:"type:varchar(64)" json:"-"`
+	DisplayKeyHash string `gorm:"type:varchar(64)" json:"-"`
 
 	// 链接
 	LiveURL       string `gorm:"type:varchar(512)" json:"live_url"`       // 直播链接
@@ -37,9 +104,32 @@ type Activity struct {
 	Admin   Admin `gorm:"foreignKey:AdminID" json:"admin"`
 	AdminID uint  `gorm:"not null" json:"admin_id"` // 外键：创建活动的管理员ID
 
+	// n对n关联：活动-协办组织者 (与创建者拥有相同的修改权限)
+	CoOrganizers []Admin `gorm:"many2many:activity_co_organizers;" json:"-"`
+
 	// 1对n关联：活动-报名记录
 	Registrations []Registration `gorm:"foreignKey:ActivityID;OnDelete:CASCADE" json:"-"`
 
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
+
+// RegistrationStateAt 计算活动在 now 时刻的报名阶段
+func (a *Activity) RegistrationStateAt(now time.Time) RegistrationState {
+	switch a.Status {
+	case ActivityStatusDraft:
+		return RegistrationStateNotPublished
+	case ActivityStatusCancelled:
+		return RegistrationStateCancelled
+	case ActivityStatusPublished:
+		if a.RegistrationOpensAt != nil && now.Before(*a.RegistrationOpensAt) {
+			return RegistrationStateUpcoming
+		}
+		if now.After(a.RegistrationDeadline) {
+			return RegistrationStateClosed // 截止时间已过，等待定时任务流转
+		}
+		return RegistrationStateOpen
+	default:
+		return RegistrationStateClosed
+	}
+}
@@ -2,11 +2,22 @@ package model
 
 import "time"
 
+// 定义管理员的 3 种角色
+type AdminRole string
+
+const (
+	AdminRoleSuper     AdminRole = "SUPER_ADMIN" // 超级管理员：管理所有活动和管理员
+	AdminRoleOrganizer AdminRole = "ORGANIZER"   // 组织者：只能修改自己创建或协办的活动
+	AdminRoleStaff     AdminRole = "STAFF"       // 签到人员：只能操作签到相关接口
+)
+
 // Admin 对应 'admins' 表，存储管理员信息
 type Admin struct {
 	ID           uint      `gorm:"primarykey"`
-	Username     string    `gorm:"type:varchar(100);uniqueIndex;not null" json:"username"` // 用户名
-	PasswordHash string    `gorm:"type:varchar(255);not null" json:"-"`                    // 存储哈希后的密码
+	Username     string    `gorm:"type:varchar(100);uniqueIndex;not null" json:"username"`    // 用户名
+	PasswordHash string    `gorm:"type:varchar(255);not null" json:"-"`                       // 存储哈希后的密码
+	Role         AdminRole `gorm:"type:varchar(20);not null;default:'ORGANIZER'" json:"role"` // 角色
+	Disabled     bool      `gorm:"not null;default:false" json:"disabled"`                    // 是否已停用
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 
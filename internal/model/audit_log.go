@@ -0,0 +1,61 @@
+package model
+
+import "time"
+
+// 审计日志的操作类型
+const (
+	AuditActionActivityCreate       = "activity.create"
+	AuditActionActivityUpdate       = "activity.update"
+	AuditActionActivityDelete       = "activity.delete"
+	AuditActionActivityPublish      = "activity.publish"
+	AuditActionActivityClose        = "activity.close"
+	AuditActionActivityReopen       = "activity.reopen"
+	AuditActionActivityCancel       = "activity.cancel"
+	AuditActionActivityFinish       = "activity.finish"
+	AuditActionPublishUnschedule    = "activity.publish_schedule.cancel"
+	AuditActionActivityLotteryDraw  = "activity.lottery.draw"
+	AuditActionActivityCreditsGrant = "activity.credits.grant"
+	AuditActionCertificatesIssue    = "activity.certificates.issue"
+	AuditActionCreditAdjust         = "credit.adjust"
+	AuditActionActivityCoOrganizers = "activity.co_organizers.set"
+	AuditActionActivityDisplayKey   = "activity.display_key.rotate"
+	AuditActionActivityFormUpdate   = "activity.form.update"
+	AuditActionEligibilityUpdate    = "activity.eligibility.update"
+	AuditActionAllowlistReplace     = "activity.allowlist.replace"
+	AuditActionRegistrationSignIn   = "registration.sign_in.update"
+	AuditActionRegistrationRemove   = "registration.remove"
+	AuditActionRegistrationApprove  = "registration.approve"
+	AuditActionRegistrationReject   = "registration.reject"
+	AuditActionWaitlistReorder      = "activity.waitlist.reorder"
+	AuditActionRegistrationsImport  = "activity.registrations.import"
+	AuditActionNotificationRetry    = "notification.retry"
+	AuditActionAdminCreate          = "admin.create"
+	AuditActionAdminRoleUpdate      = "admin.role.update"
+	AuditActionAdminStatusUpdate    = "admin.status.update"
+	AuditActionAdminPasswordReset   = "admin.password.reset"
+	AuditActionAdminPasswordChange  = "admin.password.change"
+	AuditActionAdminDelete          = "admin.delete"
+)
+
+// 审计日志的操作对象类型
+const (
+	AuditTargetActivity     = "activity"
+	AuditTargetRegistration = "registration"
+	AuditTargetAdmin        = "admin"
+	AuditTargetCredit       = "credit"
+	AuditTargetNotification = "notification"
+)
+
+// AuditLog 对应 'audit_logs' 表，记录管理员的每一次修改操作
+// 与被修改的数据在同一事务中写入，只追加不修改
+type AuditLog struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	AdminID    uint      `gorm:"not null;index" json:"admin_id"`                                      // 操作者，0 表示系统操作
+	Action     string    `gorm:"type:varchar(64);not null;index" json:"action"`                       // 操作类型
+	TargetType string    `gorm:"type:varchar(32);not null;index:idx_audit_target" json:"target_type"` // 操作对象类型
+	TargetID   uint      `gorm:"not null;index:idx_audit_target" json:"target_id"`                    // 操作对象ID
+	Diff       string    `gorm:"type:text" json:"diff"`                                               // 变更前后的字段差异 (JSON)
+	IP         string    `gorm:"type:varchar(64)" json:"ip"`                                          // 操作者IP
+	RequestID  string    `gorm:"type:varchar(64);index" json:"request_id"`                            // 请求ID
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// Certificate 对应 'certificates' 表，活动参与证明
+// 签发时保存活动和参与者信息的快照，之后修改活动或报名不影响已签发的证明
+type Certificate struct {
+	ID                 uint      `gorm:"primarykey" json:"id"`
+	Code               string    `gorm:"type:varchar(32);uniqueIndex;not null" json:"code"` // 验证码，用于公开查验
+	ActivityID         uint      `gorm:"index;not null" json:"activity_id"`
+	RegistrationID     uint      `gorm:"uniqueIndex;not null" json:"registration_id"` // 每条报名只签发一张证明
+	ParticipantName    string    `gorm:"type:varchar(100);not null" json:"participant_name"`
+	ParticipantCollege string    `gorm:"type:varchar(100);not null" json:"participant_college"`
+	ActivityTitle      string    `gorm:"type:varchar(255);not null" json:"activity_title"`
+	ActivityStartTime  time.Time `gorm:"not null" json:"activity_start_time"`
+	AttendedSeconds    int64     `gorm:"not null;default:0" json:"attended_seconds"` // 参与时长 (秒)
+	CreatedAt          time.Time `json:"issued_at"`                                  // 签发时间
+}
@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+// CreditType 学分类型
+type CreditType string
+
+const (
+	CreditTypeSecondClassroom CreditType = "SECOND_CLASSROOM" // 第二课堂学分
+	CreditTypeVolunteerHours  CreditType = "VOLUNTEER_HOURS"  // 志愿服务时长 (小时)
+)
+
+// IsValidCreditType 判断是否为支持的学分类型
+func IsValidCreditType(t CreditType) bool {
+	return t == CreditTypeSecondClassroom || t == CreditTypeVolunteerHours
+}
+
+// CreditSource 学分流水的来源
+type CreditSource string
+
+const (
+	CreditSourceGrant      CreditSource = "GRANT"      // 活动结束时自动发放
+	CreditSourceAdjustment CreditSource = "ADJUSTMENT" // 管理员手动调整
+)
+
+// CreditEntry 对应 'credit_entries' 表，学分流水只追加、不修改不删除
+// 按手机号或学号汇总得到学生在各活动中获得的学分
+type CreditEntry struct {
+	ID              uint         `gorm:"primarykey" json:"id"`
+	Phone           string       `gorm:"type:varchar(20);index;not null" json:"phone"` // 学生手机号
+	StudentID       string       `gorm:"type:varchar(32);index" json:"student_id"`     // 学生学号 (可选)
+	ParticipantName string       `gorm:"type:varchar(100)" json:"participant_name"`    // 学生姓名
+	CreditType      CreditType   `gorm:"type:varchar(32);not null" json:"credit_type"` // 学分类型
+	Amount          float64      `gorm:"type:decimal(8,2);not null" json:"amount"`     // 学分数，手动调整可以为负数
+	Source          CreditSource `gorm:"type:varchar(20);not null" json:"source"`      // 来源
+	ActivityID      uint         `gorm:"index;not null;default:0" json:"activity_id"`  // 发放学分的活动 (手动调整为 0)
+	RegistrationID  *uint        `gorm:"uniqueIndex" json:"registration_id,omitempty"` // 发放学分的报名记录，每条报名只发放一次
+	Reason          string       `gorm:"type:varchar(500)" json:"reason,omitempty"`    // 手动调整的原因
+	AdminID         uint         `gorm:"not null;default:0" json:"admin_id,omitempty"` // 手动调整的管理员
+	CreatedAt       time.Time    `json:"created_at"`
+}
@@ -1,6 +1,9 @@
 package model
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // DTOs (Data Transfer Objects) 用于API的请求和响应，实现API契约与数据库模型的解耦
 
@@ -14,59 +17,222 @@ type AdminLoginRequest struct {
 
 // AdminLoginResponse 管理员登录响应
 type AdminLoginResponse struct {
-	Token    string `json:"token"`
-	Username string `json:"username"`
+	Token        string    `json:"token"`         // 短期访问令牌
+	RefreshToken string    `json:"refresh_token"` // 刷新令牌，每次刷新后轮换，旧令牌立即失效
+	ExpiresIn    int64     `json:"expires_in"`    // 访问令牌有效期 (秒)
+	Username     string    `json:"username"`
+	Role         AdminRole `json:"role"`
+}
+
+// RefreshTokenRequest 刷新访问令牌请求
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// AdminBriefResponse 管理员简要信息
+type AdminBriefResponse struct {
+	ID       uint      `json:"id"`
+	Username string    `json:"username"`
+	Role     AdminRole `json:"role"`
+}
+
+// CreateAdminRequest 超级管理员创建管理员账号请求
+type CreateAdminRequest struct {
+	Username string    `json:"username" binding:"required,max=100"`
+	Password string    `json:"password" binding:"required,min=8"`
+	Role     AdminRole `json:"role" binding:"required"`
+}
+
+// UpdateAdminStatusRequest 停用/启用管理员账号请求
+type UpdateAdminStatusRequest struct {
+	Disabled *bool `json:"disabled" binding:"required"` // 指针类型允许传 false
+}
+
+// ResetAdminPasswordRequest 超级管理员重置密码请求
+type ResetAdminPasswordRequest struct {
+	NewPassword string `json:"new_password" binding:"required,min=8"`
+}
+
+// ChangePasswordRequest 管理员修改自己的密码请求
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=8"`
+}
+
+// ListAdminsParams 管理员列表查询参数
+type ListAdminsParams struct {
+	Page     int       `form:"page,default=1"`       // 页码
+	PageSize int       `form:"page_size,default=10"` // 每页大小
+	Username string    `form:"username"`             // 按用户名关键词过滤
+	Role     AdminRole `form:"role"`                 // 按角色过滤
+	Disabled *bool     `form:"disabled"`             // 按停用状态过滤
+}
+
+// AdminResponse 管理员账号信息
+type AdminResponse struct {
+	ID        uint      `json:"id"`
+	Username  string    `json:"username"`
+	Role      AdminRole `json:"role"`
+	Disabled  bool      `json:"disabled"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 // === Activity DTOs ===
 
 // CreateActivityRequest 创建活动请求
 type CreateActivityRequest struct {
-	Title                string    `json:"title" binding:"required"`
-	Type                 string    `json:"type" binding:"required"`
-	Description          string    `json:"description"`
-	StartTime            time.Time `json:"start_time" binding:"required"`
-	EndTime              time.Time `json:"end_time" binding:"required"`
-	Location             string    `json:"location" binding:"required"`
-	RegistrationDeadline time.Time `json:"registration_deadline" binding:"required"`
-	MaxParticipants      int       `json:"max_participants" binding:"gte=0"` // 必须大于等于0
-	LiveURL              string    `json:"live_url"`
-	AttachmentURL        string    `json:"attachment_url"`
+	Title                string             `json:"title" binding:"required"`
+	Type                 string             `json:"type" binding:"required"`
+	Description          string             `json:"description"`
+	StartTime            time.Time          `json:"start_time" binding:"required"`
+	EndTime              time.Time          `json:"end_time" binding:"required"`
+	Location             string             `json:"location" binding:"required"`
+	RegistrationOpensAt  *time.Time         `json:"registration_opens_at"` // 报名开放时间 (可选)，不填表示发布后立即开放
+	PublishAt            *time.Time         `json:"publish_at"`            // 定时发布时间 (可选)，须早于报名截止时间
+	RegistrationDeadline time.Time          `json:"registration_deadline" binding:"required"`
+	MaxParticipants      int                `json:"max_participants" binding:"gte=0"` // 必须大于等于0
+	LiveURL              string             `json:"live_url"`
+	AttachmentURL        string             `json:"attachment_url"`
+	FastRegistration     bool               `json:"fast_registration"`  // 开启快速报名 (热门活动，名额在 Redis 中预占后异步落库)
+	RequiresApproval     bool               `json:"requires_approval"`  // 报名需要组织者审核
+	Lottery              bool               `json:"lottery"`            // 抽签报名 (不能与报名审核同时开启)
+	LotteryWeights       map[string]float64 `json:"lottery_weights"`    // 抽签的学院权重 (可选)，未列出的学院权重为 1
+	CreditType           CreditType         `json:"credit_type"`        // 学分类型 (发放学分时必填)
+	CreditValue          float64            `json:"credit_value"`       // 每人发放的学分数，0 表示不发放
+	CreditMinMinutes     int                `json:"credit_min_minutes"` // 获得学分的最低参与时长 (分钟)，0 表示签到即可
 }
 
 // UpdateActivityRequest 更新活动请求
 // 使用指针类型允许部分更新 (Partial Update)
 type UpdateActivityRequest struct {
-	Title                *string    `json:"title"`
-	Type                 *string    `json:"type"`
-	Description          *string    `json:"description"`
-	StartTime            *time.Time `json:"start_time"`
-	EndTime              *time.Time `json:"end_time"`
-	Location             *string    `json:"location"`
-	RegistrationDeadline *time.Time `json:"registration_deadline"`
-	MaxParticipants      *int       `json:"max_participants" binding:"omitempty,gte=0"`
-	LiveURL              *string    `json:"live_url"`
-	AttachmentURL        *string    `json:"attachment_url"`
-	Status               *string    `json:"status"` // 用于手动更新状态
+	Title                *string            `json:"title"`
+	Type                 *string            `json:"type"`
+	Description          *string            `json:"description"`
+	StartTime            *time.Time         `json:"start_time"`
+	EndTime              *time.Time         `json:"end_time"`
+	Location             *string            `json:"location"`
+	RegistrationOpensAt  *time.Time         `json:"registration_opens_at"` // 设置为过去的时间表示立即开放
+	PublishAt            *time.Time         `json:"publish_at"`            // 只能在草稿状态设置，取消定时发布请使用取消接口
+	RegistrationDeadline *time.Time         `json:"registration_deadline"`
+	MaxParticipants      *int               `json:"max_participants" binding:"omitempty,gte=0"`
+	LiveURL              *string            `json:"live_url"`
+	AttachmentURL        *string            `json:"attachment_url"`
+	FastRegistration     *bool              `json:"fast_registration"` // 只能在草稿状态修改
+	RequiresApproval     *bool              `json:"requires_approval"` // 只能在草稿状态修改
+	Lottery              *bool              `json:"lottery"`           // 只能在草稿状态修改
+	LotteryWeights       map[string]float64 `json:"lottery_weights"`   // 只能在草稿状态修改；传空对象表示清空权重
+	CreditType           *CreditType        `json:"credit_type"`
+	CreditValue          *float64           `json:"credit_value"`
+	CreditMinMinutes     *int               `json:"credit_min_minutes"`
+	Status               *string            `json:"status"` // 用于手动更新状态，须符合状态流转规则；取消活动请使用取消接口
+}
+
+// CancelActivityRequest 取消活动请求
+type CancelActivityRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"` // 取消原因
 }
 
 // ActivityResponse 活动的通用响应
 type ActivityResponse struct {
-	ID                   uint           `json:"id"`
-	AdminID              uint           `json:"admin_id"`
-	Title                string         `json:"title"`
-	Type                 string         `json:"type"`
-	Description          string         `json:"description"`
-	StartTime            time.Time      `json:"start_time"`
-	EndTime              time.Time      `json:"end_time"`
-	Location             string         `json:"location"`
-	RegistrationDeadline time.Time      `json:"registration_deadline"`
-	MaxParticipants      int            `json:"max_participants"`
-	RegisteredCount      int            `json:"registered_count"`
-	Status               ActivityStatus `json:"status"`
-	LiveURL              string         `json:"live_url,omitempty"`
-	AttachmentURL        string         `json:"attachment_url,omitempty"`
-	CreatedAt            time.Time      `json:"created_at"`
+	ID                   uint                      `json:"id"`
+	AdminID              uint                      `json:"admin_id"`
+	Title                string                    `json:"title"`
+	Type                 string                    `json:"type"`
+	Description          string                    `json:"description"`
+	StartTime            time.Time                 `json:"start_time"`
+	EndTime              time.Time                 `json:"end_time"`
+	Location             string                    `json:"location"`
+	RegistrationOpensAt  *time.Time                `json:"registration_opens_at,omitempty"`
+	RegistrationDeadline time.Time                 `json:"registration_deadline"`
+	RegistrationState    RegistrationStateResponse `json:"registration_state"`
+	MaxParticipants      int                       `json:"max_participants"`
+	RegisteredCount      int                       `json:"registered_count"`
+	FastRegistration     bool                      `json:"fast_registration"`
+	RequiresApproval     bool                      `json:"requires_approval"`
+	Lottery              bool                      `json:"lottery"`
+	LotteryWeights       map[string]float64        `json:"lottery_weights,omitempty"`
+	LotteryDrawnAt       *time.Time                `json:"lottery_drawn_at,omitempty"`
+	CreditType           CreditType                `json:"credit_type,omitempty"`
+	CreditValue          float64                   `json:"credit_value"`
+	CreditMinMinutes     int                       `json:"credit_min_minutes"`
+	CreditsGrantedAt     *time.Time                `json:"credits_granted_at,omitempty"`
+	Status               ActivityStatus            `json:"status"`
+	PublishAt            *time.Time                `json:"publish_at,omitempty"`
+	PublishError         string                    `json:"publish_error,omitempty"` // 定时发布失败的原因
+	CancelReason         string                    `json:"cancel_reason,omitempty"`
+	CancelledAt          *time.Time                `json:"cancelled_at,omitempty"`
+	LiveURL              string                    `json:"live_url,omitempty"`
+	AttachmentURL        string                    `json:"attachment_url,omitempty"`
+	FormSchema           FormSchema                `json:"form_schema,omitempty"`
+	Eligibility          *EligibilityRules         `json:"eligibility,omitempty"`
+	CreatedAt            time.Time                 `json:"created_at"`
+}
+
+// RegistrationStateResponse 活动当前的报名阶段
+// 倒计时秒数由服务端计算，客户端据此倒计时即可，不受本地时钟偏差影响
+type RegistrationStateResponse struct {
+	State           RegistrationState `json:"state"`
+	OpensInSeconds  int64             `json:"opens_in_seconds,omitempty"`  // 距报名开放的秒数 (仅 UPCOMING)
+	ClosesInSeconds int64             `json:"closes_in_seconds,omitempty"` // 距报名截止的秒数 (仅 OPEN)
+}
+
+// PublicActivityResponse 公开接口的活动信息，不包含管理员、创建时间等内部字段
+type PublicActivityResponse struct {
+	ID                   uint                      `json:"id"`
+	Title                string                    `json:"title"`
+	Type                 string                    `json:"type"`
+	Description          string                    `json:"description"`
+	StartTime            time.Time                 `json:"start_time"`
+	EndTime              time.Time                 `json:"end_time"`
+	Location             string                    `json:"location"`
+	RegistrationOpensAt  *time.Time                `json:"registration_opens_at,omitempty"` // 报名开放时间
+	RegistrationDeadline time.Time                 `json:"registration_deadline"`
+	RegistrationState    RegistrationStateResponse `json:"registration_state"` // 当前报名阶段和倒计时
+	MaxParticipants      int                       `json:"max_participants"`
+	RegisteredCount      int                       `json:"registered_count"`
+	RequiresApproval     bool                      `json:"requires_approval"`          // 报名需要组织者审核
+	Lottery              bool                      `json:"lottery"`                    // 抽签报名，报名截止时开奖
+	LotteryDrawnAt       *time.Time                `json:"lottery_drawn_at,omitempty"` // 开奖时间
+	CreditType           CreditType                `json:"credit_type,omitempty"`      // 参加活动可获得的学分类型
+	CreditValue          float64                   `json:"credit_value"`               // 参加活动可获得的学分数，0 表示不发放
+	CreditMinMinutes     int                       `json:"credit_min_minutes"`         // 获得学分的最低参与时长 (分钟)
+	Status               ActivityStatus            `json:"status"`
+	LiveURL              string                    `json:"live_url,omitempty"`
+	AttachmentURL        string                    `json:"attachment_url,omitempty"`
+	FormSchema           FormSchema                `json:"form_schema,omitempty"` // 报名时需要填写的自定义字段
+	Eligibility          *EligibilityRules         `json:"eligibility,omitempty"` // 报名资格规则
+}
+
+// SetCoOrganizersRequest 设置活动协办组织者请求 (整体替换)
+type SetCoOrganizersRequest struct {
+	AdminIDs []uint `json:"admin_ids" binding:"required"` // 传空数组表示清空协办组织者
+}
+
+// UpdateFormSchemaRequest 整体替换活动的报名表自定义字段
+type UpdateFormSchemaRequest struct {
+	Fields FormSchema `json:"fields" binding:"required"` // 传空数组表示不需要自定义字段
+}
+
+// EligibilityResponse 活动的报名资格规则及报名名单概况
+type EligibilityResponse struct {
+	Rules               EligibilityRules `json:"rules"`
+	AllowlistPhones     int64            `json:"allowlist_phones"`      // 名单中的手机号数量
+	AllowlistStudentIDs int64            `json:"allowlist_student_ids"` // 名单中的学号数量
+}
+
+// SignInCodeResponse 签到动态码 (供大屏展示二维码)
+type SignInCodeResponse struct {
+	ActivityID uint      `json:"activity_id"`
+	Code       string    `json:"code"`       // 当前时间窗口的动态码
+	QRPayload  string    `json:"qr_payload"` // 二维码内容：签到页面URL，携带活动ID和动态码
+	Period     int       `json:"period"`     // 刷新周期 (秒)
+	ExpiresAt  time.Time `json:"expires_at"` // 当前动态码所在窗口的结束时间
+}
+
+// DisplayKeyResponse 大屏展示密钥 (仅在重新生成时返回一次明文)
+type DisplayKeyResponse struct {
+	ActivityID uint   `json:"activity_id"`
+	DisplayKey string `json:"display_key"`
 }
 
 // ListActivitiesParams 列表查询参数
@@ -78,46 +244,138 @@ type ListActivitiesParams struct {
 	Status   ActivityStatus `form:"status"`               // 按状态过滤
 	DateFrom time.Time      `form:"date_from"`            // 按时间范围过滤
 	DateTo   time.Time      `form:"date_to"`
+
+	// Statuses 限定可见的状态范围，由 Service 层设置，不从请求绑定
+	Statuses []ActivityStatus `form:"-"`
 }
 
 // === Registration DTOs ===
 
 // CreateRegistrationRequest 参与者报名请求
 type CreateRegistrationRequest struct {
-	ParticipantName    string `json:"participant_name" binding:"required"`
-	ParticipantPhone   string `json:"participant_phone" binding:"required"`
-	ParticipantCollege string `json:"participant_college" binding:"required"`
+	ParticipantName      string      `json:"participant_name" binding:"required"`
+	ParticipantPhone     string      `json:"participant_phone" binding:"required"`
+	ParticipantCollege   string      `json:"participant_college" binding:"required"`
+	ParticipantStudentID string      `json:"participant_student_id" binding:"max=32"`             // 学号 (可选)，活动按学号名单限制报名时需要填写
+	ParticipantEmail     string      `json:"participant_email" binding:"omitempty,email,max=255"` // 邮箱 (可选)，用于接收报名、递补、活动变更和取消通知
+	Answers              FormAnswers `json:"answers"`                                             // 报名表自定义字段的答案，按活动的 form_schema 填写
 }
 
 // RegistrationResponse 报名的通用响应
 type RegistrationResponse struct {
-	ID                 uint      `json:"id"`
-	ActivityID         uint      `json:"activity_id"`
-	ParticipantName    string    `json:"participant_name"`
-	ParticipantPhone   string    `json:"participant_phone"`
-	ParticipantCollege string    `json:"participant_college"`
-	RegisteredAt       time.Time `json:"registered_at"`
-	IsSignedIn         bool      `json:"is_signed_in"`
+	ID                   uint               `json:"id"`
+	ActivityID           uint               `json:"activity_id"`
+	ParticipantName      string             `json:"participant_name"`
+	ParticipantPhone     string             `json:"participant_phone"`
+	ParticipantCollege   string             `json:"participant_college"`
+	ParticipantStudentID string             `json:"participant_student_id,omitempty"`
+	ParticipantEmail     string             `json:"participant_email,omitempty"`
+	RegisteredAt         time.Time          `json:"registered_at"`
+	Status               RegistrationStatus `json:"status"`
+	WaitlistPosition     int                `json:"waitlist_position,omitempty"`
+	IsSignedIn           bool               `json:"is_signed_in"`
+	SignedInAt           *time.Time         `json:"signed_in_at,omitempty"`
+	IsLate               bool               `json:"is_late"`
+	SignedOutAt          *time.Time         `json:"signed_out_at,omitempty"`
+	LeftEarly            bool               `json:"left_early"`
+	AttendedSeconds      int64              `json:"attended_seconds"` // 参与时长 (秒)，签退后计算
+	CancelledAt          *time.Time         `json:"cancelled_at,omitempty"`
+	ReviewedAt           *time.Time         `json:"reviewed_at,omitempty"`
+	ReviewReason         string             `json:"review_reason,omitempty"`
+	Answers              FormAnswers        `json:"answers,omitempty"`
+	ManageToken          string             `json:"manage_token,omitempty"` // 仅在报名成功时返回，用于自助取消等操作
+}
+
+// CancelRegistrationRequest 参与者取消报名请求
+type CancelRegistrationRequest struct {
+	Phone string `json:"phone" binding:"required"` // 报名时填写的手机号
+	Token string `json:"token" binding:"required"` // 报名成功时返回的 manage_token
+}
+
+// RegistrationStatusRequest 参与者查询自己的报名状态请求
+type RegistrationStatusRequest struct {
+	Phone string `json:"phone" binding:"required"` // 报名时填写的手机号
+	Token string `json:"token" binding:"required"` // 报名成功时返回的 manage_token
+}
+
+// RegistrationStatusResponse 参与者查询到的报名状态，不包含个人信息
+type RegistrationStatusResponse struct {
+	ActivityID       uint               `json:"activity_id"`
+	Status           RegistrationStatus `json:"status"`
+	WaitlistPosition int                `json:"waitlist_position,omitempty"`
+	RegisteredAt     time.Time          `json:"registered_at"`
+	ReviewedAt       *time.Time         `json:"reviewed_at,omitempty"`
+	ReviewReason     string             `json:"review_reason,omitempty"`
+	IsSignedIn       bool               `json:"is_signed_in"`
+	SignedOutAt      *time.Time         `json:"signed_out_at,omitempty"`
+	CancelledAt      *time.Time         `json:"cancelled_at,omitempty"`
 }
 
 // SignInRequest 参与者签到请求 (新增)
 type SignInRequest struct {
 	Phone string `json:"phone" binding:"required"` // 参与者手机号，用于查找报名记录
-	Token string `json:"token" binding:"required"` // 签到 Token 或验证码
+	Token string `json:"token" binding:"required"` // 大屏二维码中的签到动态码
+}
+
+// SignOutRequest 参与者签退请求
+type SignOutRequest struct {
+	Phone string `json:"phone" binding:"required"` // 参与者手机号，用于查找报名记录
+	Token string `json:"token" binding:"required"` // 大屏二维码中的签退动态码
 }
 
 // ListRegistrationsParams 报名列表查询参数
 type ListRegistrationsParams struct {
-	Page        int    `form:"page,default=1"`       // 页码
-	PageSize    int    `form:"page_size,default=10"` // 每页大小
-	ActivityID  uint   `form:"activity_id"`          // 活动ID (可选)
-	ParticipantPhone string `form:"phone"`           // 参与者手机号 (可选)
-	IsSignedIn  *bool  `form:"is_signed_in"`         // 签到状态 (可选，指针类型允许传false)
+	Page             int                `form:"page,default=1"`       // 页码
+	PageSize         int                `form:"page_size,default=10"` // 每页大小
+	ActivityID       uint               `form:"activity_id"`          // 活动ID (可选)
+	ParticipantPhone string             `form:"phone"`                // 参与者手机号 (可选)
+	IsSignedIn       *bool              `form:"is_signed_in"`         // 签到状态 (可选，指针类型允许传false)
+	IsLate           *bool              `form:"is_late"`              // 是否迟到 (可选)
+	LeftEarly        *bool              `form:"left_early"`           // 是否早退 (可选)
+	Status           RegistrationStatus `form:"status"`               // 报名状态 (可选)
 }
 
 // UpdateSignInStatusRequest 管理员更新签到状态请求
 type UpdateSignInStatusRequest struct {
-	IsSignedIn bool `json:"is_signed_in" binding:"required"` // 是否签到
+	IsSignedIn *bool `json:"is_signed_in" binding:"required"` // 是否签到 (使用指针以便传 false 取消签到)
+}
+
+// BulkUpdateSignInRequest 管理员批量修改签到状态请求，registration_ids 和 phones 至少填写一项
+type BulkUpdateSignInRequest struct {
+	RegistrationIDs []uint   `json:"registration_ids" binding:"max=500"`
+	Phones          []string `json:"phones" binding:"max=500,dive,max=20"`
+	IsSignedIn      *bool    `json:"is_signed_in" binding:"required"` // 目标状态
+}
+
+// BulkSignInResult 批量修改签到状态中单条的处理结果
+type BulkSignInResult struct {
+	RegistrationID uint       `json:"registration_id,omitempty"`
+	Phone          string     `json:"phone,omitempty"` // 按手机号指定时返回
+	Success        bool       `json:"success"`
+	Changed        bool       `json:"changed"` // 是否修改了签到状态 (已是目标状态时为 false)
+	IsSignedIn     bool       `json:"is_signed_in"`
+	SignedInAt     *time.Time `json:"signed_in_at"`
+	Error          string     `json:"error,omitempty"` // 失败原因
+}
+
+// ReorderWaitlistRequest 管理员调整候补队列顺序请求
+// RegistrationIDs 必须包含该活动当前全部候补记录，按期望的递补顺序排列
+type ReorderWaitlistRequest struct {
+	RegistrationIDs []uint `json:"registration_ids" binding:"required,min=1"`
+}
+
+// ReviewRegistrationsRequest 管理员批量审核报名请求
+type ReviewRegistrationsRequest struct {
+	RegistrationIDs []uint `json:"registration_ids" binding:"required,min=1,max=200"`
+	Reason          string `json:"reason" binding:"max=500"` // 审核意见 (可选)，参与者查询报名状态时可见
+}
+
+// ReviewResult 批量审核中单条报名记录的处理结果
+type ReviewResult struct {
+	RegistrationID uint               `json:"registration_id"`
+	Success        bool               `json:"success"`
+	Status         RegistrationStatus `json:"status,omitempty"` // 处理后的状态 (成功时)
+	Error          string             `json:"error,omitempty"`  // 失败原因
 }
 
 // DashboardResponse 仪表盘统计响应
@@ -127,3 +385,125 @@ type DashboardResponse struct {
 	TotalRegistrations  int64 `json:"total_registrations"`
 	TodayRegistrations  int64 `json:"today_registrations"`
 }
+
+// === Audit Log DTOs ===
+
+// ListAuditLogsParams 审计日志查询参数
+type ListAuditLogsParams struct {
+	Page       int       `form:"page,default=1"`       // 页码
+	PageSize   int       `form:"page_size,default=20"` // 每页大小
+	AdminID    uint      `form:"admin_id"`             // 按操作者过滤
+	Action     string    `form:"action"`               // 按操作类型过滤
+	TargetType string    `form:"target_type"`          // 按操作对象类型过滤
+	TargetID   uint      `form:"target_id"`            // 按操作对象ID过滤
+	DateFrom   time.Time `form:"date_from"`            // 按时间范围过滤
+	DateTo     time.Time `form:"date_to"`
+}
+
+// ListNotificationsParams 通知查询参数
+type ListNotificationsParams struct {
+	Page       int                `form:"page,default=1"`       // 页码
+	PageSize   int                `form:"page_size,default=20"` // 每页大小
+	Status     NotificationStatus `form:"status"`               // 按发送状态过滤
+	Event      NotificationEvent  `form:"event"`                // 按事件过滤
+	ActivityID uint               `form:"activity_id"`          // 按活动过滤
+	Recipient  string             `form:"recipient"`            // 按收件人过滤
+}
+
+// AuditLogResponse 审计日志响应，diff 以 JSON 对象形式返回
+type AuditLogResponse struct {
+	ID         uint            `json:"id"`
+	AdminID    uint            `json:"admin_id"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type"`
+	TargetID   uint            `json:"target_id"`
+	Diff       json.RawMessage `json:"diff"`
+	IP         string          `json:"ip"`
+	RequestID  string          `json:"request_id"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// === Credit DTOs ===
+
+// CreditAdjustmentRequest 管理员手动调整学分请求，手机号和学号至少填写一个
+type CreditAdjustmentRequest struct {
+	Phone           string     `json:"phone" binding:"required_without=StudentID,max=20"`
+	StudentID       string     `json:"student_id" binding:"max=32"`
+	ParticipantName string     `json:"participant_name" binding:"max=100"`
+	CreditType      CreditType `json:"credit_type" binding:"required"`
+	Amount          float64    `json:"amount" binding:"required"` // 调整的学分数，负数表示扣减，不能为 0
+	Reason          string     `json:"reason" binding:"required,max=500"`
+}
+
+// CreditQueryParams 学分查询参数，手机号和学号至少填写一个，两者都填时合并两者的流水
+type CreditQueryParams struct {
+	Phone     string `form:"phone"`
+	StudentID string `form:"student_id"`
+}
+
+// CreditSummaryResponse 学生的学分汇总及流水
+type CreditSummaryResponse struct {
+	Phone     string                 `json:"phone,omitempty"`
+	StudentID string                 `json:"student_id,omitempty"`
+	Totals    map[CreditType]float64 `json:"totals"`  // 按学分类型汇总
+	Entries   []*CreditEntry         `json:"entries"` // 学分流水，按时间倒序
+}
+
+// === Certificate DTOs ===
+
+// CertificateVerificationResponse 公开查验参与证明的结果
+type CertificateVerificationResponse struct {
+	Code               string    `json:"code"`
+	ParticipantName    string    `json:"participant_name"`
+	ParticipantCollege string    `json:"participant_college"`
+	ActivityTitle      string    `json:"activity_title"`
+	ActivityStartTime  time.Time `json:"activity_start_time"`
+	AttendedSeconds    int64     `json:"attended_seconds"`
+	IssuedAt           time.Time `json:"issued_at"`
+}
+
+// RegistrationExportFormat 报名表导出格式
+type RegistrationExportFormat string
+
+const (
+	RegistrationExportCSV  RegistrationExportFormat = "csv"
+	RegistrationExportXLSX RegistrationExportFormat = "xlsx"
+)
+
+// === Import DTOs ===
+
+// ImportOptions CSV 导入选项
+type ImportOptions struct {
+	DryRun bool `form:"dry_run"` // 试运行：逐行校验并返回结果，不保存任何数据
+	Atomic bool `form:"atomic"`  // 整体导入：任意一行失败时全部不保存 (重复的行不算失败)
+}
+
+// ImportRowOutcome CSV 导入中单行的处理结果
+type ImportRowOutcome string
+
+const (
+	ImportRowSuccess   ImportRowOutcome = "SUCCESS"   // 导入成功 (试运行时表示可以导入)
+	ImportRowDuplicate ImportRowOutcome = "DUPLICATE" // 已报名或已签到，跳过
+	ImportRowFailed    ImportRowOutcome = "FAILED"    // 校验未通过
+)
+
+// ImportRowResult CSV 导入中单行的处理结果
+type ImportRowResult struct {
+	Line   int                `json:"line"` // CSV 行号 (表头为第 1 行)
+	Phone  string             `json:"phone"`
+	Result ImportRowOutcome   `json:"result"`
+	Status RegistrationStatus `json:"status,omitempty"` // 导入报名成功时的报名状态 (已确认、候补、待审核或待抽签)
+	Error  string             `json:"error,omitempty"`  // 失败或重复的原因
+}
+
+// ImportReport CSV 导入的结果报告
+type ImportReport struct {
+	DryRun     bool              `json:"dry_run"`
+	Atomic     bool              `json:"atomic"`
+	Committed  bool              `json:"committed"` // 是否已保存 (试运行、或整体导入有失败行时为 false)
+	Total      int               `json:"total"`
+	Succeeded  int               `json:"succeeded"`
+	Duplicates int               `json:"duplicates"`
+	Failed     int               `json:"failed"`
+	Rows       []ImportRowResult `json:"rows"`
+}
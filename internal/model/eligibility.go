@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// EligibilityRules 活动的报名资格规则，未设置的规则不限制
+type EligibilityRules struct {
+	AllowedColleges  []string       `json:"allowed_colleges,omitempty"` // 允许报名的学院 (participant_college)，为空表示不限
+	CollegeQuotas    map[string]int `json:"college_quotas,omitempty"`   // 各学院的名额上限 (已确认人数)，未列出的学院只受总人数限制
+	RequireAllowlist bool           `json:"require_allowlist"`          // 只允许名单内的手机号或学号报名，名单通过 CSV 上传
+}
+
+// 报名名单的条目类型
+type AllowlistKind string
+
+const (
+	AllowlistKindPhone     AllowlistKind = "PHONE"      // 手机号
+	AllowlistKindStudentID AllowlistKind = "STUDENT_ID" // 学号
+)
+
+// AllowlistEntry 对应 'allowlist_entries' 表，存储活动的报名名单
+// UniqueIndex约束：同一个活动中，同一类型的值只出现一次
+type AllowlistEntry struct {
+	ID         uint          `gorm:"primarykey" json:"id"`
+	ActivityID uint          `gorm:"uniqueIndex:idx_allowlist_entry;not null" json:"activity_id"`            // 外键：活动ID
+	Kind       AllowlistKind `gorm:"type:varchar(20);uniqueIndex:idx_allowlist_entry;not null" json:"kind"`  // 条目类型
+	Value      string        `gorm:"type:varchar(64);uniqueIndex:idx_allowlist_entry;not null" json:"value"` // 手机号或学号
+	CreatedAt  time.Time     `json:"created_at"`
+}
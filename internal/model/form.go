@@ -0,0 +1,29 @@
+package model
+
+// 报名表自定义字段的类型
+type FormFieldType string
+
+const (
+	FormFieldText        FormFieldType = "text"         // 单行文本
+	FormFieldTextarea    FormFieldType = "textarea"     // 多行文本
+	FormFieldNumber      FormFieldType = "number"       // 数字
+	FormFieldSelect      FormFieldType = "select"       // 单选，答案必须是 options 之一
+	FormFieldMultiSelect FormFieldType = "multi_select" // 多选，答案为 options 的子集
+)
+
+// FormField 报名表中的一个自定义字段
+type FormField struct {
+	Key      string        `json:"key"`               // 字段标识，同一表单内唯一，作为答案的键
+	Label    string        `json:"label"`             // 展示给报名者的问题
+	Type     FormFieldType `json:"type"`              // 字段类型
+	Required bool          `json:"required"`          // 是否必填
+	Options  []string      `json:"options,omitempty"` // 可选项 (仅 select / multi_select)
+	Pattern  string        `json:"pattern,omitempty"` // 校验答案的正则表达式 (仅 text / textarea)
+}
+
+// FormSchema 活动的报名表：姓名、手机号、学院之外的自定义字段，按数组顺序展示
+type FormSchema []FormField
+
+// FormAnswers 报名者填写的自定义字段答案：字段 key -> 答案
+// text / textarea / select 为字符串，number 为数字，multi_select 为字符串数组
+type FormAnswers map[string]any
@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// LotteryDraw 对应 'lottery_draws' 表，保存抽签活动的开奖记录，每个活动只开奖一次
+// 使用相同的 Seed、Algorithm、候选名单 (按报名ID升序) 和学院权重可以复现抽签顺序
+type LotteryDraw struct {
+	ID             uint               `gorm:"primarykey" json:"id"`
+	ActivityID     uint               `gorm:"uniqueIndex;not null" json:"activity_id"`          // 外键：活动ID
+	Seed           string             `gorm:"type:varchar(64);not null" json:"seed"`            // 随机种子 (十六进制)
+	Algorithm      string             `gorm:"type:varchar(32);not null" json:"algorithm"`       // 抽签算法版本
+	Seats          int                `gorm:"not null" json:"seats"`                            // 开奖时的可分配名额 (0 表示不限制)
+	CollegeWeights map[string]float64 `gorm:"type:json;serializer:json" json:"college_weights"` // 开奖时使用的学院权重
+	Candidates     int                `gorm:"not null" json:"candidates"`                       // 参与抽签的人数
+	Winners        int                `gorm:"not null" json:"winners"`                          // 中签人数
+	Results        []LotteryResult    `gorm:"type:json;serializer:json" json:"results"`         // 按抽签顺序排列的结果
+	DrawnAt        time.Time          `gorm:"not null" json:"drawn_at"`                         // 开奖时间
+}
+
+// LotteryResult 单个报名者的抽签结果
+type LotteryResult struct {
+	RegistrationID   uint    `json:"registration_id"`
+	College          string  `json:"college"`
+	Weight           float64 `json:"weight"`
+	Rank             int     `json:"rank"`                        // 抽签顺序 (从1开始)
+	Won              bool    `json:"won"`                         // 是否中签
+	WaitlistPosition int     `json:"waitlist_position,omitempty"` // 未中签时的候补序号
+}
@@ -0,0 +1,52 @@
+package model
+
+import "time"
+
+// NotificationEvent 触发通知的事件
+type NotificationEvent string
+
+const (
+	NotificationEventRegistered        NotificationEvent = "REGISTERED"         // 报名成功 (含进入候补、待审核、待抽签)
+	NotificationEventPromoted          NotificationEvent = "PROMOTED"           // 候补递补为正式报名
+	NotificationEventActivityUpdated   NotificationEvent = "ACTIVITY_UPDATED"   // 活动时间或地点变更
+	NotificationEventActivityCancelled NotificationEvent = "ACTIVITY_CANCELLED" // 活动取消
+)
+
+// NotificationChannel 通知的发送渠道
+type NotificationChannel string
+
+const (
+	NotificationChannelEmail NotificationChannel = "EMAIL" // 邮件，收件人为报名时填写的邮箱
+)
+
+// NotificationStatus 通知的发送状态
+type NotificationStatus string
+
+const (
+	NotificationStatusPending NotificationStatus = "PENDING" // 等待发送 (含等待重试)
+	NotificationStatusSent    NotificationStatus = "SENT"    // 已发送
+	NotificationStatusFailed  NotificationStatus = "FAILED"  // 重试次数用尽，不再发送
+)
+
+// Notification 对应 'notifications' 表，持久化的通知队列
+// 与触发通知的数据在同一事务中写入，由后台任务按 NextAttemptAt 发送，失败后按退避时间重试
+type Notification struct {
+	ID             uint                `gorm:"primarykey" json:"id"`
+	Channel        NotificationChannel `gorm:"type:varchar(20);not null" json:"channel"`
+	Event          NotificationEvent   `gorm:"type:varchar(32);not null;index" json:"event"`
+	Recipient      string              `gorm:"type:varchar(255);not null" json:"recipient"` // 收件人 (邮件为邮箱地址)
+	Subject        string              `gorm:"type:varchar(255);not null" json:"subject"`   // 入队时按模板渲染
+	Body           string              `gorm:"type:text" json:"body"`
+	ActivityID     uint                `gorm:"not null;index" json:"activity_id"`
+	RegistrationID uint                `gorm:"not null;index" json:"registration_id"`
+
+	// 发送状态：等待发送的通知按 (status, next_attempt_at) 索引领取
+	Status        NotificationStatus `gorm:"type:varchar(20);not null;default:'PENDING';index:idx_notification_due,priority:1" json:"status"`
+	Attempts      int                `gorm:"not null;default:0" json:"attempts"`                                    // 已尝试发送的次数
+	NextAttemptAt time.Time          `gorm:"not null;index:idx_notification_due,priority:2" json:"next_attempt_at"` // 下次发送时间
+	LastError     string             `gorm:"type:varchar(500);not null;default:''" json:"last_error"`               // 最近一次发送失败的原因
+	SentAt        *time.Time         `gorm:"null" json:"sent_at"`                                                   // 发送成功时间
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
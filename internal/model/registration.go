@@ -2,14 +2,54 @@ package model
 
 import "time"
 
+// 定义报名记录的状态
+type RegistrationStatus string
+
+const (
+	RegistrationStatusConfirmed  RegistrationStatus = "CONFIRMED"  // 报名成功，占用名额
+	RegistrationStatusWaitlisted RegistrationStatus = "WAITLISTED" // 候补中，等待空位递补
+	RegistrationStatusCancelled  RegistrationStatus = "CANCELLED"  // 已取消 (保留记录)
+	RegistrationStatusPending    RegistrationStatus = "PENDING"    // 待审核，不占用名额 (仅需要审核的活动)
+	RegistrationStatusRejected   RegistrationStatus = "REJECTED"   // 审核未通过 (保留记录，不能重新报名)
+	RegistrationStatusApplied    RegistrationStatus = "APPLIED"    // 已参与抽签，等待开奖，不占用名额 (仅抽签活动)
+)
+
+// 报名取消的发起方
+const (
+	CancelledByParticipant = "PARTICIPANT" // 参与者自助取消
+	CancelledByAdmin       = "ADMIN"       // 管理员移除
+)
+
 // Registration 对应 'registrations' 表，存储报名信息
 // UniqueIndex约束：同一个活动(ActivityID)中，参与者手机号(ParticipantPhone)必须是唯一的。
 type Registration struct {
-	ID                 uint      `gorm:"primarykey"`
-	ParticipantName    string    `gorm:"type:varchar(100);not null" json:"participant_name"`                                // 参与者姓名
-	ParticipantPhone   string    `gorm:"type:varchar(20);uniqueIndex:idx_activity_phone;not null" json:"participant_phone"` // 参与者手机号
-	ParticipantCollege string    `gorm:"type:varchar(100);not null" json:"participant_college"`                             // 参与者学院
-	RegisteredAt       time.Time `gorm:"autoCreateTime" json:"registered_at"`                                               // 报名时间
+	ID                   uint      `gorm:"primarykey"`
+	ParticipantName      string    `gorm:"type:varchar(100);not null" json:"participant_name"`                                // 参与者姓名
+	ParticipantPhone     string    `gorm:"type:varchar(20);uniqueIndex:idx_activity_phone;not null" json:"participant_phone"` // 参与者手机号
+	ParticipantCollege   string    `gorm:"type:varchar(100);not null" json:"participant_college"`                             // 参与者学院
+	ParticipantStudentID string    `gorm:"type:varchar(32)" json:"participant_student_id"`                                    // 参与者学号 (可选，用于报名名单)
+	ParticipantEmail     string    `gorm:"type:varchar(255)" json:"participant_email"`                                        // 参与者邮箱 (可选，用于接收通知)
+	RegisteredAt         time.Time `gorm:"autoCreateTime" json:"registered_at"`                                               // 报名时间
+
+	// 报名表自定义字段的答案，按活动的 FormSchema 校验
+	Answers FormAnswers `gorm:"type:json;serializer:json" json:"answers,omitempty"`
+
+	// 报名状态与候补队列
+	Status           RegistrationStatus `gorm:"type:varchar(20);not null;default:'CONFIRMED';index" json:"status"` // 报名状态
+	WaitlistPosition int                `gorm:"not null;default:0" json:"waitlist_position"`                       // 候补序号 (从1开始，非候补为0)
+
+	// 自助管理凭证：只保存哈希，明文仅在报名成功时返回一次
+	ManageTokenHash string `gorm:"type:varchar(64)" json:"-"`
+	ManageToken     string `gorm:"-" json:"-"` // 非持久化字段，仅用于把明文凭证返回给报名者
+
+	// 审核记录 (仅需要审核的活动)
+	ReviewedAt   *time.Time `gorm:"null" json:"reviewed_at"`                // 审核时间
+	ReviewedBy   uint       `gorm:"not null;default:0" json:"reviewed_by"`  // 审核的管理员ID
+	ReviewReason string     `gorm:"type:varchar(500)" json:"review_reason"` // 审核意见 (可选)
+
+	// 取消记录
+	CancelledAt *time.Time `gorm:"null" json:"cancelled_at"`                       // 取消时间
+	CancelledBy string     `gorm:"type:varchar(20)" json:"cancelled_by,omitempty"` // 取消发起方
 
 	// 关联：活动
 	ActivityID uint     `gorm:"uniqueIndex:idx_activity_phone;not null" json:"activity_id"` // 外键：活动ID
@@ -18,4 +58,10 @@ type Registration struct {
 	// 可选的签到功能字段
 	IsSignedIn bool       `gorm:"not null;default:false" json:"is_signed_in"` // 是否已签到
 	SignedInAt *time.Time `gorm:"null" json:"signed_in_at"`                   // 签到时间
+	IsLate     bool       `gorm:"not null;default:false" json:"is_late"`      // 签到时间晚于活动开始时间 (超过 attendance.late_threshold)
+
+	// 签退与参与时长 (签退时计算)
+	SignedOutAt     *time.Time `gorm:"null" json:"signed_out_at"`                  // 签退时间
+	LeftEarly       bool       `gorm:"not null;default:false" json:"left_early"`   // 签退时间早于活动结束时间 (超过 attendance.early_leave_threshold)
+	AttendedSeconds int64      `gorm:"not null;default:0" json:"attended_seconds"` // 参与时长：签到到签退与活动时间的交集 (秒)
 }
@@ -26,8 +26,17 @@ type ActivityRepository interface {
 	FindByIDForUpdate(ctx context.Context, id uint) (*model.Activity, error)
 	// List 列出活动 (带过滤和分页)
 	List(ctx context.Context, params *model.ListActivitiesParams) ([]*model.Activity, int64, error)
-	// 批量更新活动状态（定时任务用）
-	UpdateStatusByDeadline(ctx context.Context) (closedCount int64, finishedCount int64, err error)
+	// 协办组织者：判断是否协办、整体替换、列出
+	IsCoOrganizer(ctx context.Context, activityID, adminID uint) (bool, error)
+	ReplaceCoOrganizers(ctx context.Context, activity *model.Activity, admins []*model.Admin) error
+	ListCoOrganizers(ctx context.Context, activityID uint) ([]*model.Admin, error)
+	// 定时发布：查找已到发布时间且尚未失败的草稿、列出所有设置了定时发布的草稿
+	ListDueForPublish(ctx context.Context, now time.Time) ([]*model.Activity, error)
+	ListScheduledPublishes(ctx context.Context) ([]*model.Activity, error)
+	// 查找需要由定时任务流转状态的活动 (报名截止或活动结束时间已过)
+	ListDueForStatusUpdate(ctx context.Context, now time.Time) ([]*model.Activity, error)
+	// 查找开启了快速报名且正在报名中的活动（名额校对任务用）
+	ListFastRegistrationOpen(ctx context.Context) ([]*model.Activity, error)
 }
 
 // ----- 实现 -----
@@ -62,8 +71,9 @@ func (r *activityRepositoryImpl) Update(ctx context.Context, activity *model.Act
 }
 
 // Delete 删除活动
+// 同时清理协办组织者关联表中的记录，避免外键约束阻止删除
 func (r *activityRepositoryImpl) Delete(ctx context.Context, id uint) error {
-	return r.db.WithContext(ctx).Delete(&model.Activity{}, id).Error
+	return r.db.WithContext(ctx).Select("CoOrganizers").Delete(&model.Activity{ID: id}).Error
 }
 
 // FindByID 通过ID查找
@@ -105,6 +115,10 @@ func (r *activityRepositoryImpl) List(ctx context.Context, params *model.ListAct
 		query = query.Where("status = ?", params.Status)
 		countQuery = countQuery.Where("status = ?", params.Status)
 	}
+	if len(params.Statuses) > 0 {
+		query = query.Where("status IN ?", params.Statuses)
+		countQuery = countQuery.Where("status IN ?", params.Statuses)
+	}
 	if params.Title != "" {
 		query = query.Where("title LIKE ?", "%"+params.Title+"%")
 		countQuery = countQuery.Where("title LIKE ?", "%"+params.Title+"%")
@@ -135,23 +149,72 @@ func (r *activityRepositoryImpl) List(ctx context.Context, params *model.ListAct
 	return activities, total, nil
 }
 
-// UpdateStatusByDeadline 批量更新活动状态（定时任务用）
-func (r *activityRepositoryImpl) UpdateStatusByDeadline(ctx context.Context) (int64, int64, error) {
-	// 1. 报名截止时间已过，且状态为 PUBLISHED，更新为 CLOSED
-	closed := r.db.WithContext(ctx).Model(&model.Activity{}).
-		Where("registration_deadline < ? AND status = ?", time.Now(), model.ActivityStatusPublished).
-		Update("status", model.ActivityStatusClosed)
+// IsCoOrganizer 判断管理员是否为活动的协办组织者
+func (r *activityRepositoryImpl) IsCoOrganizer(ctx context.Context, activityID, adminID uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Table("activity_co_organizers").
+		Where("activity_id = ? AND admin_id = ?", activityID, adminID).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
 
-	// 2. 活动开始时间已过，且状态为 PUBLISHED 或 CLOSED，更新为 FINISHED
-	finished := r.db.WithContext(ctx).Model(&model.Activity{}).
-		Where("end_time < ? AND status IN (?, ?)", time.Now(), model.ActivityStatusPublished, model.ActivityStatusClosed).
-		Update("status", model.ActivityStatusFinished)
+// ReplaceCoOrganizers 整体替换活动的协办组织者
+func (r *activityRepositoryImpl) ReplaceCoOrganizers(ctx context.Context, activity *model.Activity, admins []*model.Admin) error {
+	return r.db.WithContext(ctx).Model(activity).Association("CoOrganizers").Replace(admins)
+}
 
-	if closed.Error != nil {
-		return 0, 0, closed.Error
-	}
-	if finished.Error != nil {
-		return closed.RowsAffected, 0, finished.Error
+// ListCoOrganizers 列出活动的协办组织者
+func (r *activityRepositoryImpl) ListCoOrganizers(ctx context.Context, activityID uint) ([]*model.Admin, error) {
+	var admins []*model.Admin
+	err := r.db.WithContext(ctx).Model(&model.Activity{ID: activityID}).Association("CoOrganizers").Find(&admins)
+	if err != nil {
+		return nil, err
 	}
-	return closed.RowsAffected, finished.RowsAffected, nil
+	return admins, nil
+}
+
+// ListDueForPublish 查找已到定时发布时间的草稿活动（定时任务用），发布失败过的活动需重新设置发布时间
+func (r *activityRepositoryImpl) ListDueForPublish(ctx context.Context, now time.Time) ([]*model.Activity, error) {
+	var activities []*model.Activity
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND publish_at <= ? AND publish_error = ''", model.ActivityStatusDraft, now).
+		Order("publish_at ASC, id ASC").
+		Find(&activities).Error
+	return activities, err
+}
+
+// ListScheduledPublishes 按发布时间列出设置了定时发布的草稿活动（包括发布失败的）
+func (r *activityRepositoryImpl) ListScheduledPublishes(ctx context.Context) ([]*model.Activity, error) {
+	var activities []*model.Activity
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND publish_at IS NOT NULL", model.ActivityStatusDraft).
+		Order("publish_at ASC, id ASC").
+		Find(&activities).Error
+	return activities, err
+}
+
+// ListDueForStatusUpdate 查找需要自动流转状态的活动（定时任务用）
+// 报名截止时间已过的 PUBLISHED 活动需要截止报名；结束时间已过的 PUBLISHED/CLOSED 活动需要标记为已结束
+func (r *activityRepositoryImpl) ListDueForStatusUpdate(ctx context.Context, now time.Time) ([]*model.Activity, error) {
+	var activities []*model.Activity
+	err := r.db.WithContext(ctx).
+		Where("(status = ? AND registration_deadline < ?) OR (status IN (?, ?) AND end_time < ?)",
+			model.ActivityStatusPublished, now,
+			model.ActivityStatusPublished, model.ActivityStatusClosed, now).
+		Order("id ASC").
+		Find(&activities).Error
+	return activities, err
+}
+
+// ListFastRegistrationOpen 查找开启了快速报名且正在报名中的活动（名额校对任务用）
+func (r *activityRepositoryImpl) ListFastRegistrationOpen(ctx context.Context) ([]*model.Activity, error) {
+	var activities []*model.Activity
+	err := r.db.WithContext(ctx).
+		Where("fast_registration = ? AND status = ?", true, model.ActivityStatusPublished).
+		Order("id ASC").
+		Find(&activities).Error
+	return activities, err
 }
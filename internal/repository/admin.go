@@ -10,12 +10,25 @@ import (
 
 // 接口：管理员仓库
 type AdminRepository interface {
+	// WithTx 返回绑定事务的仓库
+	WithTx(tx *gorm.DB) AdminRepository
+
 	// 创建管理员
 	Create(ctx context.Context, admin *model.Admin) error
 	// 根据用户名查找第一个
 	FindByUsername(ctx context.Context, username string) (*model.Admin, error)
 	// 根据ID查找
 	FindByID(ctx context.Context, id uint) (*model.Admin, error)
+	// 根据ID列表批量查找
+	FindByIDs(ctx context.Context, ids []uint) ([]*model.Admin, error)
+	// 更新管理员 (全字段)
+	Update(ctx context.Context, admin *model.Admin) error
+	// 删除管理员，同时清理其协办关系
+	Delete(ctx context.Context, id uint) error
+	// 多条件查询管理员 (分页)
+	List(ctx context.Context, params *model.ListAdminsParams) ([]*model.Admin, int64, error)
+	// 统计管理员创建的活动数
+	CountOwnedActivities(ctx context.Context, id uint) (int64, error)
 }
 
 // ----- 实现 -----
@@ -30,6 +43,11 @@ func NewAdminRepository(db *gorm.DB) AdminRepository {
 	return &adminRepositoryImpl{db: db}
 }
 
+// WithTx 实现了事务绑定
+func (r *adminRepositoryImpl) WithTx(tx *gorm.DB) AdminRepository {
+	return &adminRepositoryImpl{db: tx}
+}
+
 // 方法实现
 // Create 创建管理员
 func (r *adminRepositoryImpl) Create(ctx context.Context, admin *model.Admin) error {
@@ -53,3 +71,75 @@ func (r *adminRepositoryImpl) FindByID(ctx context.Context, id uint) (*model.Adm
 	}
 	return &admin, nil
 }
+
+// FindByIDs 根据ID列表批量查找
+func (r *adminRepositoryImpl) FindByIDs(ctx context.Context, ids []uint) ([]*model.Admin, error) {
+	var admins []*model.Admin
+	if len(ids) == 0 {
+		return admins, nil
+	}
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&admins).Error; err != nil {
+		return nil, err
+	}
+	return admins, nil
+}
+
+// Update 更新管理员
+func (r *adminRepositoryImpl) Update(ctx context.Context, admin *model.Admin) error {
+	return r.db.WithContext(ctx).Save(admin).Error
+}
+
+// Delete 删除管理员
+// 先清理协办组织者关联表中的记录，避免外键约束阻止删除
+func (r *adminRepositoryImpl) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM activity_co_organizers WHERE admin_id = ?", id).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.Admin{}, id).Error
+	})
+}
+
+// List 多条件查询管理员 (带分页)
+func (r *adminRepositoryImpl) List(ctx context.Context, params *model.ListAdminsParams) ([]*model.Admin, int64, error) {
+	var admins []*model.Admin
+	var total int64
+
+	// 创建两个独立查询构建器，一个计数，一个分页查找
+	query := r.db.WithContext(ctx).Model(&model.Admin{})
+	countQuery := r.db.WithContext(ctx).Model(&model.Admin{})
+
+	// 应用过滤条件
+	if params.Username != "" {
+		query = query.Where("username LIKE ?", "%"+params.Username+"%")
+		countQuery = countQuery.Where("username LIKE ?", "%"+params.Username+"%")
+	}
+	if params.Role != "" {
+		query = query.Where("role = ?", params.Role)
+		countQuery = countQuery.Where("role = ?", params.Role)
+	}
+	if params.Disabled != nil {
+		query = query.Where("disabled = ?", *params.Disabled)
+		countQuery = countQuery.Where("disabled = ?", *params.Disabled)
+	}
+
+	// 1. 获取总数
+	if err := countQuery.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 2. 应用分页并查询
+	offset := (params.Page - 1) * params.PageSize
+	if err := query.Order("id ASC").Limit(params.PageSize).Offset(offset).Find(&admins).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return admins, total, nil
+}
+
+// CountOwnedActivities 统计管理员创建的活动数
+func (r *adminRepositoryImpl) CountOwnedActivities(ctx context.Context, id uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.Activity{}).Where("admin_id = ?", id).Count(&count).Error
+	return count, err
+}
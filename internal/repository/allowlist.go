@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/frozenf1sh/gostudent/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// 接口：报名名单仓库
+type AllowlistRepository interface {
+	// 返回一个使用事务的仓库实例
+	WithTx(tx *gorm.DB) AllowlistRepository
+
+	// 整体替换某活动的报名名单 (必须在事务中调用)
+	Replace(ctx context.Context, activityID uint, entries []*model.AllowlistEntry) error
+	// 判断手机号或学号 (为空时跳过) 是否在某活动的报名名单中
+	Contains(ctx context.Context, activityID uint, phone, studentID string) (bool, error)
+	// 按类型统计某活动报名名单的条目数
+	CountByKind(ctx context.Context, activityID uint) (map[model.AllowlistKind]int64, error)
+}
+
+// ----- 实现 -----
+
+// 报名名单仓库实现
+type allowlistRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// 构造函数
+func NewAllowlistRepository(db *gorm.DB) AllowlistRepository {
+	return &allowlistRepositoryImpl{db: db}
+}
+
+// WithTx 实现了事务绑定
+func (r *allowlistRepositoryImpl) WithTx(tx *gorm.DB) AllowlistRepository {
+	return &allowlistRepositoryImpl{db: tx}
+}
+
+// Replace 先删除旧名单再分批写入新名单
+func (r *allowlistRepositoryImpl) Replace(ctx context.Context, activityID uint, entries []*model.AllowlistEntry) error {
+	if err := r.db.WithContext(ctx).Where("activity_id = ?", activityID).Delete(&model.AllowlistEntry{}).Error; err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	for _, entry := range entries {
+		entry.ActivityID = activityID
+	}
+	return r.db.WithContext(ctx).CreateInBatches(entries, 500).Error
+}
+
+// Contains 手机号和学号任意一个在名单中即可 (名单中不会保存空值)
+func (r *allowlistRepositoryImpl) Contains(ctx context.Context, activityID uint, phone, studentID string) (bool, error) {
+	if phone == "" && studentID == "" {
+		return false, nil
+	}
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.AllowlistEntry{}).
+		Where("activity_id = ?", activityID).
+		Where(r.db.Where("kind = ? AND value = ?", model.AllowlistKindPhone, phone).
+			Or("kind = ? AND value = ?", model.AllowlistKindStudentID, studentID)).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// CountByKind 按类型统计名单条目数，没有条目的类型不出现在结果中
+func (r *allowlistRepositoryImpl) CountByKind(ctx context.Context, activityID uint) (map[model.AllowlistKind]int64, error) {
+	var rows []struct {
+		Kind  model.AllowlistKind
+		Count int64
+	}
+	err := r.db.WithContext(ctx).Model(&model.AllowlistEntry{}).
+		Select("kind, COUNT(*) AS count").
+		Where("activity_id = ?", activityID).
+		Group("kind").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[model.AllowlistKind]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Kind] = row.Count
+	}
+	return counts, nil
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/frozenf1sh/gostudent/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// 接口：审计日志仓库
+type AuditLogRepository interface {
+	// WithTx 返回绑定事务的仓库，审计日志与被修改的数据在同一事务中写入
+	WithTx(tx *gorm.DB) AuditLogRepository
+	// 写入审计日志
+	Create(ctx context.Context, log *model.AuditLog) error
+	// 多条件查询审计日志 (分页)
+	List(ctx context.Context, params *model.ListAuditLogsParams) ([]*model.AuditLog, int64, error)
+}
+
+// ----- 实现 -----
+
+// 审计日志仓库实现
+type auditLogRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// 构造函数
+func NewAuditLogRepository(db *gorm.DB) AuditLogRepository {
+	return &auditLogRepositoryImpl{db: db}
+}
+
+// WithTx 实现了事务绑定
+func (r *auditLogRepositoryImpl) WithTx(tx *gorm.DB) AuditLogRepository {
+	return &auditLogRepositoryImpl{db: tx}
+}
+
+// Create 写入审计日志
+func (r *auditLogRepositoryImpl) Create(ctx context.Context, log *model.AuditLog) error {
+	return r.db.WithContext(ctx).Create(log).Error
+}
+
+// List 多条件查询审计日志，按时间倒序
+func (r *auditLogRepositoryImpl) List(ctx context.Context, params *model.ListAuditLogsParams) ([]*model.AuditLog, int64, error) {
+	var logs []*model.AuditLog
+	var total int64
+
+	// 创建两个独立查询构建器，一个计数，一个分页查找
+	query := r.db.WithContext(ctx).Model(&model.AuditLog{})
+	countQuery := r.db.WithContext(ctx).Model(&model.AuditLog{})
+
+	// 应用过滤条件
+	if params.AdminID != 0 {
+		query = query.Where("admin_id = ?", params.AdminID)
+		countQuery = countQuery.Where("admin_id = ?", params.AdminID)
+	}
+	if params.Action != "" {
+		query = query.Where("action = ?", params.Action)
+		countQuery = countQuery.Where("action = ?", params.Action)
+	}
+	if params.TargetType != "" {
+		query = query.Where("target_type = ?", params.TargetType)
+		countQuery = countQuery.Where("target_type = ?", params.TargetType)
+	}
+	if params.TargetID != 0 {
+		query = query.Where("target_id = ?", params.TargetID)
+		countQuery = countQuery.Where("target_id = ?", params.TargetID)
+	}
+	if !params.DateFrom.IsZero() {
+		query = query.Where("created_at >= ?", params.DateFrom)
+		countQuery = countQuery.Where("created_at >= ?", params.DateFrom)
+	}
+	if !params.DateTo.IsZero() {
+		query = query.Where("created_at <= ?", params.DateTo)
+		countQuery = countQuery.Where("created_at <= ?", params.DateTo)
+	}
+
+	// 1. 获取总数
+	if err := countQuery.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 2. 应用分页并查询
+	offset := (params.Page - 1) * params.PageSize
+	if err := query.Order("id DESC").Limit(params.PageSize).Offset(offset).Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return logs, total, nil
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/frozenf1sh/gostudent/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// 接口：参与证明仓库
+type CertificateRepository interface {
+	// 返回一个使用事务的仓库实例
+	WithTx(tx *gorm.DB) CertificateRepository
+
+	// 批量保存新签发的证明
+	CreateBatch(ctx context.Context, certificates []*model.Certificate) error
+	// 按报名ID升序列出某活动已签发的证明
+	ListByActivityID(ctx context.Context, activityID uint) ([]*model.Certificate, error)
+	// 通过验证码查找证明，不存在时返回 nil
+	FindByCode(ctx context.Context, code string) (*model.Certificate, error)
+}
+
+// ----- 实现 -----
+
+// 参与证明仓库实现
+type certificateRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// 构造函数
+func NewCertificateRepository(db *gorm.DB) CertificateRepository {
+	return &certificateRepositoryImpl{db: db}
+}
+
+// WithTx 实现了事务绑定
+func (r *certificateRepositoryImpl) WithTx(tx *gorm.DB) CertificateRepository {
+	return &certificateRepositoryImpl{db: tx}
+}
+
+// CreateBatch 批量保存证明
+func (r *certificateRepositoryImpl) CreateBatch(ctx context.Context, certificates []*model.Certificate) error {
+	if len(certificates) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).CreateInBatches(certificates, 100).Error
+}
+
+// ListByActivityID 列出某活动已签发的证明
+func (r *certificateRepositoryImpl) ListByActivityID(ctx context.Context, activityID uint) ([]*model.Certificate, error) {
+	var certificates []*model.Certificate
+	err := r.db.WithContext(ctx).
+		Where("activity_id = ?", activityID).
+		Order("registration_id ASC").
+		Find(&certificates).Error
+	if err != nil {
+		return nil, err
+	}
+	return certificates, nil
+}
+
+// FindByCode 通过验证码查找证明
+func (r *certificateRepositoryImpl) FindByCode(ctx context.Context, code string) (*model.Certificate, error) {
+	var certificate model.Certificate
+	err := r.db.WithContext(ctx).Where("code = ?", code).First(&certificate).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil // 验证码不存在，不算错误
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &certificate, nil
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/frozenf1sh/gostudent/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// 接口：学分流水仓库，流水只追加不修改
+type CreditRepository interface {
+	// 返回一个使用事务的仓库实例
+	WithTx(tx *gorm.DB) CreditRepository
+
+	// 追加一条学分流水
+	Create(ctx context.Context, entry *model.CreditEntry) error
+	// 批量追加学分流水
+	CreateBatch(ctx context.Context, entries []*model.CreditEntry) error
+	// 按时间倒序列出某学生的学分流水，手机号或学号匹配任一即可 (为空的条件忽略)
+	ListByStudent(ctx context.Context, phone, studentID string) ([]*model.CreditEntry, error)
+}
+
+// ----- 实现 -----
+
+// 学分流水仓库实现
+type creditRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// 构造函数
+func NewCreditRepository(db *gorm.DB) CreditRepository {
+	return &creditRepositoryImpl{db: db}
+}
+
+// WithTx 实现了事务绑定
+func (r *creditRepositoryImpl) WithTx(tx *gorm.DB) CreditRepository {
+	return &creditRepositoryImpl{db: tx}
+}
+
+// Create 追加一条学分流水
+func (r *creditRepositoryImpl) Create(ctx context.Context, entry *model.CreditEntry) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+// CreateBatch 批量追加学分流水
+func (r *creditRepositoryImpl) CreateBatch(ctx context.Context, entries []*model.CreditEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).CreateInBatches(entries, 100).Error
+}
+
+// ListByStudent 按手机号或学号查询学分流水
+func (r *creditRepositoryImpl) ListByStudent(ctx context.Context, phone, studentID string) ([]*model.CreditEntry, error) {
+	var entries []*model.CreditEntry
+	query := r.db.WithContext(ctx).Model(&model.CreditEntry{})
+	switch {
+	case phone != "" && studentID != "":
+		query = query.Where("phone = ? OR student_id = ?", phone, studentID)
+	case phone != "":
+		query = query.Where("phone = ?", phone)
+	default:
+		query = query.Where("student_id = ?", studentID)
+	}
+	if err := query.Order("created_at DESC, id DESC").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -56,9 +56,7 @@ func GormInit() (db *gorm.DB) {
 	slog.Info("已连接到数据库")
 
 	slog.Info("开始数据库自动迁移")
-	err = db.AutoMigrate(&model.Admin{})
-	err = errors.Join(err, db.AutoMigrate(&model.Activity{}))
-	err = errors.Join(err, db.AutoMigrate(&model.Registration{}))
+	err = AutoMigrate(db)
 	if err != nil {
 		slog.Error("数据库自动迁移失败", "reason", err)
 		os.Exit(1)
@@ -67,3 +65,18 @@ func GormInit() (db *gorm.DB) {
 
 	return db
 }
+
+// AutoMigrate 迁移所有表结构
+func AutoMigrate(db *gorm.DB) error {
+	return errors.Join(
+		db.AutoMigrate(&model.Admin{}),
+		db.AutoMigrate(&model.Activity{}),
+		db.AutoMigrate(&model.Registration{}),
+		db.AutoMigrate(&model.AuditLog{}),
+		db.AutoMigrate(&model.AllowlistEntry{}),
+		db.AutoMigrate(&model.LotteryDraw{}),
+		db.AutoMigrate(&model.CreditEntry{}),
+		db.AutoMigrate(&model.Certificate{}),
+		db.AutoMigrate(&model.Notification{}),
+	)
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/frozenf1sh/gostudent/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// 接口：抽签开奖记录仓库
+type LotteryRepository interface {
+	// 返回一个使用事务的仓库实例
+	WithTx(tx *gorm.DB) LotteryRepository
+
+	// 保存开奖记录
+	Create(ctx context.Context, draw *model.LotteryDraw) error
+	// 查找活动的开奖记录，未开奖时返回 nil
+	FindByActivityID(ctx context.Context, activityID uint) (*model.LotteryDraw, error)
+}
+
+// ----- 实现 -----
+
+// 抽签开奖记录仓库实现
+type lotteryRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// 构造函数
+func NewLotteryRepository(db *gorm.DB) LotteryRepository {
+	return &lotteryRepositoryImpl{db: db}
+}
+
+// WithTx 实现了事务绑定
+func (r *lotteryRepositoryImpl) WithTx(tx *gorm.DB) LotteryRepository {
+	return &lotteryRepositoryImpl{db: tx}
+}
+
+// Create 保存开奖记录，只追加不修改
+func (r *lotteryRepositoryImpl) Create(ctx context.Context, draw *model.LotteryDraw) error {
+	return r.db.WithContext(ctx).Create(draw).Error
+}
+
+// FindByActivityID 查找活动的开奖记录
+func (r *lotteryRepositoryImpl) FindByActivityID(ctx context.Context, activityID uint) (*model.LotteryDraw, error) {
+	var draw model.LotteryDraw
+	err := r.db.WithContext(ctx).Where("activity_id = ?", activityID).First(&draw).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil // 尚未开奖，不算错误
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &draw, nil
+}
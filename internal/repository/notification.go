@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/frozenf1sh/gostudent/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 接口：通知队列仓库
+type NotificationRepository interface {
+	// WithTx 返回绑定事务的仓库，通知与触发通知的数据在同一事务中写入
+	WithTx(tx *gorm.DB) NotificationRepository
+	// 批量写入通知
+	CreateBatch(ctx context.Context, notifications []*model.Notification) error
+	// 领取到期的待发送通知，并把它们的下次发送时间推迟 lease，避免被其他实例重复领取
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*model.Notification, error)
+	// 保存发送结果 (状态、尝试次数、下次发送时间、失败原因、发送时间)
+	UpdateDelivery(ctx context.Context, notification *model.Notification) error
+	// 通过主键id查找
+	FindByID(ctx context.Context, id uint) (*model.Notification, error)
+	// 多条件查询通知 (分页)
+	List(ctx context.Context, params *model.ListNotificationsParams) ([]*model.Notification, int64, error)
+}
+
+// ----- 实现 -----
+
+// 通知队列仓库实现
+type notificationRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// 构造函数
+func NewNotificationRepository(db *gorm.DB) NotificationRepository {
+	return &notificationRepositoryImpl{db: db}
+}
+
+// WithTx 实现了事务绑定
+func (r *notificationRepositoryImpl) WithTx(tx *gorm.DB) NotificationRepository {
+	return &notificationRepositoryImpl{db: tx}
+}
+
+// CreateBatch 批量写入通知
+func (r *notificationRepositoryImpl) CreateBatch(ctx context.Context, notifications []*model.Notification) error {
+	return r.db.WithContext(ctx).CreateInBatches(notifications, 100).Error
+}
+
+// ClaimDue 在独立事务中锁定到期的待发送通知 (跳过已被其他实例锁定的行)，推迟下次发送时间后提交
+// 领取后进程退出的通知会在 lease 之后被重新领取
+func (r *notificationRepositoryImpl) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*model.Notification, error) {
+	var notifications []*model.Notification
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", model.NotificationStatusPending, now).
+			Order("next_attempt_at").
+			Limit(limit).
+			Find(&notifications).Error
+		if err != nil || len(notifications) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(notifications))
+		for i, n := range notifications {
+			ids[i] = n.ID
+		}
+		return tx.Model(&model.Notification{}).Where("id IN ?", ids).Update("next_attempt_at", now.Add(lease)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+// UpdateDelivery 保存发送结果
+func (r *notificationRepositoryImpl) UpdateDelivery(ctx context.Context, notification *model.Notification) error {
+	return r.db.WithContext(ctx).Model(&model.Notification{}).Where("id = ?", notification.ID).Updates(map[string]any{
+		"status":          notification.Status,
+		"attempts":        notification.Attempts,
+		"next_attempt_at": notification.NextAttemptAt,
+		"last_error":      notification.LastError,
+		"sent_at":         notification.SentAt,
+	}).Error
+}
+
+// FindByID 通过主键查找通知
+func (r *notificationRepositoryImpl) FindByID(ctx context.Context, id uint) (*model.Notification, error) {
+	var notification model.Notification
+	if err := r.db.WithContext(ctx).First(&notification, id).Error; err != nil {
+		return nil, err
+	}
+	return &notification, nil
+}
+
+// List 多条件查询通知，按时间倒序
+func (r *notificationRepositoryImpl) List(ctx context.Context, params *model.ListNotificationsParams) ([]*model.Notification, int64, error) {
+	var notifications []*model.Notification
+	var total int64
+
+	// 创建两个独立查询构建器，一个计数，一个分页查找
+	query := applyNotificationFilters(r.db.WithContext(ctx).Model(&model.Notification{}), params)
+	countQuery := applyNotificationFilters(r.db.WithContext(ctx).Model(&model.Notification{}), params)
+
+	// 1. 获取总数
+	if err := countQuery.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 2. 应用分页并查询
+	offset := (params.Page - 1) * params.PageSize
+	if err := query.Order("id DESC").Limit(params.PageSize).Offset(offset).Find(&notifications).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return notifications, total, nil
+}
+
+// applyNotificationFilters 应用通知查询的过滤条件
+func applyNotificationFilters(query *gorm.DB, params *model.ListNotificationsParams) *gorm.DB {
+	if params.Status != "" {
+		query = query.Where("status = ?", params.Status)
+	}
+	if params.Event != "" {
+		query = query.Where("event = ?", params.Event)
+	}
+	if params.ActivityID != 0 {
+		query = query.Where("activity_id = ?", params.ActivityID)
+	}
+	if params.Recipient != "" {
+		query = query.Where("recipient = ?", params.Recipient)
+	}
+	return query
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/frozenf1sh/gostudent/internal/model"
@@ -16,6 +17,10 @@ type RegistrationRepository interface {
 
 	// 创建一个报名
 	Create(ctx context.Context, registration *model.Registration) error
+	// 更新报名记录 (全字段)
+	Update(ctx context.Context, registration *model.Registration) error
+	// 删除报名记录
+	Delete(ctx context.Context, id uint) error
 
 	// 通过活动id和手机号检查报名是否已存在报名
 	FindByActivityAndPhone(ctx context.Context, activityID uint, phone string) (*model.Registration, error)
@@ -23,10 +28,34 @@ type RegistrationRepository interface {
 	ListByActivityID(ctx context.Context, activityID uint, page, pageSize int) ([]*model.Registration, int64, error)
 	// 多条件查询报名记录
 	List(ctx context.Context, params *model.ListRegistrationsParams) ([]*model.Registration, int64, error)
+	// 分批读取符合条件的全部报名记录，用于导出
+	ListInBatches(ctx context.Context, params *model.ListRegistrationsParams, batchSize int, fn func(batch []*model.Registration) error) error
 	// 通过主键id查找
 	FindByID(ctx context.Context, id uint) (*model.Registration, error)
-	// 更新签到状态+时间
-	UpdateSignInStatus(ctx context.Context, registrationID uint, signedIn bool, signedInAt time.Time) error
+	// 更新签到状态+时间 (取消签到时 signedInAt 传 nil，同时清除签退记录)
+	UpdateSignInStatus(ctx context.Context, registrationID uint, signedIn bool, signedInAt *time.Time, isLate bool) error
+	// 记录签退时间、是否早退和参与时长
+	UpdateSignOut(ctx context.Context, registrationID uint, signedOutAt time.Time, leftEarly bool, attendedSeconds int64) error
+
+	// 按候补顺序列出某活动的所有候补记录
+	ListWaitlist(ctx context.Context, activityID uint) ([]*model.Registration, error)
+	// 查找某活动候补队列中排在最前面的记录，没有候补时返回 nil
+	FindFirstWaitlisted(ctx context.Context, activityID uint) (*model.Registration, error)
+	// 获取某活动下一个可用的候补序号
+	NextWaitlistPosition(ctx context.Context, activityID uint) (int, error)
+
+	// 统计某活动占用名额 (已确认) 的报名数
+	CountConfirmed(ctx context.Context, activityID uint) (int64, error)
+	// 按报名ID升序列出某活动等待开奖的抽签报名
+	ListApplied(ctx context.Context, activityID uint) ([]*model.Registration, error)
+	// 统计某活动某学院占用名额 (已确认) 的报名数，用于学院名额限制
+	CountConfirmedByCollege(ctx context.Context, activityID uint, college string) (int64, error)
+	// 列出某活动所有未取消报名的手机号
+	ListActivePhones(ctx context.Context, activityID uint) ([]string, error)
+	// 按报名ID升序列出某活动已确认、已签到且参与时长不少于 minAttendedSeconds 的报名
+	ListAttended(ctx context.Context, activityID uint, minAttendedSeconds int64) ([]*model.Registration, error)
+	// 按报名ID升序列出某活动指定状态的报名，用于发送活动变更、取消通知
+	ListByStatuses(ctx context.Context, activityID uint, statuses []model.RegistrationStatus) ([]*model.Registration, error)
 }
 
 // ----- 实现 -----
@@ -52,6 +81,16 @@ func (r *registrationRepositoryImpl) Create(ctx context.Context, registration *m
 	return r.db.WithContext(ctx).Create(registration).Error
 }
 
+// Update 更新报名记录
+func (r *registrationRepositoryImpl) Update(ctx context.Context, registration *model.Registration) error {
+	return r.db.WithContext(ctx).Save(registration).Error
+}
+
+// Delete 删除报名记录
+func (r *registrationRepositoryImpl) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&model.Registration{}, id).Error
+}
+
 // FindByActivityAndPhone 检查重复报名
 // 可以在事务中调用 (使用 WithTx)
 func (r *registrationRepositoryImpl) FindByActivityAndPhone(ctx context.Context, activityID uint, phone string) (*model.Registration, error) {
@@ -87,22 +126,8 @@ func (r *registrationRepositoryImpl) List(ctx context.Context, params *model.Lis
 	var total int64
 
 	// 构造查询条件
-	query := r.db.WithContext(ctx).Model(&model.Registration{})
-	countQuery := r.db.WithContext(ctx).Model(&model.Registration{})
-
-	// 应用过滤条件
-	if params.ActivityID != 0 {
-		query = query.Where("activity_id = ?", params.ActivityID)
-		countQuery = countQuery.Where("activity_id = ?", params.ActivityID)
-	}
-	if params.ParticipantPhone != "" {
-		query = query.Where("participant_phone = ?", params.ParticipantPhone)
-		countQuery = countQuery.Where("participant_phone = ?", params.ParticipantPhone)
-	}
-	if params.IsSignedIn != nil {
-		query = query.Where("is_signed_in = ?", *params.IsSignedIn)
-		countQuery = countQuery.Where("is_signed_in = ?", *params.IsSignedIn)
-	}
+	query := applyRegistrationFilters(r.db.WithContext(ctx).Model(&model.Registration{}), params)
+	countQuery := applyRegistrationFilters(r.db.WithContext(ctx).Model(&model.Registration{}), params)
 
 	// 1. 获取总数
 	if err := countQuery.Count(&total).Error; err != nil {
@@ -118,6 +143,39 @@ func (r *registrationRepositoryImpl) List(ctx context.Context, params *model.Lis
 	return registrations, total, nil
 }
 
+// ListInBatches 按报名ID升序分批读取符合条件的全部报名记录 (忽略分页参数)，每批调用一次 fn
+// fn 返回错误时停止读取并返回该错误
+func (r *registrationRepositoryImpl) ListInBatches(ctx context.Context, params *model.ListRegistrationsParams, batchSize int, fn func(batch []*model.Registration) error) error {
+	var batch []*model.Registration
+	query := applyRegistrationFilters(r.db.WithContext(ctx).Model(&model.Registration{}), params)
+	return query.FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+		return fn(batch)
+	}).Error
+}
+
+// applyRegistrationFilters 应用报名记录的过滤条件
+func applyRegistrationFilters(query *gorm.DB, params *model.ListRegistrationsParams) *gorm.DB {
+	if params.ActivityID != 0 {
+		query = query.Where("activity_id = ?", params.ActivityID)
+	}
+	if params.ParticipantPhone != "" {
+		query = query.Where("participant_phone = ?", params.ParticipantPhone)
+	}
+	if params.IsSignedIn != nil {
+		query = query.Where("is_signed_in = ?", *params.IsSignedIn)
+	}
+	if params.IsLate != nil {
+		query = query.Where("is_late = ?", *params.IsLate)
+	}
+	if params.LeftEarly != nil {
+		query = query.Where("left_early = ?", *params.LeftEarly)
+	}
+	if params.Status != "" {
+		query = query.Where("status = ?", params.Status)
+	}
+	return query
+}
+
 // 通过ID查找报名记录
 func (r *registrationRepositoryImpl) FindByID(ctx context.Context, id uint) (*model.Registration, error) {
 	var reg model.Registration
@@ -128,9 +186,135 @@ func (r *registrationRepositoryImpl) FindByID(ctx context.Context, id uint) (*mo
 }
 
 // 更新签到状态
-func (r *registrationRepositoryImpl) UpdateSignInStatus(ctx context.Context, registrationID uint, signedIn bool, signedInAt time.Time) error {
-	return r.db.WithContext(ctx).Model(&model.Registration{}).Where("id = ?", registrationID).Updates(map[string]any{
+func (r *registrationRepositoryImpl) UpdateSignInStatus(ctx context.Context, registrationID uint, signedIn bool, signedInAt *time.Time, isLate bool) error {
+	updates := map[string]any{
 		"is_signed_in": signedIn,
 		"signed_in_at": signedInAt,
+		"is_late":      isLate,
+	}
+	if !signedIn {
+		updates["signed_out_at"] = nil
+		updates["left_early"] = false
+		updates["attended_seconds"] = 0
+	}
+	return r.db.WithContext(ctx).Model(&model.Registration{}).Where("id = ?", registrationID).Updates(updates).Error
+}
+
+// UpdateSignOut 记录签退
+func (r *registrationRepositoryImpl) UpdateSignOut(ctx context.Context, registrationID uint, signedOutAt time.Time, leftEarly bool, attendedSeconds int64) error {
+	return r.db.WithContext(ctx).Model(&model.Registration{}).Where("id = ?", registrationID).Updates(map[string]any{
+		"signed_out_at":    signedOutAt,
+		"left_early":       leftEarly,
+		"attended_seconds": attendedSeconds,
 	}).Error
 }
+
+// ListWaitlist 按候补顺序列出候补记录
+func (r *registrationRepositoryImpl) ListWaitlist(ctx context.Context, activityID uint) ([]*model.Registration, error) {
+	var registrations []*model.Registration
+	err := r.db.WithContext(ctx).
+		Where("activity_id = ? AND status = ?", activityID, model.RegistrationStatusWaitlisted).
+		Order("waitlist_position ASC, id ASC").
+		Find(&registrations).Error
+	if err != nil {
+		return nil, err
+	}
+	return registrations, nil
+}
+
+// FindFirstWaitlisted 查找候补队列队首
+// 必须在持有活动行锁的事务中调用，以保证递补顺序
+func (r *registrationRepositoryImpl) FindFirstWaitlisted(ctx context.Context, activityID uint) (*model.Registration, error) {
+	var reg model.Registration
+	err := r.db.WithContext(ctx).
+		Where("activity_id = ? AND status = ?", activityID, model.RegistrationStatusWaitlisted).
+		Order("waitlist_position ASC, id ASC").
+		First(&reg).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil // 没有候补，不算错误
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &reg, nil
+}
+
+// NextWaitlistPosition 获取下一个候补序号 (当前最大序号 + 1)
+func (r *registrationRepositoryImpl) NextWaitlistPosition(ctx context.Context, activityID uint) (int, error) {
+	var maxPosition int
+	err := r.db.WithContext(ctx).Model(&model.Registration{}).
+		Where("activity_id = ? AND status = ?", activityID, model.RegistrationStatusWaitlisted).
+		Select("COALESCE(MAX(waitlist_position), 0)").
+		Scan(&maxPosition).Error
+	if err != nil {
+		return 0, err
+	}
+	return maxPosition + 1, nil
+}
+
+// CountConfirmed 统计某活动已确认 (占用名额) 的报名数
+func (r *registrationRepositoryImpl) CountConfirmed(ctx context.Context, activityID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.Registration{}).
+		Where("activity_id = ? AND status = ?", activityID, model.RegistrationStatusConfirmed).
+		Count(&count).Error
+	return count, err
+}
+
+// ListActivePhones 列出某活动所有未取消报名 (已确认和候补) 的手机号
+func (r *registrationRepositoryImpl) ListActivePhones(ctx context.Context, activityID uint) ([]string, error) {
+	var phones []string
+	err := r.db.WithContext(ctx).Model(&model.Registration{}).
+		Where("activity_id = ? AND status <> ?", activityID, model.RegistrationStatusCancelled).
+		Pluck("participant_phone", &phones).Error
+	return phones, err
+}
+
+// ListAttended 列出满足考勤条件的报名，用于发放学分
+func (r *registrationRepositoryImpl) ListAttended(ctx context.Context, activityID uint, minAttendedSeconds int64) ([]*model.Registration, error) {
+	var registrations []*model.Registration
+	query := r.db.WithContext(ctx).
+		Where("activity_id = ? AND status = ? AND is_signed_in = ?", activityID, model.RegistrationStatusConfirmed, true)
+	if minAttendedSeconds > 0 {
+		query = query.Where("attended_seconds >= ?", minAttendedSeconds)
+	}
+	if err := query.Order("id ASC").Find(&registrations).Error; err != nil {
+		return nil, err
+	}
+	return registrations, nil
+}
+
+// ListByStatuses 列出某活动指定状态的报名
+func (r *registrationRepositoryImpl) ListByStatuses(ctx context.Context, activityID uint, statuses []model.RegistrationStatus) ([]*model.Registration, error) {
+	var registrations []*model.Registration
+	err := r.db.WithContext(ctx).
+		Where("activity_id = ? AND status IN ?", activityID, statuses).
+		Order("id ASC").
+		Find(&registrations).Error
+	if err != nil {
+		return nil, err
+	}
+	return registrations, nil
+}
+
+// CountConfirmedByCollege 统计某活动某学院已确认 (占用名额) 的报名数
+func (r *registrationRepositoryImpl) CountConfirmedByCollege(ctx context.Context, activityID uint, college string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.Registration{}).
+		Where("activity_id = ? AND participant_college = ? AND status = ?", activityID, college, model.RegistrationStatusConfirmed).
+		Count(&count).Error
+	return count, err
+}
+
+// ListApplied 按报名ID升序列出等待开奖的报名，顺序固定以保证抽签可复现
+func (r *registrationRepositoryImpl) ListApplied(ctx context.Context, activityID uint) ([]*model.Registration, error) {
+	var registrations []*model.Registration
+	err := r.db.WithContext(ctx).
+		Where("activity_id = ? AND status = ?", activityID, model.RegistrationStatusApplied).
+		Order("id ASC").
+		Find(&registrations).Error
+	if err != nil {
+		return nil, err
+	}
+	return registrations, nil
+}
@@ -0,0 +1,269 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/frozenf1sh/gostudent/internal/config"
+	"github.com/frozenf1sh/gostudent/internal/handler"
+	"github.com/frozenf1sh/gostudent/internal/model"
+	"github.com/frozenf1sh/gostudent/internal/repository"
+	"github.com/frozenf1sh/gostudent/internal/service"
+	"github.com/frozenf1sh/gostudent/pkg/fishlogger"
+	"github.com/frozenf1sh/gostudent/pkg/redis"
+	"github.com/frozenf1sh/gostudent/pkg/utils"
+	"github.com/gin-gonic/gin"
+	goredis "github.com/redis/go-redis/v9"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testServer 使用 SQLite 内存数据库和 miniredis 组装完整路由
+type testServer struct {
+	engine *gin.Engine
+	db     *gorm.DB
+	token  string // 超级管理员的访问令牌
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	fishlogger.AppLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	config.GlobalConfig = config.Config{}
+	config.GlobalConfig.JWT.Secret = "test-secret"
+	config.GlobalConfig.JWT.AdminExpiresIn = 15 * time.Minute
+	config.GlobalConfig.JWT.RefreshExpiresIn = time.Hour
+	config.GlobalConfig.LoginProtection.RateLimit = 100
+	config.GlobalConfig.LoginProtection.RateBurst = 100
+	utils.InitJWT()
+
+	mr := miniredis.RunT(t)
+	redis.Client = goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repository.AutoMigrate(db); err != nil {
+		t.Fatal(err)
+	}
+
+	adminRepo := repository.NewAdminRepository(db)
+	activityRepo := repository.NewActivityRepository(db)
+	registrationRepo := repository.NewRegistrationRepository(db)
+	auditRepo := repository.NewAuditLogRepository(db)
+	allowlistRepo := repository.NewAllowlistRepository(db)
+	lotteryRepo := repository.NewLotteryRepository(db)
+	creditRepo := repository.NewCreditRepository(db)
+	certificateRepo := repository.NewCertificateRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db)
+
+	fastRegistrationSvc := service.NewFastRegistrationService(db, activityRepo, registrationRepo, allowlistRepo, notificationRepo)
+	adminSvc := service.NewAdminService(db, adminRepo, auditRepo)
+	activitySvc := service.NewActivityService(db, activityRepo, registrationRepo, adminRepo, auditRepo, allowlistRepo, lotteryRepo, creditRepo, notificationRepo, fastRegistrationSvc)
+	registrationSvc := service.NewRegistrationService(db, activityRepo, registrationRepo, auditRepo, allowlistRepo, notificationRepo, fastRegistrationSvc)
+
+	engine := InitRouter(
+		handler.NewAdminHandler(adminSvc),
+		handler.NewActivityHandler(activitySvc),
+		handler.NewRegistrationHandler(registrationSvc),
+		handler.NewDashboardHandler(db, activityRepo, registrationRepo),
+		handler.NewAuditHandler(service.NewAuditService(auditRepo)),
+		handler.NewCreditHandler(service.NewCreditService(db, creditRepo, auditRepo)),
+		handler.NewCertificateHandler(service.NewCertificateService(db, activityRepo, registrationRepo, certificateRepo, auditRepo)),
+		handler.NewNotificationHandler(service.NewNotificationService(db, notificationRepo, auditRepo, nil)),
+		adminSvc, activitySvc, registrationSvc,
+	)
+	s := &testServer{engine: engine, db: db}
+
+	if _, err := adminSvc.CreateAdmin(context.Background(), "root", "password123", model.AdminRoleSuper); err != nil {
+		t.Fatal(err)
+	}
+	var login model.AdminLoginResponse
+	if code := s.do(t, http.MethodPost, "/api/v1/admin/login", `{"username":"root","password":"password123"}`, &login); code != http.StatusOK {
+		t.Fatalf("login status = %d", code)
+	}
+	s.token = login.Token
+	return s
+}
+
+// do 发送请求并把响应中的 data 解析到 out，返回 HTTP 状态码
+func (s *testServer) do(t *testing.T, method, path, body string, out any) int {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if s.token != "" && strings.HasPrefix(path, "/api/v1/admin/") {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	w := httptest.NewRecorder()
+	s.engine.ServeHTTP(w, req)
+
+	if out != nil && w.Code == http.StatusOK {
+		var resp struct {
+			Data json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s %s: invalid response %s", method, path, w.Body.String())
+		}
+		if err := json.Unmarshal(resp.Data, out); err != nil {
+			t.Fatalf("%s %s: invalid data %s", method, path, resp.Data)
+		}
+	}
+	return w.Code
+}
+
+// seedActivities 为每种状态创建一个活动，返回状态到活动ID的映射
+func seedActivities(t *testing.T, db *gorm.DB) map[model.ActivityStatus]uint {
+	t.Helper()
+	now := time.Now()
+	ids := make(map[model.ActivityStatus]uint)
+	for _, status := range []model.ActivityStatus{
+		model.ActivityStatusDraft,
+		model.ActivityStatusPublished,
+		model.ActivityStatusClosed,
+		model.ActivityStatusFinished,
+		model.ActivityStatusCancelled,
+	} {
+		activity := &model.Activity{
+			AdminID:              1,
+			Title:                "活动 " + string(status),
+			Type:                 "讲座",
+			StartTime:            now.Add(48 * time.Hour),
+			EndTime:              now.Add(50 * time.Hour),
+			Location:             "A101",
+			Status:               status,
+			RegistrationDeadline: now.Add(24 * time.Hour),
+		}
+		if err := db.Create(activity).Error; err != nil {
+			t.Fatal(err)
+		}
+		ids[status] = activity.ID
+	}
+	return ids
+}
+
+// 公开接口只展示已发布、已截止报名和已结束的活动，草稿和已取消的活动视为不存在；
+// 公开接口的响应不包含管理字段，管理接口仍返回所有状态的活动和完整字段
+func TestPublicActivities(t *testing.T) {
+	s := newTestServer(t) // 创建管理员和登录需要计算 bcrypt，各子测试共用
+	ids := seedActivities(t, s.db)
+
+	t.Run("DetailHidesUnpublished", func(t *testing.T) { testPublicDetailHidesUnpublished(t, s, ids) })
+	t.Run("ListHidesUnpublished", func(t *testing.T) { testPublicListHidesUnpublished(t, s, ids) })
+	t.Run("ResponseOmitsAdminFields", func(t *testing.T) { testPublicResponseOmitsAdminFields(t, s, ids) })
+}
+
+func testPublicDetailHidesUnpublished(t *testing.T, s *testServer, ids map[model.ActivityStatus]uint) {
+	for status, id := range ids {
+		code := s.do(t, http.MethodGet, fmt.Sprintf("/api/v1/activities/%d", id), "", nil)
+		want := http.StatusOK
+		if !model.IsPublicActivityStatus(status) {
+			want = http.StatusNotFound
+		}
+		if code != want {
+			t.Errorf("GET public activity in %s: status = %d, want %d", status, code, want)
+		}
+	}
+}
+
+func testPublicListHidesUnpublished(t *testing.T, s *testServer, ids map[model.ActivityStatus]uint) {
+	var page struct {
+		List  []model.PublicActivityResponse `json:"list"`
+		Total int64                          `json:"total"`
+	}
+	if code := s.do(t, http.MethodGet, "/api/v1/activities?page_size=50", "", &page); code != http.StatusOK {
+		t.Fatalf("list status = %d", code)
+	}
+	if page.Total != int64(len(model.PublicActivityStatuses)) || len(page.List) != len(model.PublicActivityStatuses) {
+		t.Fatalf("public list total = %d, len = %d, want %d", page.Total, len(page.List), len(model.PublicActivityStatuses))
+	}
+	for _, item := range page.List {
+		if !model.IsPublicActivityStatus(item.Status) {
+			t.Errorf("public list contains activity %d in %s", item.ID, item.Status)
+		}
+	}
+
+	// 显式按草稿或已取消状态过滤也查不到
+	for _, status := range []model.ActivityStatus{model.ActivityStatusDraft, model.ActivityStatusCancelled} {
+		page.List, page.Total = nil, 0
+		if code := s.do(t, http.MethodGet, "/api/v1/activities?status="+string(status), "", &page); code != http.StatusOK {
+			t.Fatalf("list status = %d", code)
+		}
+		if page.Total != 0 || len(page.List) != 0 {
+			t.Errorf("public list with status=%s returned %d activities (activity %d should be hidden)", status, page.Total, ids[status])
+		}
+	}
+}
+
+func testPublicResponseOmitsAdminFields(t *testing.T, s *testServer, ids map[model.ActivityStatus]uint) {
+	adminOnly := []string{"admin_id", "created_at", "fast_registration", "lottery_weights", "publish_at", "publish_error", "cancel_reason", "cancelled_at", "credits_granted_at"}
+
+	var public map[string]any
+	if code := s.do(t, http.MethodGet, fmt.Sprintf("/api/v1/activities/%d", ids[model.ActivityStatusPublished]), "", &public); code != http.StatusOK {
+		t.Fatalf("public detail status = %d", code)
+	}
+	for _, field := range adminOnly {
+		if _, ok := public[field]; ok {
+			t.Errorf("public activity response contains admin-only field %q", field)
+		}
+	}
+
+	var page struct {
+		List []map[string]any `json:"list"`
+	}
+	if code := s.do(t, http.MethodGet, "/api/v1/activities?page_size=50", "", &page); code != http.StatusOK {
+		t.Fatalf("public list status = %d", code)
+	}
+	for _, item := range page.List {
+		for _, field := range adminOnly {
+			if _, ok := item[field]; ok {
+				t.Errorf("public activity list item contains admin-only field %q", field)
+			}
+		}
+	}
+
+	// 管理接口：所有状态可见，返回完整的 ActivityResponse
+	for status, id := range ids {
+		var detail map[string]any
+		if code := s.do(t, http.MethodGet, fmt.Sprintf("/api/v1/admin/activities/%d", id), "", &detail); code != http.StatusOK {
+			t.Fatalf("admin detail for %s: status = %d", status, code)
+		}
+		for _, field := range []string{"admin_id", "created_at", "fast_registration"} {
+			if _, ok := detail[field]; !ok {
+				t.Errorf("admin activity response for %s is missing %q", status, field)
+			}
+		}
+	}
+	var adminPage struct {
+		List  []model.ActivityResponse `json:"list"`
+		Total int64                    `json:"total"`
+	}
+	if code := s.do(t, http.MethodGet, "/api/v1/admin/activities?page_size=50", "", &adminPage); code != http.StatusOK {
+		t.Fatalf("admin list status = %d", code)
+	}
+	if adminPage.Total != int64(len(ids)) {
+		t.Errorf("admin list total = %d, want %d", adminPage.Total, len(ids))
+	}
+	var draft struct {
+		List []model.ActivityResponse `json:"list"`
+	}
+	if code := s.do(t, http.MethodGet, "/api/v1/admin/activities?status=DRAFT", "", &draft); code != http.StatusOK {
+		t.Fatalf("admin list status = %d", code)
+	}
+	if len(draft.List) != 1 || draft.List[0].ID != ids[model.ActivityStatusDraft] {
+		t.Errorf("admin list with status=DRAFT = %+v, want activity %d", draft.List, ids[model.ActivityStatusDraft])
+	}
+}
@@ -1,10 +1,14 @@
 package router
 
 import (
+	"log/slog"
 	"net/http"
 
+	"github.com/frozenf1sh/gostudent/internal/config"
 	"github.com/frozenf1sh/gostudent/internal/handler"
 	"github.com/frozenf1sh/gostudent/internal/middleware"
+	"github.com/frozenf1sh/gostudent/internal/model"
+	"github.com/frozenf1sh/gostudent/internal/service"
 	"github.com/frozenf1sh/gostudent/pkg/fishlogger"
 	"github.com/frozenf1sh/gostudent/pkg/utils"
 	"github.com/gin-gonic/gin"
@@ -16,9 +20,25 @@ func InitRouter(
 	activityH handler.ActivityHandler,
 	registrationH handler.RegistrationHandler,
 	dashboardH handler.DashboardHandler, // 新增参数
+	auditH handler.AuditHandler,
+	creditH handler.CreditHandler,
+	certificateH handler.CertificateHandler,
+	notificationH handler.NotificationHandler,
+	adminSvc service.AdminService, // 认证中间件加载管理员角色
+	activitySvc service.ActivityService, // 校验活动修改权限
+	registrationSvc service.RegistrationService, // 通过报名记录定位所属活动
 ) *gin.Engine {
 	// 创建 Gin 实例
 	r := gin.New()
+	// Handler 将 *gin.Context 直接作为 context 传给 Service，
+	// 开启后 Service 可以读取中间件写入 c.Request.Context() 的值 (如审计操作者)
+	r.ContextWithFallback = true
+	// 客户端IP用于登录锁定和审计日志，只信任配置的代理转发的请求头，防止客户端伪造 X-Forwarded-For
+	if err := r.SetTrustedProxies(config.GlobalConfig.Server.TrustedProxies); err != nil {
+		slog.Error("可信代理配置有误", "reason", err)
+		panic("可信代理配置有误")
+	}
+	r.TrustedPlatform = config.GlobalConfig.Server.TrustedPlatform
 
 	// 1. 设置全局中间件
 	// 添加Logger
@@ -26,6 +46,8 @@ func InitRouter(
 	r.Use(gin.LoggerWithWriter(ginAdapter))
 	// 恢复器
 	r.Use(gin.Recovery())
+	// 请求ID
+	r.Use(middleware.RequestID())
 	// 跨域处理 (CORS)
 	// r.Use(middleware.GetCors())
 
@@ -39,51 +61,141 @@ func InitRouter(
 	// =========================================================
 	publicGroup := r.Group("/api/v1")
 	{
-		// P1 & P2: 活动查询
-		publicGroup.GET("/activities", activityH.ListActivities)
+		// P1 & P2: 活动查询 (只展示已发布的活动，使用公开 DTO)
+		publicGroup.GET("/activities", activityH.ListPublicActivities)
 		// 修正: 将 :id 统一为 :activity_id 以匹配 Handler 中的 c.Param("activity_id")
-		publicGroup.GET("/activities/:activity_id", activityH.GetActivityByID)
+		publicGroup.GET("/activities/:activity_id", activityH.GetPublicActivityByID)
 
 		// P3 & P4: 活动报名与签到 (路径已规范)
-		publicGroup.POST("/activities/:activity_id/register", registrationH.Register)
-		publicGroup.POST("/activities/:activity_id/signin", registrationH.SignIn)
-
-		// 获取签到Token
+		// 报名和签到支持 Idempotency-Key 请求头，网络重试时重放首次响应
+		idempotency := middleware.Idempotency(config.GlobalConfig.Idempotency.TTL)
+		publicGroup.POST("/activities/:activity_id/register", idempotency, registrationH.Register)
+		publicGroup.DELETE("/activities/:activity_id/register", registrationH.CancelRegistration)
+		publicGroup.POST("/activities/:activity_id/register/status", registrationH.GetMyRegistrationStatus)
+		publicGroup.POST("/activities/:activity_id/signin", idempotency, registrationH.SignIn)
+		publicGroup.POST("/activities/:activity_id/signout", idempotency, registrationH.SignOut)
+
+		// 获取签到动态码 (需携带大屏展示密钥 display_key)
 		publicGroup.GET("/activities/:activity_id/signin-token", activityH.GetSignInToken)
+		publicGroup.GET("/activities/:activity_id/signout-token", activityH.GetSignOutToken)
+
+		// 查验参与证明
+		publicGroup.GET("/certificates/:code", certificateH.VerifyCertificate)
 
 		// A1: 管理员登录 (唯一一个在 Public Group 中的 Admin 接口)
-		publicGroup.POST("/admin/login", adminH.Login)
+		loginLimit := config.GlobalConfig.LoginProtection
+		publicGroup.POST("/admin/login", middleware.RateLimit(loginLimit.RateLimit, loginLimit.RateBurst), adminH.Login)
+		publicGroup.POST("/admin/refresh", adminH.Refresh)
 	}
 
 	// =========================================================
-	// 3. Admin Group (管理接口: 需要 JWT 认证)
+	// 3. Admin Group (管理接口: 需要 JWT 认证，按角色分组授权)
 	// =========================================================
-	// 假设 middleware.JWTAuth 是我们用于校验 Token 的中间件
 	adminGroup := r.Group("/api/v1/admin")
-	adminGroup.Use(middleware.JWTAuthAdmin()) // 应用 JWT 认证中间件
+	adminGroup.Use(middleware.JWTAuthAdmin(adminSvc)) // 应用 JWT 认证中间件
 	{
-		// A2 - A6: 活动管理 (CRUD + 发布)
-		adminGroup.POST("/activities", activityH.CreateActivity)
+		// 只读接口：所有角色均可访问
 		adminGroup.GET("/activities", activityH.ListActivities) // A5: 管理员查询所有活动（包含草稿等状态）
-
+		adminGroup.GET("/activities/scheduled-publishes", activityH.ListScheduledPublishes)
 		// 修正: 将 :id 统一为 :activity_id 以匹配 Handler 中的 c.Param("activity_id")
 		adminGroup.GET("/activities/:activity_id", activityH.GetActivityByID)
-		adminGroup.PUT("/activities/:activity_id", activityH.UpdateActivity)
-		adminGroup.DELETE("/activities/:activity_id", activityH.DeleteActivity)
+		adminGroup.GET("/activities/:activity_id/co-organizers", activityH.ListCoOrganizers)
+		adminGroup.GET("/activities/:activity_id/form", activityH.GetRegistrationForm)
+		adminGroup.GET("/activities/:activity_id/eligibility", activityH.GetEligibility)
+		adminGroup.GET("/activities/:activity_id/lottery", activityH.GetLotteryDraw)
+
+		// A9: Admin面板统计信息
+		adminGroup.GET("/dashboard", dashboardH.GetDashboardData) // 仪表盘统计接口
+
+		// 当前登录管理员
+		adminGroup.POST("/logout", adminH.Logout)
+		adminGroup.GET("/me", adminH.GetMe)
+		adminGroup.PUT("/me/password", adminH.ChangeMyPassword)
+	}
+
+	// 按活动ID / 报名ID 校验组织者对活动的修改权限
+	activityScope := middleware.RequireActivityAccess(activitySvc, middleware.ActivityIDFromParam())
+	registrationScope := middleware.RequireActivityAccess(activitySvc, middleware.ActivityIDFromRegistration(registrationSvc))
+
+	// A7 & A8: 报名记录查询 (含手机号、报名表答案等个人信息)
+	// 组织者只能查看自己创建或协办的活动，签到人员只能在活动签到期间查看，跨活动查询仅限超级管理员
+	registrationRead := middleware.RequireRegistrationAccess(activitySvc, middleware.ActivityIDFromParam())
+	adminGroup.GET("/activities/:activity_id/registrations", registrationRead, registrationH.ListRegistrations)
+	adminGroup.GET("/activities/:activity_id/waitlist", registrationRead, registrationH.ListWaitlist)
+	adminGroup.GET("/registrations/:registration_id", middleware.RequireRegistrationAccess(activitySvc, middleware.ActivityIDFromRegistration(registrationSvc)), registrationH.GetRegistrationByID) // A8
+	adminGroup.GET("/registrations", middleware.RequireRegistrationAccess(activitySvc, middleware.ActivityIDFromQuery()), registrationH.ListRegistrations)
+
+	// 签到接口：超级管理员、组织者 (仅限自己的活动)、签到人员
+	signInGroup := adminGroup.Group("", middleware.RequireRoles(model.AdminRoleSuper, model.AdminRoleOrganizer, model.AdminRoleStaff))
+	{
+		// 签到动态码与大屏展示密钥
+		signInGroup.GET("/activities/:activity_id/signin-token", activityScope, activityH.GetSignInToken)
+		signInGroup.GET("/activities/:activity_id/signout-token", activityScope, activityH.GetSignOutToken)
+		signInGroup.POST("/activities/:activity_id/display-key", activityScope, activityH.RotateDisplayKey)
+		signInGroup.PUT("/registrations/:registration_id/sign_in", registrationScope, registrationH.AdminUpdateSignInStatus)
+		signInGroup.PUT("/activities/:activity_id/registrations/sign_in", activityScope, registrationH.BulkUpdateSignInStatus)
+		signInGroup.POST("/activities/:activity_id/sign-ins/import", activityScope, registrationH.ImportSignIns)
+	}
 
+	// 活动管理接口：超级管理员、组织者 (仅限自己创建或协办的活动)
+	organizerGroup := adminGroup.Group("", middleware.RequireRoles(model.AdminRoleSuper, model.AdminRoleOrganizer))
+	{
+		// A2 - A6: 活动管理 (CRUD + 发布)
+		organizerGroup.POST("/activities", activityH.CreateActivity)
+		organizerGroup.PUT("/activities/:activity_id", activityScope, activityH.UpdateActivity)
+		organizerGroup.DELETE("/activities/:activity_id", activityScope, activityH.DeleteActivity)
 		// 修正: 将 :id/publish 统一为 :activity_id/publish
-		adminGroup.POST("/activities/:activity_id/publish", activityH.PublishActivity)
+		organizerGroup.POST("/activities/:activity_id/publish", activityScope, activityH.PublishActivity)
+		organizerGroup.DELETE("/activities/:activity_id/publish-schedule", activityScope, activityH.CancelScheduledPublish)
+		organizerGroup.POST("/activities/:activity_id/close", activityScope, activityH.CloseRegistration)
+		organizerGroup.POST("/activities/:activity_id/reopen", activityScope, activityH.ReopenRegistration)
+		organizerGroup.POST("/activities/:activity_id/cancel", activityScope, activityH.CancelActivity)
+		organizerGroup.PUT("/activities/:activity_id/co-organizers", activityScope, activityH.SetCoOrganizers)
+		organizerGroup.PUT("/activities/:activity_id/form", activityScope, activityH.UpdateRegistrationForm)
+		organizerGroup.PUT("/activities/:activity_id/eligibility", activityScope, activityH.UpdateEligibility)
+		organizerGroup.PUT("/activities/:activity_id/allowlist", activityScope, activityH.UploadAllowlist)
+
+		// 报名记录与候补队列管理
+		organizerGroup.DELETE("/registrations/:registration_id", registrationScope, registrationH.AdminRemoveRegistration)
+		organizerGroup.PUT("/activities/:activity_id/waitlist", activityScope, registrationH.ReorderWaitlist)
+		organizerGroup.POST("/activities/:activity_id/registrations/approve", activityScope, registrationH.ApproveRegistrations)
+		organizerGroup.POST("/activities/:activity_id/registrations/reject", activityScope, registrationH.RejectRegistrations)
+		organizerGroup.POST("/activities/:activity_id/registrations/import", activityScope, registrationH.ImportRegistrations)
+		// 报名表导出 (含手机号等个人信息)
+		organizerGroup.GET("/activities/:activity_id/registrations/export", activityScope, registrationH.ExportRegistrations)
+
+		// 参与证明 (下载时签发)
+		organizerGroup.GET("/activities/:activity_id/certificates", activityScope, certificateH.DownloadCertificates)
+	}
+
+	// 管理员账号管理：仅超级管理员
+	superGroup := adminGroup.Group("/admins", middleware.RequireRoles(model.AdminRoleSuper))
+	{
+		superGroup.POST("", adminH.CreateAdmin)
+		superGroup.GET("", adminH.ListAdmins)
+		superGroup.PUT("/:admin_id/status", adminH.UpdateAdminStatus)
+		superGroup.PUT("/:admin_id/password", adminH.ResetAdminPassword)
+		superGroup.DELETE("/:admin_id", adminH.DeleteAdmin)
+	}
 
-		// 移除adminGroup中的签到Token端点
+	// 审计日志：仅超级管理员
+	auditGroup := adminGroup.Group("/audit-logs", middleware.RequireRoles(model.AdminRoleSuper))
+	{
+		auditGroup.GET("", auditH.ListAuditLogs)
+	}
 
-		// A7 & A8: 报名记录管理 (路径已规范)
-		adminGroup.GET("/activities/:activity_id/registrations", registrationH.ListRegistrations)
-		adminGroup.GET("/registrations/:registration_id", registrationH.GetRegistrationByID) // A8
-		adminGroup.GET("/registrations", registrationH.ListRegistrations)
-		adminGroup.PUT("/registrations/:registration_id/sign_in", registrationH.AdminUpdateSignInStatus)
+	// 学分：所有管理员可以查询，手动调整仅超级管理员
+	adminGroup.GET("/credits", creditH.GetStudentCredits)
+	creditGroup := adminGroup.Group("/credits", middleware.RequireRoles(model.AdminRoleSuper))
+	{
+		creditGroup.POST("/adjustments", creditH.AdjustCredits)
+	}
 
-		// A9: Admin面板统计信息
-		adminGroup.GET("/dashboard", dashboardH.GetDashboardData) // 仪表盘统计接口
+	// 通知队列：仅超级管理员
+	notificationGroup := adminGroup.Group("/notifications", middleware.RequireRoles(model.AdminRoleSuper))
+	{
+		notificationGroup.GET("", notificationH.ListNotifications)
+		notificationGroup.POST("/:notification_id/retry", notificationH.RetryNotification)
 	}
 
 	// 4. 处理 404 错误
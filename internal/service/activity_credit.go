@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/frozenf1sh/gostudent/internal/model"
+	"gorm.io/gorm"
+)
+
+// maxCreditValue 单个活动每人可发放的最大学分数
+const maxCreditValue = 1000
+
+var ErrInvalidCreditSettings = errors.New("credit value must be between 0 and 1000 with a valid credit type, and the minimum attendance must not be negative")
+
+// checkCreditSettings 校验活动的学分设置：发放学分时必须指定学分类型，学分数最多保留两位小数
+func checkCreditSettings(creditType model.CreditType, value float64, minMinutes int) (float64, error) {
+	if math.IsNaN(value) || value < 0 || value > maxCreditValue || minMinutes < 0 {
+		return 0, ErrInvalidCreditSettings
+	}
+	if (value > 0 || creditType != "") && !model.IsValidCreditType(creditType) {
+		return 0, ErrInvalidCreditSettings
+	}
+	return roundCredit(value), nil
+}
+
+// roundCredit 学分保留两位小数，与数据库 decimal(8,2) 一致
+func roundCredit(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// creditsDue 活动结束时发放学分
+func creditsDue(from, to model.ActivityStatus) bool {
+	return from != model.ActivityStatusFinished && to == model.ActivityStatusFinished
+}
+
+// creditedDuration 发放学分时的参与时长：已签退的使用签退时记录的时长；
+// 尚未签退的 (活动结束后签退仍开放 SignOutGrace) 按签到至活动结束计算，活动提前结束时计算到当前时间
+func creditedDuration(activity *model.Activity, reg *model.Registration, now time.Time) time.Duration {
+	switch {
+	case reg.SignedOutAt != nil:
+		return time.Duration(reg.AttendedSeconds) * time.Second
+	case reg.SignedInAt != nil:
+		return attendedDuration(activity, *reg.SignedInAt, now)
+	default:
+		return 0
+	}
+}
+
+// grantCreditsInTx 为满足考勤条件的报名者发放学分：报名已确认、已签到，且参与时长 (见 creditedDuration) 不少于活动要求的最低时长
+// 每条报名只发放一次 (流水的报名ID唯一)，未设置学分或已发放时直接返回。
+// 必须在已锁定活动行的事务中调用，只修改内存中的活动 (发放时间)，由调用方负责持久化
+func (s *activityServiceImpl) grantCreditsInTx(ctx context.Context, tx *gorm.DB, activity *model.Activity, now time.Time) error {
+	if activity.CreditValue <= 0 || activity.CreditsGrantedAt != nil {
+		return nil
+	}
+
+	// 活动结束时多数参与者尚未签退，不能按已记录的参与时长过滤
+	registrations, err := s.registrationRepo.WithTx(tx).ListAttended(ctx, activity.ID, 0)
+	if err != nil {
+		return err
+	}
+
+	minDuration := time.Duration(activity.CreditMinMinutes) * time.Minute
+	entries := make([]*model.CreditEntry, 0, len(registrations))
+	for _, reg := range registrations {
+		if creditedDuration(activity, reg, now) < minDuration {
+			continue
+		}
+		entries = append(entries, &model.CreditEntry{
+			Phone:           reg.ParticipantPhone,
+			StudentID:       reg.ParticipantStudentID,
+			ParticipantName: reg.ParticipantName,
+			CreditType:      activity.CreditType,
+			Amount:          activity.CreditValue,
+			Source:          model.CreditSourceGrant,
+			ActivityID:      activity.ID,
+			RegistrationID:  &reg.ID,
+		})
+	}
+	if err := s.creditRepo.WithTx(tx).CreateBatch(ctx, entries); err != nil {
+		return err
+	}
+
+	activity.CreditsGrantedAt = &now
+	return recordAudit(ctx, s.auditRepo.WithTx(tx), model.AuditActionActivityCreditsGrant, model.AuditTargetActivity, activity.ID, nil,
+		map[string]any{"credit_type": activity.CreditType, "credit_value": activity.CreditValue, "credit_min_minutes": activity.CreditMinMinutes, "recipients": len(entries)})
+}
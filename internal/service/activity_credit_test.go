@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/frozenf1sh/gostudent/internal/config"
+	"github.com/frozenf1sh/gostudent/internal/model"
+	"github.com/frozenf1sh/gostudent/internal/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 活动结束时签退仍开放，尚未签退的参与者按签到至活动结束计算参与时长
+func TestGrantCreditsCountsParticipantsNotSignedOut(t *testing.T) {
+	config.GlobalConfig = config.Config{}
+	db := newTestDB(t)
+	s := &activityServiceImpl{
+		db:               db,
+		registrationRepo: repository.NewRegistrationRepository(db),
+		auditRepo:        repository.NewAuditLogRepository(db),
+		creditRepo:       repository.NewCreditRepository(db),
+	}
+	ctx := context.Background()
+
+	end := time.Now().Truncate(time.Second)
+	start := end.Add(-2 * time.Hour)
+	activity := &model.Activity{
+		AdminID:              1,
+		Title:                "志愿服务",
+		Type:                 "志愿",
+		StartTime:            start,
+		EndTime:              end,
+		Location:             "A101",
+		Status:               model.ActivityStatusPublished,
+		RegistrationDeadline: start.Add(-time.Hour),
+		CreditType:           model.CreditTypeVolunteerHours,
+		CreditValue:          2,
+		CreditMinMinutes:     60,
+	}
+	if err := db.Create(activity).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	at := func(d time.Duration) *time.Time { v := start.Add(d); return &v }
+	registrations := map[string]*model.Registration{
+		"not signed out":       {SignedInAt: at(0)},
+		"signed out at end":    {SignedInAt: at(0), SignedOutAt: at(2 * time.Hour), AttendedSeconds: 7200},
+		"left early":           {SignedInAt: at(0), SignedOutAt: at(30 * time.Minute), AttendedSeconds: 1800},
+		"arrived late":         {SignedInAt: at(90 * time.Minute)},
+		"not signed in":        {},
+		"cancelled registrant": {SignedInAt: at(0), Status: model.RegistrationStatusCancelled},
+	}
+	i := 0
+	for _, reg := range registrations {
+		i++
+		reg.ActivityID = activity.ID
+		reg.ParticipantName = "参与者"
+		reg.ParticipantPhone = fmt.Sprintf("1380013800%d", i)
+		reg.ParticipantCollege = "计算机学院"
+		reg.IsSignedIn = reg.SignedInAt != nil
+		if reg.Status == "" {
+			reg.Status = model.RegistrationStatusConfirmed
+		}
+		if err := db.Omit(clause.Associations).Create(reg).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return s.grantCreditsInTx(ctx, tx, activity, end)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if activity.CreditsGrantedAt == nil {
+		t.Error("credits_granted_at was not set")
+	}
+
+	var entries []model.CreditEntry
+	if err := db.Where("activity_id = ?", activity.ID).Find(&entries).Error; err != nil {
+		t.Fatal(err)
+	}
+	var granted []uint
+	for _, entry := range entries {
+		granted = append(granted, *entry.RegistrationID)
+		if entry.Amount != 2 || entry.Source != model.CreditSourceGrant {
+			t.Errorf("credit entry = %+v", entry)
+		}
+	}
+	slices.Sort(granted)
+	want := []uint{registrations["not signed out"].ID, registrations["signed out at end"].ID}
+	slices.Sort(want)
+	if !slices.Equal(granted, want) {
+		t.Errorf("credits granted to registrations %v, want %v", granted, want)
+	}
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/frozenf1sh/gostudent/internal/model"
+)
+
+// ErrIllegalTransition 活动状态流转表中不存在该流转
+var ErrIllegalTransition = errors.New("illegal activity status transition")
+
+// ActivityTransitionError 活动状态流转被拒绝
+// Err 为具体原因：ErrIllegalTransition 表示流转表中不存在该流转，其余为守卫条件不满足
+type ActivityTransitionError struct {
+	From model.ActivityStatus
+	To   model.ActivityStatus
+	Err  error
+}
+
+func (e *ActivityTransitionError) Error() string {
+	return fmt.Sprintf("cannot transition activity from %s to %s: %v", e.From, e.To, e.Err)
+}
+
+func (e *ActivityTransitionError) Unwrap() error {
+	return e.Err
+}
+
+// transitionGuard 流转的守卫条件，返回 nil 表示允许
+type transitionGuard func(activity *model.Activity, now time.Time) error
+
+// activityTransitions 活动状态流转表：当前状态 -> 目标状态 -> 守卫条件
+// 未列出的流转一律拒绝；FINISHED 和 CANCELLED 为终态
+//
+//	DRAFT     -> PUBLISHED  发布 (开始时间和报名截止时间都未过)
+//	DRAFT     -> CANCELLED  取消
+//	PUBLISHED -> CLOSED     提前截止报名，或报名截止时间已过 (定时任务)
+//	PUBLISHED -> FINISHED   活动结束时间已过 (定时任务)
+//	PUBLISHED -> CANCELLED  取消
+//	CLOSED    -> PUBLISHED  重新开放报名 (报名截止时间未过)
+//	CLOSED    -> FINISHED   活动结束时间已过 (定时任务)
+//	CLOSED    -> CANCELLED  取消
+var activityTransitions = map[model.ActivityStatus]map[model.ActivityStatus]transitionGuard{
+	model.ActivityStatusDraft: {
+		model.ActivityStatusPublished: guardCanOpenRegistration,
+		model.ActivityStatusCancelled: nil,
+	},
+	model.ActivityStatusPublished: {
+		model.ActivityStatusClosed:    nil,
+		model.ActivityStatusFinished:  guardActivityEnded,
+		model.ActivityStatusCancelled: nil,
+	},
+	model.ActivityStatusClosed: {
+		model.ActivityStatusPublished: guardCanOpenRegistration,
+		model.ActivityStatusFinished:  guardActivityEnded,
+		model.ActivityStatusCancelled: nil,
+	},
+}
+
+// guardCanOpenRegistration 开放报名 (发布或重新开放) 要求活动未开始且报名截止时间未过
+func guardCanOpenRegistration(activity *model.Activity, now time.Time) error {
+	if activity.StartTime.Before(now) {
+		return ErrActivityIsRunning
+	}
+	if activity.RegistrationDeadline.Before(now) {
+		return ErrActivityRegistrationOver
+	}
+	return nil
+}
+
+// guardActivityEnded 只有活动结束时间已过才能标记为已结束
+func guardActivityEnded(activity *model.Activity, now time.Time) error {
+	if now.Before(activity.EndTime) {
+		return errors.New("activity has not ended yet")
+	}
+	return nil
+}
+
+// checkActivityTransition 校验活动能否流转到目标状态
+func checkActivityTransition(activity *model.Activity, to model.ActivityStatus, now time.Time) error {
+	guard, ok := activityTransitions[activity.Status][to]
+	if !ok {
+		return &ActivityTransitionError{From: activity.Status, To: to, Err: ErrIllegalTransition}
+	}
+	if guard != nil {
+		if err := guard(activity, now); err != nil {
+			return &ActivityTransitionError{From: activity.Status, To: to, Err: err}
+		}
+	}
+	return nil
+}
+
+// transitionActivity 校验并修改内存中的活动状态，由调用方负责持久化
+func transitionActivity(activity *model.Activity, to model.ActivityStatus, now time.Time) error {
+	if err := checkActivityTransition(activity, to, now); err != nil {
+		return err
+	}
+	activity.Status = to
+	return nil
+}
+
+// transitionAuditAction 状态流转对应的审计日志操作类型
+func transitionAuditAction(from, to model.ActivityStatus) string {
+	switch {
+	case to == model.ActivityStatusPublished && from == model.ActivityStatusDraft:
+		return model.AuditActionActivityPublish
+	case to == model.ActivityStatusPublished:
+		return model.AuditActionActivityReopen
+	case to == model.ActivityStatusClosed:
+		return model.AuditActionActivityClose
+	case to == model.ActivityStatusCancelled:
+		return model.AuditActionActivityCancel
+	default:
+		return model.AuditActionActivityFinish
+	}
+}
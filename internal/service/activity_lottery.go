@@ -0,0 +1,192 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/frozenf1sh/gostudent/internal/model"
+	"github.com/frozenf1sh/gostudent/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// lotteryAlgorithm 抽签算法版本，随开奖记录保存；修改算法时必须更换版本号
+// es-pcg-v1: 种子的前后 8 字节作为 PCG 的两个参数，候选者按报名ID升序依次取一个均匀随机数 u，
+// 以 ln(u)/weight 为键降序排列 (Efraimidis-Spirakis 加权无放回抽样)，键相同时报名ID小的在前
+const lotteryAlgorithm = "es-pcg-v1"
+
+// 学院权重的取值范围
+const (
+	maxLotteryWeight   = 100
+	maxLotteryColleges = 100
+)
+
+var ErrInvalidLotteryWeights = errors.New("lottery weights must be positive numbers no greater than 100")
+
+// normalizeLotteryWeights 校验学院权重并去除学院名称两端空白，没有权重时返回 nil
+func normalizeLotteryWeights(weights map[string]float64) (map[string]float64, error) {
+	if len(weights) == 0 {
+		return nil, nil
+	}
+	if len(weights) > maxLotteryColleges {
+		return nil, ErrInvalidLotteryWeights
+	}
+	normalized := make(map[string]float64, len(weights))
+	for college, weight := range weights {
+		college = strings.TrimSpace(college)
+		if college == "" || math.IsNaN(weight) || weight <= 0 || weight > maxLotteryWeight {
+			return nil, ErrInvalidLotteryWeights
+		}
+		normalized[college] = weight
+	}
+	return normalized, nil
+}
+
+// lotteryWeight 学院的抽签权重，未设置的学院为 1
+func lotteryWeight(weights map[string]float64, college string) float64 {
+	if weight, ok := weights[college]; ok {
+		return weight
+	}
+	return 1
+}
+
+// lotteryOrder 按种子计算抽签顺序，candidates 必须按报名ID升序排列
+// 相同的种子、候选名单和权重总是得到相同的顺序
+func lotteryOrder(seed []byte, candidates []*model.Registration, weights map[string]float64) []*model.Registration {
+	rng := rand.New(rand.NewPCG(binary.BigEndian.Uint64(seed[:8]), binary.BigEndian.Uint64(seed[8:16])))
+
+	keys := make(map[uint]float64, len(candidates))
+	for _, reg := range candidates {
+		u := rng.Float64()
+		for u == 0 {
+			u = rng.Float64() // ln(0) 无意义，概率极低
+		}
+		keys[reg.ID] = math.Log(u) / lotteryWeight(weights, reg.ParticipantCollege)
+	}
+
+	order := slices.Clone(candidates)
+	slices.SortStableFunc(order, func(a, b *model.Registration) int {
+		return cmp.Compare(keys[b.ID], keys[a.ID]) // 键大者在前
+	})
+	return order
+}
+
+// lotteryDue 判断状态流转是否结束了报名阶段 (截止报名或直接结束)，抽签活动在此时开奖
+func lotteryDue(from, to model.ActivityStatus) bool {
+	return from == model.ActivityStatusPublished && (to == model.ActivityStatusClosed || to == model.ActivityStatusFinished)
+}
+
+// drawLotteryInTx 抽签活动截止报名时开奖：按抽签顺序分配名额 (同时遵守学院名额)，未中签者按顺序进入候补队列，
+// 并保存种子和结果。未开启抽签或已开奖时直接返回。
+// 必须在已锁定活动行的事务中调用，只修改内存中的活动 (已报名人数和开奖时间)，由调用方负责持久化
+func (s *activityServiceImpl) drawLotteryInTx(ctx context.Context, tx *gorm.DB, activity *model.Activity, now time.Time) error {
+	if !activity.Lottery || activity.LotteryDrawnAt != nil {
+		return nil
+	}
+	registrationRepo := s.registrationRepo.WithTx(tx)
+
+	// 1. 候选名单与随机种子
+	candidates, err := registrationRepo.ListApplied(ctx, activity.ID)
+	if err != nil {
+		return err
+	}
+	seedHex, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return err
+	}
+	seed, _ := hex.DecodeString(seedHex)
+
+	draw := &model.LotteryDraw{
+		ActivityID:     activity.ID,
+		Seed:           seedHex,
+		Algorithm:      lotteryAlgorithm,
+		CollegeWeights: activity.LotteryWeights,
+		Candidates:     len(candidates),
+		DrawnAt:        now,
+	}
+	if activity.MaxParticipants > 0 {
+		draw.Seats = max(activity.MaxParticipants-activity.RegisteredCount, 0)
+	}
+
+	// 2. 按抽签顺序分配名额，学院名额在内存中计数
+	position, err := registrationRepo.NextWaitlistPosition(ctx, activity.ID)
+	if err != nil {
+		return err
+	}
+	collegeConfirmed := make(map[string]int64)
+	for rank, reg := range lotteryOrder(seed, candidates, activity.LotteryWeights) {
+		result := model.LotteryResult{
+			RegistrationID: reg.ID,
+			College:        reg.ParticipantCollege,
+			Weight:         lotteryWeight(activity.LotteryWeights, reg.ParticipantCollege),
+			Rank:           rank + 1,
+		}
+
+		won := hasFreeSeat(activity)
+		if won && hasCollegeQuotas(activity) {
+			if quota, ok := activity.Eligibility.CollegeQuotas[reg.ParticipantCollege]; ok {
+				confirmed, counted := collegeConfirmed[reg.ParticipantCollege]
+				if !counted {
+					if confirmed, err = registrationRepo.CountConfirmedByCollege(ctx, activity.ID, reg.ParticipantCollege); err != nil {
+						return err
+					}
+				}
+				won = confirmed < int64(quota)
+				if won {
+					confirmed++
+				}
+				collegeConfirmed[reg.ParticipantCollege] = confirmed
+			}
+		}
+
+		if won {
+			reg.Status = model.RegistrationStatusConfirmed
+			activity.RegisteredCount += 1
+			draw.Winners++
+		} else {
+			reg.Status = model.RegistrationStatusWaitlisted
+			reg.WaitlistPosition = position
+			result.WaitlistPosition = position
+			position++
+		}
+		if err := registrationRepo.Update(ctx, reg); err != nil {
+			return err
+		}
+		result.Won = won
+		draw.Results = append(draw.Results, result)
+	}
+
+	// 3. 保存开奖记录
+	activity.LotteryDrawnAt = &now
+	if err := s.lotteryRepo.WithTx(tx).Create(ctx, draw); err != nil {
+		return err
+	}
+	return recordAudit(ctx, s.auditRepo.WithTx(tx), model.AuditActionActivityLotteryDraw, model.AuditTargetActivity, activity.ID, nil,
+		map[string]any{"seed": draw.Seed, "algorithm": draw.Algorithm, "candidates": draw.Candidates, "winners": draw.Winners})
+}
+
+// GetLotteryDraw 查询活动的开奖记录
+func (s *activityServiceImpl) GetLotteryDraw(ctx context.Context, id uint) (*model.LotteryDraw, error) {
+	activity, err := s.activityRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, ErrActivityNotFound
+	}
+	if !activity.Lottery {
+		return nil, ErrNotLotteryActivity
+	}
+	draw, err := s.lotteryRepo.FindByActivityID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if draw == nil {
+		return nil, ErrLotteryNotDrawn
+	}
+	return draw, nil
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/frozenf1sh/gostudent/internal/model"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrPublishScheduleLocked = errors.New("scheduled publishing can only be set while the activity is a draft")
+	ErrInvalidPublishAt      = errors.New("publish time must be in the future and before the registration deadline")
+	ErrPublishNotScheduled   = errors.New("activity has no scheduled publish")
+)
+
+// errPublishScheduleChanged 行锁内发现定时发布已被取消或修改，本轮跳过
+var errPublishScheduleChanged = errors.New("publish schedule changed")
+
+// checkPublishAt 校验定时发布时间：必须晚于当前时间，且早于报名截止时间 (否则到时无法发布)
+func checkPublishAt(publishAt, deadline, now time.Time) error {
+	if !publishAt.After(now) || !publishAt.Before(deadline) {
+		return ErrInvalidPublishAt
+	}
+	return nil
+}
+
+// preparePublish 发布前的校验：只有草稿可以发布，发布后清除定时发布设置
+func preparePublish(activity *model.Activity) error {
+	if activity.Status != model.ActivityStatusDraft {
+		return &ActivityTransitionError{From: activity.Status, To: model.ActivityStatusPublished, Err: ErrActivityAlreadyPublished}
+	}
+	activity.PublishAt = nil
+	activity.PublishError = ""
+	return nil
+}
+
+// publishDueActivities 发布已到定时发布时间的草稿活动
+// 与手动发布执行相同的校验，失败时把原因记录在活动上，管理员修改活动后重新设置发布时间即可再次排期
+func (s *activityServiceImpl) publishDueActivities(ctx context.Context, now time.Time) {
+	activities, err := s.activityRepo.ListDueForPublish(ctx, now)
+	if err != nil {
+		slog.Error("定时发布活动失败", "err", err)
+		return
+	}
+
+	var published, failed int
+	for _, due := range activities {
+		err := s.changeStatus(ctx, due.ID, model.ActivityStatusPublished, func(activity *model.Activity) error {
+			// 在行锁内重新检查，定时发布可能已被管理员取消或修改
+			if activity.Status == model.ActivityStatusDraft && (activity.PublishAt == nil || activity.PublishAt.After(now) || activity.PublishError != "") {
+				return errPublishScheduleChanged
+			}
+			return preparePublish(activity)
+		})
+		var transitionErr *ActivityTransitionError
+		switch {
+		case err == nil:
+			published++
+		case errors.Is(err, errPublishScheduleChanged), errors.Is(err, ErrActivityAlreadyPublished), errors.Is(err, ErrActivityNotFound):
+			// 已被取消、改期、手动发布或删除
+		case errors.As(err, &transitionErr):
+			// 发布校验未通过，需要管理员处理
+			failed++
+			slog.Warn("定时发布活动失败", "activity_id", due.ID, "err", err)
+			if err := s.recordPublishFailure(ctx, due.ID, transitionErr); err != nil {
+				slog.Error("记录定时发布失败原因失败", "activity_id", due.ID, "err", err)
+			}
+		default:
+			// 数据库等临时错误，下一轮重试
+			slog.Error("定时发布活动失败，等待重试", "activity_id", due.ID, "err", err)
+		}
+	}
+	if len(activities) > 0 {
+		slog.Info("定时发布活动", "time", now, "published_count", published, "failed_count", failed)
+	}
+}
+
+// recordPublishFailure 把定时发布失败的原因保存到活动上，定时任务不再重试该活动
+func (s *activityServiceImpl) recordPublishFailure(ctx context.Context, id uint, cause *ActivityTransitionError) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		activity, err := s.activityRepo.WithTx(tx).FindByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if activity.Status != model.ActivityStatusDraft || activity.PublishAt == nil {
+			return nil
+		}
+		activity.PublishError = publishFailureReason(cause)
+		return s.activityRepo.WithTx(tx).Update(ctx, activity)
+	})
+}
+
+// publishFailureReason 定时发布失败原因的说明，供管理员查看
+func publishFailureReason(err *ActivityTransitionError) string {
+	switch {
+	case errors.Is(err, ErrActivityIsRunning):
+		return "活动已开始，无法发布"
+	case errors.Is(err, ErrActivityRegistrationOver):
+		return "报名截止时间已过，无法发布"
+	default:
+		return err.Error()
+	}
+}
+
+// ListScheduledPublishes 按发布时间列出设置了定时发布的草稿活动，发布失败的活动带有失败原因
+func (s *activityServiceImpl) ListScheduledPublishes(ctx context.Context) ([]*model.Activity, error) {
+	return s.activityRepo.ListScheduledPublishes(ctx)
+}
+
+// CancelScheduledPublish 取消草稿活动的定时发布 (同时清除失败原因)
+func (s *activityServiceImpl) CancelScheduledPublish(ctx context.Context, id uint) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		activity, err := s.activityRepo.WithTx(tx).FindByIDForUpdate(ctx, id)
+		if err != nil {
+			return ErrActivityNotFound
+		}
+		if activity.Status != model.ActivityStatusDraft || activity.PublishAt == nil {
+			return ErrPublishNotScheduled
+		}
+		before := map[string]any{"publish_at": activity.PublishAt, "publish_error": activity.PublishError}
+
+		activity.PublishAt = nil
+		activity.PublishError = ""
+		if err := s.activityRepo.WithTx(tx).Update(ctx, activity); err != nil {
+			return err
+		}
+		return recordAudit(ctx, s.auditRepo.WithTx(tx), model.AuditActionPublishUnschedule, model.AuditTargetActivity, id, before, nil)
+	})
+}
@@ -2,13 +2,18 @@ package service
 
 import (
 	"context"
+	"encoding/hex"
 	"errors"
+	"net/url"
+	"strconv"
 	"time"
 
 	"log/slog"
 
+	"github.com/frozenf1sh/gostudent/internal/config"
 	"github.com/frozenf1sh/gostudent/internal/model"
 	"github.com/frozenf1sh/gostudent/internal/repository"
+	"github.com/frozenf1sh/gostudent/pkg/utils"
 	"gorm.io/gorm"
 )
 
@@ -17,6 +22,21 @@ var (
 	ErrActivityAlreadyPublished = errors.New("activity is already published or ended")
 	ErrActivityRegistrationOver = errors.New("registration deadline has passed")
 	ErrActivityIsRunning        = errors.New("activity is already running or finished")
+	ErrSignInNotAvailable       = errors.New("sign-in is only available while the activity is running")
+	ErrInvalidDisplayKey        = errors.New("invalid display key")
+	ErrActivityForbidden        = errors.New("no permission to modify this activity")
+	ErrRegistrationsForbidden   = errors.New("no permission to view registrations of this activity")
+	ErrInvalidCoOrganizer       = errors.New("co-organizers must be existing organizer accounts")
+	ErrCancelReasonRequired     = errors.New("cancelling an activity requires a reason")
+	ErrFastRegistrationLocked   = errors.New("fast registration can only be changed while the activity is a draft")
+	ErrFormSchemaLocked         = errors.New("registration form can only be edited while the activity is a draft")
+	ErrApprovalSettingLocked    = errors.New("registration approval can only be changed while the activity is a draft")
+	ErrEligibilityLocked        = errors.New("eligibility rules cannot be changed after the activity has finished or been cancelled")
+	ErrLotterySettingLocked     = errors.New("lottery settings can only be changed while the activity is a draft")
+	ErrLotteryWithApproval      = errors.New("lottery mode cannot be combined with registration approval")
+	ErrNotLotteryActivity       = errors.New("activity is not in lottery mode")
+	ErrLotteryNotDrawn          = errors.New("lottery has not been drawn yet")
+	ErrInvalidRegistrationOpen  = errors.New("registration must open before the registration deadline")
 )
 
 // ActivityService 定义活动业务逻辑接口
@@ -24,15 +44,57 @@ type ActivityService interface {
 	CreateActivity(ctx context.Context, adminID uint, req *model.CreateActivityRequest) (*model.Activity, error)
 	GetActivityByID(ctx context.Context, id uint) (*model.Activity, error)
 	ListActivities(ctx context.Context, params *model.ListActivitiesParams) ([]*model.Activity, int64, error)
+	// 公开接口：只返回已发布、已截止报名和已结束的活动
+	GetPublicActivityByID(ctx context.Context, id uint) (*model.Activity, error)
+	ListPublicActivities(ctx context.Context, params *model.ListActivitiesParams) ([]*model.Activity, int64, error)
 	UpdateActivity(ctx context.Context, id uint, req *model.UpdateActivityRequest) error
 	DeleteActivity(ctx context.Context, id uint) error
 	PublishActivity(ctx context.Context, id uint) error // 发布活动 (核心功能之一)
+	// 状态流转：提前截止报名、重新开放报名、取消活动 (规则见 activity_lifecycle.go)
+	CloseRegistration(ctx context.Context, id uint) error
+	ReopenRegistration(ctx context.Context, id uint) error
+	CancelActivity(ctx context.Context, id uint, reason string) error
 	StartActivityStatusUpdater(ctx context.Context, interval time.Duration)
+
+	// 签到动态码：获取当前动态码、校验大屏展示密钥、重新生成展示密钥
+	GetSignInCode(ctx context.Context, id uint) (*model.SignInCodeResponse, error)
+	GetSignOutCode(ctx context.Context, id uint) (*model.SignInCodeResponse, error)
+	VerifyDisplayKey(ctx context.Context, id uint, displayKey string) error
+	RotateDisplayKey(ctx context.Context, id uint) (string, error)
+
+	// 权限：校验管理员能否修改活动、能否查看活动的报名记录；协办组织者的设置与查询
+	CheckActivityAccess(ctx context.Context, adminID uint, role model.AdminRole, activityID uint) error
+	CheckRegistrationAccess(ctx context.Context, adminID uint, role model.AdminRole, activityID uint) error
+	SetCoOrganizers(ctx context.Context, activityID uint, adminIDs []uint) ([]*model.Admin, error)
+	ListCoOrganizers(ctx context.Context, activityID uint) ([]*model.Admin, error)
+
+	// 报名表：整体替换自定义字段 (仅草稿状态)
+	UpdateFormSchema(ctx context.Context, id uint, schema model.FormSchema) (model.FormSchema, error)
+
+	// 报名资格：规则的查询与设置、整体替换报名名单
+	GetEligibility(ctx context.Context, id uint) (*model.EligibilityResponse, error)
+	UpdateEligibility(ctx context.Context, id uint, rules *model.EligibilityRules) error
+	ReplaceAllowlist(ctx context.Context, id uint, entries []*model.AllowlistEntry) error
+
+	// 定时发布：列出已排期的草稿、取消排期 (发布由定时任务执行，见 activity_publish_schedule.go)
+	ListScheduledPublishes(ctx context.Context) ([]*model.Activity, error)
+	CancelScheduledPublish(ctx context.Context, id uint) error
+
+	// 抽签：查询开奖记录 (开奖由截止报名时的状态流转触发，见 activity_lottery.go)
+	GetLotteryDraw(ctx context.Context, id uint) (*model.LotteryDraw, error)
 }
 
 type activityServiceImpl struct {
-	db           *gorm.DB // 用于事务
-	activityRepo repository.ActivityRepository
+	db               *gorm.DB // 用于事务
+	activityRepo     repository.ActivityRepository
+	registrationRepo repository.RegistrationRepository // 扩容时递补候补队列
+	adminRepo        repository.AdminRepository        // 校验协办组织者
+	auditRepo        repository.AuditLogRepository     // 记录修改操作
+	allowlistRepo    repository.AllowlistRepository    // 报名名单
+	lotteryRepo      repository.LotteryRepository      // 抽签开奖记录
+	creditRepo       repository.CreditRepository       // 活动结束时发放学分
+	notificationRepo repository.NotificationRepository // 候补递补、活动变更和取消时通知报名者
+	fastRegistration FastRegistrationService           // 状态或名额变化后同步快速报名的 Redis 名额
 }
 
 // StartActivityStatusUpdater 启动活动状态自动更新定时任务（建议在 main.go 初始化时调用）
@@ -47,22 +109,71 @@ func (s *activityServiceImpl) StartActivityStatusUpdater(ctx context.Context, in
 				slog.Info("活动状态自动更新任务已停止")
 				return
 			case t := <-ticker.C:
-				closed, finished, err := s.activityRepo.UpdateStatusByDeadline(ctx)
-				if err != nil {
-					slog.Error("活动状态自动更新失败", "err", err)
-				} else {
-					slog.Debug("活动状态自动更新", "time", t, "closed_count", closed, "finished_count", finished)
-				}
+				s.publishDueActivities(ctx, t)
+				s.updateDueActivityStatuses(ctx, t)
 			}
 		}
 	}()
 }
 
+// updateDueActivityStatuses 按状态流转规则逐个处理到期的活动
+// 报名截止时间已过的活动截止报名，结束时间已过的活动标记为已结束
+func (s *activityServiceImpl) updateDueActivityStatuses(ctx context.Context, now time.Time) {
+	activities, err := s.activityRepo.ListDueForStatusUpdate(ctx, now)
+	if err != nil {
+		slog.Error("活动状态自动更新失败", "err", err)
+		return
+	}
+
+	var closed, finished int
+	for _, due := range activities {
+		err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			// 在行锁内重新读取，状态可能已被管理员修改
+			activity, err := s.activityRepo.WithTx(tx).FindByIDForUpdate(ctx, due.ID)
+			if err != nil {
+				return err
+			}
+			if activity.Status != model.ActivityStatusPublished && activity.Status != model.ActivityStatusClosed {
+				return nil
+			}
+			to := model.ActivityStatusClosed
+			if !now.Before(activity.EndTime) {
+				to = model.ActivityStatusFinished
+			} else if activity.Status != model.ActivityStatusPublished || !activity.RegistrationDeadline.Before(now) {
+				return nil // 已不需要流转
+			}
+			if err := s.changeStatusInTx(ctx, tx, activity, to, now, nil); err != nil {
+				return err
+			}
+			if to == model.ActivityStatusFinished {
+				finished++
+			} else {
+				closed++
+			}
+			return nil
+		})
+		if err != nil {
+			slog.Error("活动状态自动更新失败", "activity_id", due.ID, "err", err)
+			continue
+		}
+		s.syncFastRegistration(ctx, due)
+	}
+	slog.Debug("活动状态自动更新", "time", now, "closed_count", closed, "finished_count", finished)
+}
+
 // NewActivityService 创建 ActivityService 实例
-func NewActivityService(db *gorm.DB, repo repository.ActivityRepository) ActivityService {
+func NewActivityService(db *gorm.DB, repo repository.ActivityRepository, rRepo repository.RegistrationRepository, adminRepo repository.AdminRepository, auditRepo repository.AuditLogRepository, allowlistRepo repository.AllowlistRepository, lotteryRepo repository.LotteryRepository, creditRepo repository.CreditRepository, notificationRepo repository.NotificationRepository, fastRegistration FastRegistrationService) ActivityService {
 	return &activityServiceImpl{
-		db:           db,
-		activityRepo: repo,
+		db:               db,
+		activityRepo:     repo,
+		registrationRepo: rRepo,
+		adminRepo:        adminRepo,
+		auditRepo:        auditRepo,
+		allowlistRepo:    allowlistRepo,
+		lotteryRepo:      lotteryRepo,
+		creditRepo:       creditRepo,
+		notificationRepo: notificationRepo,
+		fastRegistration: fastRegistration,
 	}
 }
 
@@ -74,8 +185,38 @@ func (s *activityServiceImpl) CreateActivity(ctx context.Context, adminID uint,
 	} else if req.EndTime.Before(req.StartTime) {
 		return nil, errors.New("结束时间不能晚于开始时间")
 	}
+	if err := checkRegistrationOpensAt(req.RegistrationOpensAt, req.RegistrationDeadline); err != nil {
+		return nil, err
+	}
+	if req.PublishAt != nil {
+		if err := checkPublishAt(*req.PublishAt, req.RegistrationDeadline, time.Now()); err != nil {
+			return nil, err
+		}
+	}
 
-	// 2. DTO -> Model 转换
+	if req.Lottery && req.RequiresApproval {
+		return nil, ErrLotteryWithApproval
+	}
+	lotteryWeights, err := normalizeLotteryWeights(req.LotteryWeights)
+	if err != nil {
+		return nil, err
+	}
+	creditValue, err := checkCreditSettings(req.CreditType, req.CreditValue, req.CreditMinMinutes)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. 生成签到、签退动态码密钥
+	secret, err := utils.GenerateRandomToken(20)
+	if err != nil {
+		return nil, err
+	}
+	signOutSecret, err := utils.GenerateRandomToken(20)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. DTO -> Model 转换
 	activity := &model.Activity{
 		AdminID:              adminID,
 		Title:                req.Title,
@@ -84,49 +225,143 @@ func (s *activityServiceImpl) CreateActivity(ctx context.Context, adminID uint,
 		StartTime:            req.StartTime,
 		EndTime:              req.EndTime,
 		Location:             req.Location,
+		RegistrationOpensAt:  req.RegistrationOpensAt,
 		RegistrationDeadline: req.RegistrationDeadline,
+		PublishAt:            req.PublishAt,
 		MaxParticipants:      req.MaxParticipants,
 		LiveURL:              req.LiveURL,
 		AttachmentURL:        req.AttachmentURL,
+		FastRegistration:     req.FastRegistration,
+		RequiresApproval:     req.RequiresApproval,
+		Lottery:              req.Lottery,
+		LotteryWeights:       lotteryWeights,
+		CreditType:           req.CreditType,
+		CreditValue:          creditValue,
+		CreditMinMinutes:     req.CreditMinMinutes,
+		SignInSecret:         secret,
+		SignOutSecret:        signOutSecret,
 		// 状态默认为 DRAFT
 		Status: model.ActivityStatusDraft,
 	}
 
-	// 3. 调用 Repository 存储
-	if err := s.activityRepo.Create(ctx, activity); err != nil {
+	// 4. 调用 Repository 存储，并在同一事务中记录审计日志
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := s.activityRepo.WithTx(tx).Create(ctx, activity); err != nil {
+			return err
+		}
+		return recordAudit(ctx, s.auditRepo.WithTx(tx), model.AuditActionActivityCreate, model.AuditTargetActivity, activity.ID, nil, auditSnapshot(activity))
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return activity, nil
 }
 
+// checkRegistrationOpensAt 校验报名开放时间早于报名截止时间
+// 报名截止时间已校验不晚于活动开始时间，因此开放时间同样早于活动开始时间
+func checkRegistrationOpensAt(opensAt *time.Time, deadline time.Time) error {
+	if opensAt != nil && !opensAt.Before(deadline) {
+		return ErrInvalidRegistrationOpen
+	}
+	return nil
+}
+
 // PublishActivity 发布活动，将状态从 DRAFT 变为 PUBLISHED
+// 已截止报名的活动重新开放请使用 ReopenRegistration
 func (s *activityServiceImpl) PublishActivity(ctx context.Context, id uint) error {
-	// 1. 获取活动
-	activity, err := s.activityRepo.FindByID(ctx, id)
+	return s.changeStatus(ctx, id, model.ActivityStatusPublished, preparePublish)
+}
+
+// CloseRegistration 提前截止报名，PUBLISHED -> CLOSED
+func (s *activityServiceImpl) CloseRegistration(ctx context.Context, id uint) error {
+	return s.changeStatus(ctx, id, model.ActivityStatusClosed, nil)
+}
+
+// ReopenRegistration 重新开放报名，CLOSED -> PUBLISHED，要求报名截止时间未过
+func (s *activityServiceImpl) ReopenRegistration(ctx context.Context, id uint) error {
+	return s.changeStatus(ctx, id, model.ActivityStatusPublished, func(activity *model.Activity) error {
+		if activity.Status != model.ActivityStatusClosed {
+			return &ActivityTransitionError{From: activity.Status, To: model.ActivityStatusPublished, Err: ErrIllegalTransition}
+		}
+		return nil
+	})
+}
+
+// CancelActivity 取消活动并记录原因，已结束或已取消的活动不能取消
+func (s *activityServiceImpl) CancelActivity(ctx context.Context, id uint, reason string) error {
+	return s.changeStatus(ctx, id, model.ActivityStatusCancelled, func(activity *model.Activity) error {
+		now := time.Now()
+		activity.CancelReason = reason
+		activity.CancelledAt = &now
+		return nil
+	})
+}
+
+// changeStatus 在活动行锁内执行状态流转
+// prepare 在流转校验前调用，可做额外校验或设置随状态一起保存的字段
+// 提交后同步快速报名的 Redis 名额 (开放报名时预加载，其余状态清除)
+func (s *activityServiceImpl) changeStatus(ctx context.Context, id uint, to model.ActivityStatus, prepare func(activity *model.Activity) error) error {
+	var activity *model.Activity
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		activity, err = s.activityRepo.WithTx(tx).FindByIDForUpdate(ctx, id)
+		if err != nil {
+			return ErrActivityNotFound
+		}
+		return s.changeStatusInTx(ctx, tx, activity, to, time.Now(), prepare)
+	})
 	if err != nil {
-		return ErrActivityNotFound
+		return err
 	}
+	s.syncFastRegistration(ctx, activity)
+	return nil
+}
 
-	// 2. 状态校验
-	if activity.Status != model.ActivityStatusDraft {
-		return ErrActivityAlreadyPublished
+// syncFastRegistration 活动提交修改后校对快速报名的 Redis 名额，失败只记录日志，由校对任务重试
+func (s *activityServiceImpl) syncFastRegistration(ctx context.Context, activity *model.Activity) {
+	if !activity.FastRegistration {
+		return
 	}
+	if err := s.fastRegistration.Sync(ctx, activity.ID); err != nil {
+		slog.Warn("快速报名名额同步失败，等待校对任务重试", "activity_id", activity.ID, "err", err)
+	}
+}
 
-	// 3. 时间校验：活动开始时间不能已过
-	if activity.StartTime.Before(time.Now()) {
-		return ErrActivityIsRunning
+// changeStatusInTx 按流转表校验并保存活动状态，同时记录审计日志
+// 必须在已通过 FindByIDForUpdate 锁定活动行的事务中调用
+func (s *activityServiceImpl) changeStatusInTx(ctx context.Context, tx *gorm.DB, activity *model.Activity, to model.ActivityStatus, now time.Time, prepare func(activity *model.Activity) error) error {
+	before := auditSnapshot(activity)
+	from := activity.Status
+
+	if prepare != nil {
+		if err := prepare(activity); err != nil {
+			return err
+		}
 	}
-	// 4. 报名截止时间不能已过
-	if activity.RegistrationDeadline.Before(time.Now()) {
-		return ErrActivityRegistrationOver
+	if err := transitionActivity(activity, to, now); err != nil {
+		return err
+	}
+	if lotteryDue(from, to) {
+		if err := s.drawLotteryInTx(ctx, tx, activity, now); err != nil {
+			return err
+		}
+	}
+	if creditsDue(from, to) {
+		if err := s.grantCreditsInTx(ctx, tx, activity, now); err != nil {
+			return err
+		}
+	}
+	if to == model.ActivityStatusCancelled {
+		if err := notifyRegistrants(ctx, s.registrationRepo.WithTx(tx), s.notificationRepo.WithTx(tx), model.NotificationEventActivityCancelled, activity); err != nil {
+			return err
+		}
 	}
 
-	// 5. 更新状态
-	activity.Status = model.ActivityStatusPublished
-
-	// 6. 调用 Repository 更新
-	return s.activityRepo.Update(ctx, activity)
+	if err := s.activityRepo.WithTx(tx).Update(ctx, activity); err != nil {
+		return err
+	}
+	return recordAudit(ctx, s.auditRepo.WithTx(tx), transitionAuditAction(from, to), model.AuditTargetActivity, activity.ID, before, auditSnapshot(activity))
 }
 
 // GetActivityByID 获取单个活动详情
@@ -143,17 +378,61 @@ func (s *activityServiceImpl) ListActivities(ctx context.Context, params *model.
 	return s.activityRepo.List(ctx, params)
 }
 
+// GetPublicActivityByID 获取公开可见的活动详情，草稿和已取消的活动视为不存在
+func (s *activityServiceImpl) GetPublicActivityByID(ctx context.Context, id uint) (*model.Activity, error) {
+	activity, err := s.activityRepo.FindByID(ctx, id)
+	if err != nil || !model.IsPublicActivityStatus(activity.Status) {
+		return nil, ErrActivityNotFound
+	}
+	return activity, nil
+}
+
+// ListPublicActivities 列出公开可见的活动
+func (s *activityServiceImpl) ListPublicActivities(ctx context.Context, params *model.ListActivitiesParams) ([]*model.Activity, int64, error) {
+	params.Statuses = model.PublicActivityStatuses
+	return s.activityRepo.List(ctx, params)
+}
+
 // UpdateActivity 完整更新活动逻辑
+// 在活动行锁内执行，人数上限提高时按顺序递补候补队列
 func (s *activityServiceImpl) UpdateActivity(ctx context.Context, id uint, req *model.UpdateActivityRequest) error {
-	// 1. 查找活动
-	activity, err := s.activityRepo.FindByID(ctx, id)
+	var (
+		activity  *model.Activity
+		seatDelta int
+	)
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		activity, seatDelta, err = s.updateActivityInTx(ctx, tx, id, req)
+		return err
+	})
 	if err != nil {
-		return ErrActivityNotFound
+		return err
+	}
+
+	// 快速报名：先按增量调整剩余名额 (校对在有待落库记录时会跳过)，再尝试校对
+	s.fastRegistration.AdjustSeats(ctx, activity, seatDelta)
+	s.syncFastRegistration(ctx, activity)
+	return nil
+}
+
+// updateActivityInTx UpdateActivity 的事务内实现
+// 返回修改后的活动，以及人数上限变化和候补递补导致的空余名额变化量 (上限改为或改自不限制时为 0)
+func (s *activityServiceImpl) updateActivityInTx(ctx context.Context, tx *gorm.DB, id uint, req *model.UpdateActivityRequest) (*model.Activity, int, error) {
+	// 1. 查找活动并加锁
+	activity, err := s.activityRepo.WithTx(tx).FindByIDForUpdate(ctx, id)
+	if err != nil {
+		return nil, 0, ErrActivityNotFound
 	}
+	before := auditSnapshot(activity)
+	oldMax := activity.MaxParticipants
+	oldStart, oldEnd, oldLocation := activity.StartTime, activity.EndTime, activity.Location
 
 	// 2. 检查活动是否在允许修改的状态
 	if activity.Status == model.ActivityStatusFinished {
-		return errors.New("无法修改已结束的活动")
+		return nil, 0, errors.New("无法修改已结束的活动")
+	}
+	if activity.Status == model.ActivityStatusCancelled {
+		return nil, 0, errors.New("无法修改已取消的活动")
 	}
 
 	// 3. DTO -> Model 赋值 (只更新非空字段)
@@ -183,6 +462,55 @@ func (s *activityServiceImpl) UpdateActivity(ctx context.Context, id uint, req *
 		activity.MaxParticipants = *req.MaxParticipants
 	}
 
+	// 快速报名开关：发布后名额可能已加载到 Redis，只允许在草稿状态修改
+	if req.FastRegistration != nil && *req.FastRegistration != activity.FastRegistration {
+		if activity.Status != model.ActivityStatusDraft {
+			return nil, 0, ErrFastRegistrationLocked
+		}
+		activity.FastRegistration = *req.FastRegistration
+	}
+
+	// 报名审核开关：发布后已有报名按原方式处理，只允许在草稿状态修改
+	if req.RequiresApproval != nil && *req.RequiresApproval != activity.RequiresApproval {
+		if activity.Status != model.ActivityStatusDraft {
+			return nil, 0, ErrApprovalSettingLocked
+		}
+		activity.RequiresApproval = *req.RequiresApproval
+	}
+
+	// 抽签设置：发布后报名者已按抽签方式提交，只允许在草稿状态修改
+	if req.Lottery != nil && *req.Lottery != activity.Lottery {
+		if activity.Status != model.ActivityStatusDraft {
+			return nil, 0, ErrLotterySettingLocked
+		}
+		activity.Lottery = *req.Lottery
+	}
+	if req.LotteryWeights != nil {
+		if activity.Status != model.ActivityStatusDraft {
+			return nil, 0, ErrLotterySettingLocked
+		}
+		if activity.LotteryWeights, err = normalizeLotteryWeights(req.LotteryWeights); err != nil {
+			return nil, 0, err
+		}
+	}
+	if activity.Lottery && activity.RequiresApproval {
+		return nil, 0, ErrLotteryWithApproval
+	}
+
+	// 学分设置：活动结束时按当时的设置发放
+	if req.CreditType != nil {
+		activity.CreditType = *req.CreditType
+	}
+	if req.CreditValue != nil {
+		activity.CreditValue = *req.CreditValue
+	}
+	if req.CreditMinMinutes != nil {
+		activity.CreditMinMinutes = *req.CreditMinMinutes
+	}
+	if activity.CreditValue, err = checkCreditSettings(activity.CreditType, activity.CreditValue, activity.CreditMinMinutes); err != nil {
+		return nil, 0, err
+	}
+
 	// C. 时间类型更新 (使用临时变量来执行时间校验)
 	newStartTime := activity.StartTime
 	if req.StartTime != nil {
@@ -197,40 +525,517 @@ func (s *activityServiceImpl) UpdateActivity(ctx context.Context, id uint, req *
 	if req.RegistrationDeadline != nil {
 		newDeadline = *req.RegistrationDeadline // 修复：RegistrationDeadline
 	}
+	newOpensAt := activity.RegistrationOpensAt
+	if req.RegistrationOpensAt != nil {
+		newOpensAt = req.RegistrationOpensAt
+	}
 
 	// D. 业务逻辑校验：报名截止时间不能晚于活动开始时间
 	if newDeadline.After(newStartTime) {
-		return errors.New("报名截止时间不能晚于活动开始时间")
+		return nil, 0, errors.New("报名截止时间不能晚于活动开始时间")
 	} else if newEndTime.Before(newStartTime) {
-		return errors.New("结束时间不能晚于开始时间")
+		return nil, 0, errors.New("结束时间不能晚于开始时间")
+	}
+	if err := checkRegistrationOpensAt(newOpensAt, newDeadline); err != nil {
+		return nil, 0, err
 	}
 
 	// 如果校验通过，才赋值回 activity model
 	activity.StartTime = newStartTime
 	activity.EndTime = newEndTime
 	activity.RegistrationDeadline = newDeadline
+	activity.RegistrationOpensAt = newOpensAt
 
-	// E. 状态更新
-	if req.Status != nil {
-		// 修复：Status 字段赋值和类型转换
+	// 定时发布：只能在草稿状态设置，重新设置后清除上次的失败原因
+	if req.PublishAt != nil {
+		if activity.Status != model.ActivityStatusDraft {
+			return nil, 0, ErrPublishScheduleLocked
+		}
+		if err := checkPublishAt(*req.PublishAt, newDeadline, time.Now()); err != nil {
+			return nil, 0, err
+		}
+		activity.PublishAt = req.PublishAt
+		activity.PublishError = ""
+	}
+
+	// E. 状态更新：按状态流转表校验 (使用上面更新后的时间)
+	if req.Status != nil && model.ActivityStatus(*req.Status) != activity.Status {
 		newStatus := model.ActivityStatus(*req.Status)
+		if newStatus == model.ActivityStatusCancelled {
+			// 取消需要填写原因，只能通过取消接口
+			return nil, 0, &ActivityTransitionError{From: activity.Status, To: newStatus, Err: ErrCancelReasonRequired}
+		}
+		from, now := activity.Status, time.Now()
+		if err := transitionActivity(activity, newStatus, now); err != nil {
+			return nil, 0, err
+		}
+		if from == model.ActivityStatusDraft {
+			activity.PublishAt = nil // 草稿发布或取消后不再定时发布
+			activity.PublishError = ""
+		}
+		if lotteryDue(from, newStatus) {
+			if err := s.drawLotteryInTx(ctx, tx, activity, now); err != nil {
+				return nil, 0, err
+			}
+		}
+		if creditsDue(from, newStatus) {
+			if err := s.grantCreditsInTx(ctx, tx, activity, now); err != nil {
+				return nil, 0, err
+			}
+		}
+	}
 
-		// 简单的状态值校验
-		if newStatus != model.ActivityStatusDraft &&
-			newStatus != model.ActivityStatusPublished &&
-			newStatus != model.ActivityStatusClosed &&
-			newStatus != model.ActivityStatusFinished {
-			return errors.New("invalid status value")
+	// 已发布的活动时间或地点变更时通知报名者 (草稿没有报名者)
+	if activity.Status != model.ActivityStatusDraft &&
+		(!activity.StartTime.Equal(oldStart) || !activity.EndTime.Equal(oldEnd) || activity.Location != oldLocation) {
+		if err := notifyRegistrants(ctx, s.registrationRepo.WithTx(tx), s.notificationRepo.WithTx(tx), model.NotificationEventActivityUpdated, activity); err != nil {
+			return nil, 0, err
 		}
-		activity.Status = newStatus
+	}
+
+	// F. 名额变化后递补候补队列 (快速报名活动需计入尚未落库的预占名额)
+	if err := s.fastRegistration.FillPendingSeats(ctx, activity); err != nil {
+		return nil, 0, err
+	}
+	promoted, err := fillSeatsFromWaitlist(ctx, s.registrationRepo.WithTx(tx), activity)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := enqueueNotifications(ctx, s.notificationRepo.WithTx(tx), model.NotificationEventPromoted, activity, promoted...); err != nil {
+		return nil, 0, err
+	}
+	seatDelta := 0
+	if oldMax > 0 && activity.MaxParticipants > 0 {
+		seatDelta = activity.MaxParticipants - oldMax - len(promoted)
 	}
 
 	// 4. 调用 Repository 更新
-	return s.activityRepo.Update(ctx, activity)
+	if err := s.activityRepo.WithTx(tx).Update(ctx, activity); err != nil {
+		return nil, 0, err
+	}
+	if err := recordAudit(ctx, s.auditRepo.WithTx(tx), model.AuditActionActivityUpdate, model.AuditTargetActivity, id, before, auditSnapshot(activity)); err != nil {
+		return nil, 0, err
+	}
+	return activity, seatDelta, nil
 }
 
 // DeleteActivity 删除活动
 func (s *activityServiceImpl) DeleteActivity(ctx context.Context, id uint) error {
 	// 考虑删除活动的连锁反应（报名记录）。如果使用 Gorm 外键约束 ON DELETE CASCADE，则会自动删除。
-	return s.activityRepo.Delete(ctx, id)
+	var activity *model.Activity
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		activity, err = s.activityRepo.WithTx(tx).FindByIDForUpdate(ctx, id)
+		if err != nil {
+			return ErrActivityNotFound
+		}
+		if err := s.activityRepo.WithTx(tx).Delete(ctx, id); err != nil {
+			return err
+		}
+		return recordAudit(ctx, s.auditRepo.WithTx(tx), model.AuditActionActivityDelete, model.AuditTargetActivity, id, auditSnapshot(activity), nil)
+	})
+	if err != nil {
+		return err
+	}
+	// 活动已删除，校对时会清除快速报名的 Redis 名额
+	s.syncFastRegistration(ctx, activity)
+	return nil
+}
+
+// attendanceCodeKind 签到和签退动态码的差异部分
+type attendanceCodeKind struct {
+	secret      func(activity *model.Activity) *string             // 动态码密钥字段
+	available   func(activity *model.Activity, now time.Time) bool // 当前能否获取动态码
+	unavailable error
+	qrBaseURL   string // 二维码中页面的地址
+}
+
+// GetSignInCode 获取活动当前时间窗口的签到动态码
+// 仅在活动进行期间可获取；历史活动缺少密钥时在行锁内补齐
+func (s *activityServiceImpl) GetSignInCode(ctx context.Context, id uint) (*model.SignInCodeResponse, error) {
+	return s.getAttendanceCode(ctx, id, attendanceCodeKind{
+		secret: func(activity *model.Activity) *string { return &activity.SignInSecret },
+		available: func(activity *model.Activity, now time.Time) bool {
+			// 已取消的活动不能签到
+			return activity.Status != model.ActivityStatusCancelled && !now.Before(activity.StartTime) && !now.After(activity.EndTime)
+		},
+		unavailable: ErrSignInNotAvailable,
+		qrBaseURL:   config.GlobalConfig.SignIn.QRBaseURL,
+	})
+}
+
+// GetSignOutCode 获取活动当前时间窗口的签退动态码
+// 活动开始后至结束后 attendance.sign_out_grace 内可获取，密钥与签到动态码相互独立
+func (s *activityServiceImpl) GetSignOutCode(ctx context.Context, id uint) (*model.SignInCodeResponse, error) {
+	return s.getAttendanceCode(ctx, id, attendanceCodeKind{
+		secret:      func(activity *model.Activity) *string { return &activity.SignOutSecret },
+		available:   signOutOpen,
+		unavailable: ErrSignOutNotAvailable,
+		qrBaseURL:   config.GlobalConfig.Attendance.SignOutQRBaseURL,
+	})
+}
+
+// backfillAttendanceSecret 为升级前创建的活动生成动态码密钥
+// 加锁后重新读取，并发请求只会生成一次密钥
+func (s *activityServiceImpl) backfillAttendanceSecret(ctx context.Context, id uint, kind attendanceCodeKind) (string, error) {
+	var secret string
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		activity, err := s.activityRepo.WithTx(tx).FindByIDForUpdate(ctx, id)
+		if err != nil {
+			return ErrActivityNotFound
+		}
+		field := kind.secret(activity)
+		if *field == "" {
+			*field, err = utils.GenerateRandomToken(20)
+			if err != nil {
+				return err
+			}
+			if err := s.activityRepo.WithTx(tx).Update(ctx, activity); err != nil {
+				return err
+			}
+		}
+		secret = *field
+		return nil
+	})
+	return secret, err
+}
+
+// getAttendanceCode 计算签到或签退动态码及二维码内容
+// 大屏会频繁轮询，这里不加行锁读取活动，以免与报名、取消等操作争用活动行锁
+func (s *activityServiceImpl) getAttendanceCode(ctx context.Context, id uint, kind attendanceCodeKind) (*model.SignInCodeResponse, error) {
+	activity, err := s.activityRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, ErrActivityNotFound
+	}
+
+	// 1. 检查是否在可获取的时间范围内
+	if !kind.available(activity, time.Now()) {
+		return nil, kind.unavailable
+	}
+
+	// 2. 兼容升级前创建的活动：补齐动态码密钥
+	secret := *kind.secret(activity)
+	if secret == "" {
+		secret, err = s.backfillAttendanceSecret(ctx, id, kind)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	key, err := hex.DecodeString(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. 计算当前窗口的动态码及其过期时间
+	period := SignInCodePeriod()
+	now := time.Now()
+	counter := utils.TOTPCounter(now, period)
+	code := utils.GenerateTOTP(key, counter)
+	expiresAt := time.Unix(int64(counter+1)*int64(period/time.Second), 0)
+
+	// 4. 组装二维码内容：签到 (签退) 页面URL + 活动ID + 动态码
+	query := url.Values{}
+	query.Set("activity_id", strconv.FormatUint(uint64(id), 10))
+	query.Set("code", code)
+
+	return &model.SignInCodeResponse{
+		ActivityID: id,
+		Code:       code,
+		QRPayload:  kind.qrBaseURL + "?" + query.Encode(),
+		Period:     int(period / time.Second),
+		ExpiresAt:  expiresAt,
+	}, nil
+}
+
+// VerifyDisplayKey 校验大屏展示密钥
+func (s *activityServiceImpl) VerifyDisplayKey(ctx context.Context, id uint, displayKey string) error {
+	activity, err := s.activityRepo.FindByID(ctx, id)
+	if err != nil {
+		return ErrActivityNotFound
+	}
+	if displayKey == "" || !utils.CheckTokenHash(displayKey, activity.DisplayKeyHash) {
+		return ErrInvalidDisplayKey
+	}
+	return nil
+}
+
+// RotateDisplayKey 重新生成大屏展示密钥，旧密钥立即失效
+// 明文只返回这一次，数据库中只保存哈希
+func (s *activityServiceImpl) RotateDisplayKey(ctx context.Context, id uint) (string, error) {
+	var displayKey string
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		activity, err := s.activityRepo.WithTx(tx).FindByIDForUpdate(ctx, id)
+		if err != nil {
+			return ErrActivityNotFound
+		}
+
+		displayKey, err = utils.GenerateRandomToken(16)
+		if err != nil {
+			return err
+		}
+		activity.DisplayKeyHash = utils.HashToken(displayKey)
+		if err := s.activityRepo.WithTx(tx).Update(ctx, activity); err != nil {
+			return err
+		}
+		// 展示密钥只存哈希，审计日志中仅记录发生了轮换
+		return recordAudit(ctx, s.auditRepo.WithTx(tx), model.AuditActionActivityDisplayKey, model.AuditTargetActivity, id, nil, map[string]any{"display_key": "rotated"})
+	})
+	if err != nil {
+		return "", err
+	}
+	return displayKey, nil
+}
+
+// SignInCodePeriod 签到动态码刷新周期，未配置时默认30秒
+func SignInCodePeriod() time.Duration {
+	period := config.GlobalConfig.JWT.SignInExpiresIn
+	if period < time.Second {
+		return 30 * time.Second
+	}
+	return period
+}
+
+// CheckActivityAccess 校验管理员是否可以修改该活动
+// 超级管理员可以修改所有活动；签到人员的可访问范围已由路由限定为签到接口；
+// 组织者只能修改自己创建或协办的活动。
+func (s *activityServiceImpl) CheckActivityAccess(ctx context.Context, adminID uint, role model.AdminRole, activityID uint) error {
+	activity, err := s.activityRepo.FindByID(ctx, activityID)
+	if err != nil {
+		return ErrActivityNotFound
+	}
+
+	switch role {
+	case model.AdminRoleSuper, model.AdminRoleStaff:
+		return nil
+	case model.AdminRoleOrganizer:
+		if activity.AdminID == adminID {
+			return nil
+		}
+		isCoOrganizer, err := s.activityRepo.IsCoOrganizer(ctx, activityID, adminID)
+		if err != nil {
+			return err
+		}
+		if isCoOrganizer {
+			return nil
+		}
+	}
+	return ErrActivityForbidden
+}
+
+// CheckRegistrationAccess 校验管理员能否查看该活动的报名记录 (含手机号、报名表答案等个人信息)
+// 超级管理员可以查看所有活动；组织者只能查看自己创建或协办的活动；
+// 签到人员不与活动绑定，只能在活动签到期间 (活动开始至签退截止) 查看，用于现场核对名单
+func (s *activityServiceImpl) CheckRegistrationAccess(ctx context.Context, adminID uint, role model.AdminRole, activityID uint) error {
+	if role != model.AdminRoleStaff {
+		err := s.CheckActivityAccess(ctx, adminID, role, activityID)
+		if errors.Is(err, ErrActivityForbidden) {
+			return ErrRegistrationsForbidden
+		}
+		return err
+	}
+
+	activity, err := s.activityRepo.FindByID(ctx, activityID)
+	if err != nil {
+		return ErrActivityNotFound
+	}
+	if !signOutOpen(activity, time.Now()) {
+		return ErrRegistrationsForbidden
+	}
+	return nil
+}
+
+// SetCoOrganizers 整体替换活动的协办组织者
+// 只能指定组织者角色的账号，创建者本人不需要也不能被重复指定
+func (s *activityServiceImpl) SetCoOrganizers(ctx context.Context, activityID uint, adminIDs []uint) ([]*model.Admin, error) {
+	var admins []*model.Admin
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		activity, err := s.activityRepo.WithTx(tx).FindByIDForUpdate(ctx, activityID)
+		if err != nil {
+			return ErrActivityNotFound
+		}
+
+		// 1. 去重并排除创建者本人
+		ids := make([]uint, 0, len(adminIDs))
+		seen := make(map[uint]bool, len(adminIDs))
+		for _, id := range adminIDs {
+			if id == activity.AdminID || seen[id] {
+				continue
+			}
+			seen[id] = true
+			ids = append(ids, id)
+		}
+
+		// 2. 校验账号存在且为组织者
+		admins, err = s.adminRepo.FindByIDs(ctx, ids)
+		if err != nil {
+			return err
+		}
+		if len(admins) != len(ids) {
+			return ErrInvalidCoOrganizer
+		}
+		for _, admin := range admins {
+			if admin.Role != model.AdminRoleOrganizer {
+				return ErrInvalidCoOrganizer
+			}
+		}
+
+		// 3. 整体替换，审计日志记录替换前后的账号ID
+		current, err := s.activityRepo.WithTx(tx).ListCoOrganizers(ctx, activityID)
+		if err != nil {
+			return err
+		}
+		if err := s.activityRepo.WithTx(tx).ReplaceCoOrganizers(ctx, activity, admins); err != nil {
+			return err
+		}
+		return recordAudit(ctx, s.auditRepo.WithTx(tx), model.AuditActionActivityCoOrganizers, model.AuditTargetActivity, activityID,
+			map[string]any{"co_organizer_ids": adminIDList(current)}, map[string]any{"co_organizer_ids": adminIDList(admins)})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return admins, nil
+}
+
+// UpdateFormSchema 整体替换活动的报名表自定义字段
+// 发布后已有报名者按旧表单填写，因此只允许在草稿状态修改
+func (s *activityServiceImpl) UpdateFormSchema(ctx context.Context, id uint, schema model.FormSchema) (model.FormSchema, error) {
+	if err := validateFormSchema(schema); err != nil {
+		return nil, err
+	}
+	if len(schema) == 0 {
+		schema = nil
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		activity, err := s.activityRepo.WithTx(tx).FindByIDForUpdate(ctx, id)
+		if err != nil {
+			return ErrActivityNotFound
+		}
+		if activity.Status != model.ActivityStatusDraft {
+			return ErrFormSchemaLocked
+		}
+
+		before := map[string]any{"form_schema": activity.FormSchema}
+		activity.FormSchema = schema
+		if err := s.activityRepo.WithTx(tx).Update(ctx, activity); err != nil {
+			return err
+		}
+		return recordAudit(ctx, s.auditRepo.WithTx(tx), model.AuditActionActivityFormUpdate, model.AuditTargetActivity, id,
+			before, map[string]any{"form_schema": schema})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// GetEligibility 查询活动的报名资格规则和报名名单的条目数
+func (s *activityServiceImpl) GetEligibility(ctx context.Context, id uint) (*model.EligibilityResponse, error) {
+	activity, err := s.activityRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, ErrActivityNotFound
+	}
+	counts, err := s.allowlistRepo.CountByKind(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &model.EligibilityResponse{
+		AllowlistPhones:     counts[model.AllowlistKindPhone],
+		AllowlistStudentIDs: counts[model.AllowlistKindStudentID],
+	}
+	if activity.Eligibility != nil {
+		resp.Rules = *activity.Eligibility
+	}
+	return resp, nil
+}
+
+// UpdateEligibility 整体替换活动的报名资格规则，任何状态都可以修改，只对之后的报名生效
+// 学院名额调整后，名额已满而保留的候补记录可能可以递补，这里在同一事务内按新规则递补
+func (s *activityServiceImpl) UpdateEligibility(ctx context.Context, id uint, rules *model.EligibilityRules) error {
+	rules, err := normalizeEligibilityRules(rules)
+	if err != nil {
+		return err
+	}
+
+	var (
+		activity *model.Activity
+		promoted []*model.Registration
+	)
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		activity, err = s.activityRepo.WithTx(tx).FindByIDForUpdate(ctx, id)
+		if err != nil {
+			return ErrActivityNotFound
+		}
+		if activity.Status == model.ActivityStatusFinished || activity.Status == model.ActivityStatusCancelled {
+			return ErrEligibilityLocked
+		}
+
+		before := map[string]any{"eligibility": activity.Eligibility}
+		activity.Eligibility = rules
+
+		if err := s.fastRegistration.FillPendingSeats(ctx, activity); err != nil {
+			return err
+		}
+		promoted, err = fillSeatsFromWaitlist(ctx, s.registrationRepo.WithTx(tx), activity)
+		if err != nil {
+			return err
+		}
+		if err := enqueueNotifications(ctx, s.notificationRepo.WithTx(tx), model.NotificationEventPromoted, activity, promoted...); err != nil {
+			return err
+		}
+		if err := s.activityRepo.WithTx(tx).Update(ctx, activity); err != nil {
+			return err
+		}
+		return recordAudit(ctx, s.auditRepo.WithTx(tx), model.AuditActionEligibilityUpdate, model.AuditTargetActivity, id,
+			before, map[string]any{"eligibility": rules})
+	})
+	if err != nil {
+		return err
+	}
+	if activity.MaxParticipants > 0 {
+		s.fastRegistration.AdjustSeats(ctx, activity, -len(promoted))
+	}
+	return nil
+}
+
+// ReplaceAllowlist 整体替换活动的报名名单，审计日志只记录条目数
+func (s *activityServiceImpl) ReplaceAllowlist(ctx context.Context, id uint, entries []*model.AllowlistEntry) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := s.activityRepo.WithTx(tx).FindByIDForUpdate(ctx, id); err != nil {
+			return ErrActivityNotFound
+		}
+		counts, err := s.allowlistRepo.WithTx(tx).CountByKind(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := s.allowlistRepo.WithTx(tx).Replace(ctx, id, entries); err != nil {
+			return err
+		}
+
+		after := make(map[model.AllowlistKind]int64)
+		for _, entry := range entries {
+			after[entry.Kind]++
+		}
+		return recordAudit(ctx, s.auditRepo.WithTx(tx), model.AuditActionAllowlistReplace, model.AuditTargetActivity, id,
+			map[string]any{"allowlist": counts}, map[string]any{"allowlist": after})
+	})
+}
+
+// ListCoOrganizers 列出活动的协办组织者
+func (s *activityServiceImpl) ListCoOrganizers(ctx context.Context, activityID uint) ([]*model.Admin, error) {
+	if _, err := s.activityRepo.FindByID(ctx, activityID); err != nil {
+		return nil, ErrActivityNotFound
+	}
+	return s.activityRepo.ListCoOrganizers(ctx, activityID)
+}
+
+// adminIDList 提取管理员ID列表
+func adminIDList(admins []*model.Admin) []uint {
+	ids := make([]uint, len(admins))
+	for i, admin := range admins {
+		ids[i] = admin.ID
+	}
+	return ids
 }
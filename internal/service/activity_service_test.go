@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/frozenf1sh/gostudent/internal/config"
+	"github.com/frozenf1sh/gostudent/internal/model"
+	"github.com/frozenf1sh/gostudent/internal/repository"
+)
+
+// 报名记录的查看权限：组织者只能查看自己的活动，签到人员只能在活动签到期间查看
+func TestCheckRegistrationAccess(t *testing.T) {
+	config.GlobalConfig = config.Config{}
+	db := newTestDB(t)
+	s := &activityServiceImpl{db: db, activityRepo: repository.NewActivityRepository(db)}
+	ctx := context.Background()
+
+	const ownerID, otherOrganizerID, staffID = 2, 3, 4
+	now := time.Now()
+	create := func(start time.Time, status model.ActivityStatus) uint {
+		t.Helper()
+		activity := &model.Activity{
+			AdminID:              ownerID,
+			Title:                "活动",
+			Type:                 "讲座",
+			StartTime:            start,
+			EndTime:              start.Add(2 * time.Hour),
+			Location:             "A101",
+			Status:               status,
+			RegistrationDeadline: start.Add(-time.Hour),
+		}
+		if err := db.Create(activity).Error; err != nil {
+			t.Fatal(err)
+		}
+		return activity.ID
+	}
+	upcoming := create(now.Add(24*time.Hour), model.ActivityStatusPublished)
+	running := create(now.Add(-time.Hour), model.ActivityStatusClosed)
+	inSignOutGrace := create(now.Add(-2*time.Hour-10*time.Minute), model.ActivityStatusFinished)
+	finished := create(now.Add(-72*time.Hour), model.ActivityStatusFinished)
+	cancelled := create(now.Add(-time.Hour), model.ActivityStatusCancelled)
+
+	tests := []struct {
+		name       string
+		adminID    uint
+		role       model.AdminRole
+		activityID uint
+		want       error
+	}{
+		{"super admin", 1, model.AdminRoleSuper, finished, nil},
+		{"owner", ownerID, model.AdminRoleOrganizer, upcoming, nil},
+		{"other organizer", otherOrganizerID, model.AdminRoleOrganizer, running, ErrRegistrationsForbidden},
+		{"staff before start", staffID, model.AdminRoleStaff, upcoming, ErrRegistrationsForbidden},
+		{"staff while running", staffID, model.AdminRoleStaff, running, nil},
+		{"staff during sign-out grace", staffID, model.AdminRoleStaff, inSignOutGrace, nil},
+		{"staff after sign-out closes", staffID, model.AdminRoleStaff, finished, ErrRegistrationsForbidden},
+		{"staff on cancelled activity", staffID, model.AdminRoleStaff, cancelled, ErrRegistrationsForbidden},
+		{"missing activity", staffID, model.AdminRoleStaff, 999, ErrActivityNotFound},
+	}
+	for _, tt := range tests {
+		err := s.CheckRegistrationAccess(ctx, tt.adminID, tt.role, tt.activityID)
+		if !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+			t.Errorf("%s: CheckRegistrationAccess = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
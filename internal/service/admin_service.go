@@ -3,87 +3,405 @@ package service
 import (
 	"context"
 	"errors"
+	"log/slog"
+	"time"
 
 	"github.com/frozenf1sh/gostudent/internal/config"
 	"github.com/frozenf1sh/gostudent/internal/model"
 	"github.com/frozenf1sh/gostudent/internal/repository"
+	"github.com/frozenf1sh/gostudent/pkg/redis"
 	"github.com/frozenf1sh/gostudent/pkg/utils" // 假设 utils 包中包含 JWT 和 Hash 函数
+	"gorm.io/gorm"
 )
 
 var (
-	ErrAdminNotFound   = errors.New("admin not found")
-	ErrInvalidPassword = errors.New("invalid username or password")
+	ErrAdminNotFound       = errors.New("admin not found")
+	ErrInvalidPassword     = errors.New("invalid username or password")
+	ErrInvalidRole         = errors.New("invalid admin role")
+	ErrAdminDisabled       = errors.New("admin account is disabled")
+	ErrUsernameTaken       = errors.New("username already exists")
+	ErrCannotModifySelf    = errors.New("cannot disable or delete your own account")
+	ErrAdminHasActivities  = errors.New("admin still owns activities")
+	ErrCannotDeleteDefault = errors.New("cannot delete the default super admin")
+	ErrOldPasswordMismatch = errors.New("old password is incorrect")
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reused, session revoked")
+)
+
+// 令牌有效期的默认值，配置项未填写时使用
+// 刷新令牌的有效期同时是令牌家族在 Redis 中的过期时间，为 0 时家族永不过期且无法按管理员吊销
+const (
+	defaultAdminExpiresIn   = 15 * time.Minute
+	defaultRefreshExpiresIn = 7 * 24 * time.Hour
+)
+
+// DefaultSuperAdminID 启动时按配置创建的默认超级管理员的ID
+const DefaultSuperAdminID uint = 1
+
+// 管理员访问令牌 Data 中的键值
+const (
+	adminTokenType      = "admin_login"
+	adminTokenKeyType   = "type"
+	adminTokenKeyID     = "admin_id"
+	adminTokenKeyFamily = "fid" // 令牌家族ID，同一次登录签发的令牌共享
 )
 
 // 接口：管理员业务逻辑接口
 type AdminService interface {
-	// 登录，返回 JWT token
-	Login(ctx context.Context, req *model.AdminLoginRequest) (string, error)
+	// 登录，返回 JWT token 及管理员信息；连续失败过多时返回 *LoginLockedError
+	Login(ctx context.Context, req *model.AdminLoginRequest, clientIP string) (*model.AdminLoginResponse, error)
+	// Refresh 使用刷新令牌换取新的访问令牌和刷新令牌 (轮换)
+	Refresh(ctx context.Context, refreshToken string) (*model.AdminLoginResponse, error)
+	// Logout 注销当前访问令牌并吊销其所属会话
+	Logout(ctx context.Context, jti, familyID string, expiresAt time.Time) error
+	// IsTokenRevoked 检查访问令牌是否已被注销或吊销
+	IsTokenRevoked(ctx context.Context, jti, familyID string) (bool, error)
 	// 通过 ID 获取
 	GetByID(ctx context.Context, id uint) (*model.Admin, error)
-	// CreateAdmin 用于初始化超级管理员 (通常只在 setup 阶段运行一次)
-	CreateAdmin(ctx context.Context, username, password string) error
+	// CreateAdmin 创建管理员账号 (初始化超级管理员及超级管理员创建账号)
+	CreateAdmin(ctx context.Context, username, password string, role model.AdminRole) (*model.Admin, error)
+	// SetRole 修改管理员角色
+	SetRole(ctx context.Context, id uint, role model.AdminRole) error
+
+	// 账号管理 (超级管理员)
+	ListAdmins(ctx context.Context, params *model.ListAdminsParams) ([]*model.Admin, int64, error)
+	SetDisabled(ctx context.Context, operatorID, id uint, disabled bool) error
+	ResetPassword(ctx context.Context, id uint, newPassword string) error
+	DeleteAdmin(ctx context.Context, operatorID, id uint) error
+	// ChangePassword 管理员修改自己的密码，需要验证旧密码
+	ChangePassword(ctx context.Context, id uint, oldPassword, newPassword string) error
 }
 
 // 接口实现
 type adminServiceImpl struct {
+	db        *gorm.DB // 账号修改与审计日志在同一事务中写入
 	adminRepo repository.AdminRepository
+	auditRepo repository.AuditLogRepository
+	guard     *loginGuard
+
+	accessExpiresIn  time.Duration // 访问令牌有效期
+	refreshExpiresIn time.Duration // 刷新令牌有效期
 }
 
 // NewAdminService 创建 AdminService 实例
-func NewAdminService(repo repository.AdminRepository) AdminService {
-	return &adminServiceImpl{adminRepo: repo}
+func NewAdminService(db *gorm.DB, repo repository.AdminRepository, auditRepo repository.AuditLogRepository) AdminService {
+	s := &adminServiceImpl{
+		db:               db,
+		adminRepo:        repo,
+		auditRepo:        auditRepo,
+		guard:            newLoginGuard(),
+		accessExpiresIn:  config.GlobalConfig.JWT.AdminExpiresIn,
+		refreshExpiresIn: config.GlobalConfig.JWT.RefreshExpiresIn,
+	}
+	if s.accessExpiresIn <= 0 {
+		s.accessExpiresIn = defaultAdminExpiresIn
+	}
+	if s.refreshExpiresIn <= 0 {
+		s.refreshExpiresIn = defaultRefreshExpiresIn
+	}
+	return s
 }
 
-// CreateAdmin 仅用于项目初始化，创建第一个管理员
-func (s *adminServiceImpl) CreateAdmin(ctx context.Context, username, password string) error {
-	// 1. 哈希密码
+// CreateAdmin 创建管理员账号
+func (s *adminServiceImpl) CreateAdmin(ctx context.Context, username, password string, role model.AdminRole) (*model.Admin, error) {
+	if !isValidRole(role) {
+		return nil, ErrInvalidRole
+	}
+
+	// 1. 用户名唯一性校验
+	if existing, err := s.adminRepo.FindByUsername(ctx, username); err == nil && existing != nil {
+		return nil, ErrUsernameTaken
+	}
+
+	// 2. 哈希密码
 	hashedPassword, err := utils.HashPassword(password)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// 2. 构造模型
+	// 3. 构造模型
 	admin := &model.Admin{
 		Username:     username,
 		PasswordHash: hashedPassword,
+		Role:         role,
 	}
 
-	// 3. 存储到数据库
-	return s.adminRepo.Create(ctx, admin)
+	// 4. 存储到数据库
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := s.adminRepo.WithTx(tx).Create(ctx, admin); err != nil {
+			return err
+		}
+		return recordAudit(ctx, s.auditRepo.WithTx(tx), model.AuditActionAdminCreate, model.AuditTargetAdmin, admin.ID, nil, auditSnapshot(admin))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return admin, nil
 }
 
 // Login 处理管理员登录逻辑
-func (s *adminServiceImpl) Login(ctx context.Context, req *model.AdminLoginRequest) (string, error) {
+func (s *adminServiceImpl) Login(ctx context.Context, req *model.AdminLoginRequest, clientIP string) (*model.AdminLoginResponse, error) {
+	// 0. 该用户名在当前 IP 或当前 IP 处于锁定期时直接拒绝，不再进行密码校验
+	if err := s.guard.check(ctx, req.Username, clientIP); err != nil {
+		return nil, err
+	}
+
 	// 1. 通过用户名查找管理员
 	admin, err := s.adminRepo.FindByUsername(ctx, req.Username)
-	if err != nil {
-		// 统一返回错误，避免暴露用户是否存在的信息
-		return "", ErrInvalidPassword
-	}
-	if admin == nil {
-		return "", ErrInvalidPassword
+	if err != nil || admin == nil {
+		// 统一返回错误，避免暴露用户是否存在的信息；不存在的用户名同样计入失败次数
+		return nil, s.loginFailed(ctx, req.Username, clientIP)
 	}
 
 	// 2. 验证密码
 	if !utils.CheckPasswordHash(req.Password, admin.PasswordHash) {
-		return "", ErrInvalidPassword
+		return nil, s.loginFailed(ctx, req.Username, clientIP)
+	}
+	if err := s.guard.reset(ctx, req.Username, clientIP); err != nil {
+		slog.Warn("清除登录失败计数失败", "username", req.Username, "error", err)
 	}
 
-	// 3. 生成 JWT Token
+	// 已停用的账号不能登录 (密码正确后才提示，避免暴露账号状态)
+	if admin.Disabled {
+		return nil, ErrAdminDisabled
+	}
+
+	// 3. 创建令牌家族并签发访问令牌和刷新令牌
+	familyID, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	if err := redis.CreateTokenFamily(ctx, admin.ID, familyID, utils.HashToken(refreshToken), s.refreshExpiresIn); err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(admin, familyID, refreshToken)
+}
+
+// loginFailed 记录一次登录失败，达到阈值时返回锁定错误，否则返回 ErrInvalidPassword
+func (s *adminServiceImpl) loginFailed(ctx context.Context, username, clientIP string) error {
+	if err := s.guard.recordFailure(ctx, username, clientIP); err != nil {
+		return err
+	}
+	return ErrInvalidPassword
+}
+
+// Refresh 轮换刷新令牌
+// 已使用过的刷新令牌再次出现说明令牌可能泄露，整个家族 (该次登录的所有令牌) 会被吊销
+func (s *adminServiceImpl) Refresh(ctx context.Context, refreshToken string) (*model.AdminLoginResponse, error) {
+	newRefreshToken, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	familyID, adminID, err := redis.RotateRefreshToken(ctx, utils.HashToken(refreshToken), utils.HashToken(newRefreshToken), s.refreshExpiresIn)
+	if err != nil {
+		switch {
+		case errors.Is(err, redis.ErrRefreshTokenReused):
+			slog.Warn("检测到刷新令牌重用，已吊销整个令牌家族")
+			return nil, ErrRefreshTokenReused
+		case errors.Is(err, redis.ErrRefreshTokenNotFound):
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	// 账号可能已被删除或停用
+	admin, err := s.adminRepo.FindByID(ctx, adminID)
+	if err != nil {
+		_ = redis.RevokeTokenFamily(ctx, familyID)
+		return nil, ErrInvalidRefreshToken
+	}
+	if admin.Disabled {
+		_ = redis.RevokeTokenFamily(ctx, familyID)
+		return nil, ErrAdminDisabled
+	}
+
+	return s.issueTokens(admin, familyID, newRefreshToken)
+}
+
+// issueTokens 为令牌家族签发新的访问令牌，并组装登录响应
+func (s *adminServiceImpl) issueTokens(admin *model.Admin, familyID, refreshToken string) (*model.AdminLoginResponse, error) {
 	data := map[string]any{
-		"type":     "admin_login", // 业务层定义的类型标签
-		"admin_id": admin.ID,
+		adminTokenKeyType:   adminTokenType, // 业务层定义的类型标签
+		adminTokenKeyID:     admin.ID,
+		adminTokenKeyFamily: familyID,
 	}
-	token, err := utils.GenerateGenericJWT(data, config.GlobalConfig.JWT.AdminExpiresIn)
+	expiresIn := s.accessExpiresIn
+	token, err := utils.GenerateGenericJWT(data, expiresIn)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return token, nil
+	return &model.AdminLoginResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(expiresIn.Seconds()),
+		Username:     admin.Username,
+		Role:         admin.Role,
+	}, nil
+}
+
+// Logout 将访问令牌加入黑名单并吊销其所属家族 (对应的刷新令牌随之失效)
+func (s *adminServiceImpl) Logout(ctx context.Context, jti, familyID string, expiresAt time.Time) error {
+	if err := redis.DenyAccessToken(ctx, jti, expiresAt); err != nil {
+		return err
+	}
+	return redis.RevokeTokenFamily(ctx, familyID)
+}
+
+// IsTokenRevoked 检查访问令牌 jti 黑名单和家族吊销状态
+func (s *adminServiceImpl) IsTokenRevoked(ctx context.Context, jti, familyID string) (bool, error) {
+	if jti == "" || familyID == "" {
+		// 缺少 jti 或家族的旧令牌无法吊销，视为无效
+		return true, nil
+	}
+	return redis.IsAccessTokenRevoked(ctx, jti, familyID)
 }
 
 // GetByID 通过 ID 获取管理员信息
 func (s *adminServiceImpl) GetByID(ctx context.Context, id uint) (*model.Admin, error) {
 	return s.adminRepo.FindByID(ctx, id)
 }
+
+// SetRole 修改管理员角色
+func (s *adminServiceImpl) SetRole(ctx context.Context, id uint, role model.AdminRole) error {
+	if !isValidRole(role) {
+		return ErrInvalidRole
+	}
+	return s.updateAdmin(ctx, id, model.AuditActionAdminRoleUpdate, func(admin *model.Admin) error {
+		admin.Role = role
+		return nil
+	})
+}
+
+// updateAdmin 在事务中加载管理员、执行修改并记录审计日志
+func (s *adminServiceImpl) updateAdmin(ctx context.Context, id uint, action string, mutate func(admin *model.Admin) error) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		admin, err := s.adminRepo.WithTx(tx).FindByID(ctx, id)
+		if err != nil {
+			return ErrAdminNotFound
+		}
+		before := auditSnapshot(admin)
+		oldPasswordHash := admin.PasswordHash
+		if err := mutate(admin); err != nil {
+			return err
+		}
+		if err := s.adminRepo.WithTx(tx).Update(ctx, admin); err != nil {
+			return err
+		}
+		after := auditSnapshot(admin)
+		if admin.PasswordHash != oldPasswordHash {
+			// 密码哈希不进入审计日志，只记录密码已变更
+			after["password"] = "changed"
+		}
+		return recordAudit(ctx, s.auditRepo.WithTx(tx), action, model.AuditTargetAdmin, id, before, after)
+	})
+}
+
+// isValidRole 校验角色取值
+func isValidRole(role model.AdminRole) bool {
+	return role == model.AdminRoleSuper || role == model.AdminRoleOrganizer || role == model.AdminRoleStaff
+}
+
+// ListAdmins 多条件查询管理员
+func (s *adminServiceImpl) ListAdmins(ctx context.Context, params *model.ListAdminsParams) ([]*model.Admin, int64, error) {
+	return s.adminRepo.List(ctx, params)
+}
+
+// SetDisabled 停用或启用管理员账号，不能停用自己
+func (s *adminServiceImpl) SetDisabled(ctx context.Context, operatorID, id uint, disabled bool) error {
+	if operatorID == id {
+		return ErrCannotModifySelf
+	}
+	err := s.updateAdmin(ctx, id, model.AuditActionAdminStatusUpdate, func(admin *model.Admin) error {
+		admin.Disabled = disabled
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if disabled {
+		// 停用后已签发的令牌全部吊销
+		return redis.RevokeAdminTokens(ctx, id)
+	}
+	return nil
+}
+
+// ResetPassword 超级管理员直接重置管理员密码
+func (s *adminServiceImpl) ResetPassword(ctx context.Context, id uint, newPassword string) error {
+	hashedPassword, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	err = s.updateAdmin(ctx, id, model.AuditActionAdminPasswordReset, func(admin *model.Admin) error {
+		admin.PasswordHash = hashedPassword
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// 密码被重置后，旧会话全部失效
+	return redis.RevokeAdminTokens(ctx, id)
+}
+
+// DeleteAdmin 删除管理员账号
+// 不能删除自己和默认超级管理员；仍创建有活动的账号需要先转移或删除活动，可改为停用
+func (s *adminServiceImpl) DeleteAdmin(ctx context.Context, operatorID, id uint) error {
+	if operatorID == id {
+		return ErrCannotModifySelf
+	}
+	// 启动时按 ID 检查默认超级管理员，删除后重启会用配置中的默认密码重新创建
+	if id == DefaultSuperAdminID {
+		return ErrCannotDeleteDefault
+	}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		admin, err := s.adminRepo.WithTx(tx).FindByID(ctx, id)
+		if err != nil {
+			return ErrAdminNotFound
+		}
+		count, err := s.adminRepo.WithTx(tx).CountOwnedActivities(ctx, id)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrAdminHasActivities
+		}
+		if err := s.adminRepo.WithTx(tx).Delete(ctx, id); err != nil {
+			return err
+		}
+		return recordAudit(ctx, s.auditRepo.WithTx(tx), model.AuditActionAdminDelete, model.AuditTargetAdmin, id, auditSnapshot(admin), nil)
+	})
+	if err != nil {
+		return err
+	}
+	return redis.RevokeAdminTokens(ctx, id)
+}
+
+// ChangePassword 管理员修改自己的密码
+func (s *adminServiceImpl) ChangePassword(ctx context.Context, id uint, oldPassword, newPassword string) error {
+	admin, err := s.adminRepo.FindByID(ctx, id)
+	if err != nil {
+		return ErrAdminNotFound
+	}
+	if !utils.CheckPasswordHash(oldPassword, admin.PasswordHash) {
+		return ErrOldPasswordMismatch
+	}
+	hashedPassword, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	err = s.updateAdmin(ctx, id, model.AuditActionAdminPasswordChange, func(admin *model.Admin) error {
+		admin.PasswordHash = hashedPassword
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// 修改密码后所有会话 (包括当前会话) 需要重新登录
+	return redis.RevokeAdminTokens(ctx, id)
+}
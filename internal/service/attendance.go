@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/frozenf1sh/gostudent/internal/config"
+	"github.com/frozenf1sh/gostudent/internal/model"
+	"github.com/frozenf1sh/gostudent/pkg/utils"
+)
+
+var (
+	ErrSignOutNotAvailable = errors.New("sign-out is only available from the start of the activity until shortly after it ends")
+	ErrInvalidSignOutCode  = errors.New("invalid or expired sign-out code")
+	ErrNotSignedIn         = errors.New("participant has not signed in")
+	ErrAlreadySignedOut    = errors.New("participant has already signed out")
+)
+
+// SignOutGrace 活动结束后仍可签退的时长，未配置时为 30 分钟
+func SignOutGrace() time.Duration {
+	grace := config.GlobalConfig.Attendance.SignOutGrace
+	if grace <= 0 {
+		return 30 * time.Minute
+	}
+	return grace
+}
+
+// signOutOpen 判断当前是否可以签退：活动开始后至结束后 SignOutGrace 内，已取消的活动不能签退
+func signOutOpen(activity *model.Activity, now time.Time) bool {
+	return activity.Status != model.ActivityStatusCancelled &&
+		!now.Before(activity.StartTime) && !now.After(activity.EndTime.Add(SignOutGrace()))
+}
+
+// isLateSignIn 签到时间晚于活动开始时间超过 attendance.late_threshold 记为迟到
+func isLateSignIn(activity *model.Activity, signedInAt time.Time) bool {
+	return signedInAt.After(activity.StartTime.Add(config.GlobalConfig.Attendance.LateThreshold))
+}
+
+// isEarlyLeave 签退时间早于活动结束时间超过 attendance.early_leave_threshold 记为早退
+func isEarlyLeave(activity *model.Activity, signedOutAt time.Time) bool {
+	return signedOutAt.Before(activity.EndTime.Add(-config.GlobalConfig.Attendance.EarlyLeaveThreshold))
+}
+
+// attendedDuration 参与时长：签到到签退这段时间与活动时间的交集
+func attendedDuration(activity *model.Activity, signedInAt, signedOutAt time.Time) time.Duration {
+	from, to := signedInAt, signedOutAt
+	if from.Before(activity.StartTime) {
+		from = activity.StartTime
+	}
+	if to.After(activity.EndTime) {
+		to = activity.EndTime
+	}
+	if !to.After(from) {
+		return 0
+	}
+	return to.Sub(from)
+}
+
+// checkAttendanceCode 校验签到或签退动态码 (secretHex 为活动的动态码密钥)，错误时返回 invalid
+// 校验前检查 (活动, 手机号) 和 IP 是否已被锁定，错误达到阈值时返回 AttendanceLockedError
+func (s *registrationServiceImpl) checkAttendanceCode(ctx context.Context, activityID uint, phone, clientIP, secretHex, token string, now time.Time, invalid error) error {
+	if err := s.attendanceGuard.check(ctx, activityID, phone, clientIP); err != nil {
+		return err
+	}
+	secret, err := hex.DecodeString(secretHex)
+	if err != nil || len(secret) == 0 || !utils.ValidateTOTP(secret, token, now, SignInCodePeriod(), config.GlobalConfig.SignIn.CodeSkew) {
+		if err := s.attendanceGuard.recordFailure(ctx, activityID, phone, clientIP); err != nil {
+			return err
+		}
+		return invalid
+	}
+	if err := s.attendanceGuard.reset(ctx, activityID, phone); err != nil {
+		slog.Warn("清除动态码错误计数失败", "activity_id", activityID, "err", err)
+	}
+	return nil
+}
+
+// SignOut 参与者签退：校验签退动态码，记录签退时间、是否早退和参与时长
+// 只有已签到且尚未签退的报名可以签退
+func (s *registrationServiceImpl) SignOut(ctx context.Context, activityID uint, phone string, token string, clientIP string) error {
+	// 1. 检查活动是否在可签退的时间范围内
+	activity, err := s.activityRepo.FindByID(ctx, activityID)
+	if err != nil {
+		return ErrActivityNotFound
+	}
+	now := time.Now()
+	if !signOutOpen(activity, now) {
+		return ErrSignOutNotAvailable
+	}
+
+	// 校验大屏二维码中的签退动态码，允许少量时钟偏差；错误次数过多时临时锁定
+	if err := s.checkAttendanceCode(ctx, activityID, phone, clientIP, activity.SignOutSecret, token, now, ErrInvalidSignOutCode); err != nil {
+		return err
+	}
+
+	// 2. 查找报名记录
+	reg, err := s.registrationRepo.FindByActivityAndPhone(ctx, activityID, phone)
+	if err != nil {
+		return err
+	}
+	if reg == nil || reg.Status != model.RegistrationStatusConfirmed {
+		return ErrRegistrationNotFound
+	}
+
+	// 3. 必须先签到，且不能重复签退
+	if !reg.IsSignedIn || reg.SignedInAt == nil {
+		return ErrNotSignedIn
+	}
+	if reg.SignedOutAt != nil {
+		return ErrAlreadySignedOut
+	}
+
+	// 4. 记录签退时间并计算参与时长
+	attended := attendedDuration(activity, *reg.SignedInAt, now)
+	return s.registrationRepo.UpdateSignOut(ctx, reg.ID, now, isEarlyLeave(activity, now), int64(attended/time.Second))
+}
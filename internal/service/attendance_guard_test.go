@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/frozenf1sh/gostudent/internal/config"
+	"github.com/frozenf1sh/gostudent/internal/model"
+	"github.com/frozenf1sh/gostudent/internal/repository"
+	"github.com/frozenf1sh/gostudent/pkg/redis"
+	"github.com/frozenf1sh/gostudent/pkg/utils"
+	goredis "github.com/redis/go-redis/v9"
+	"gorm.io/gorm/clause"
+)
+
+// 同一活动同一手机号动态码连续错误达到阈值后临时锁定，正确的动态码也被拒绝；其他手机号不受影响
+func TestSignInCodeAttemptsLocked(t *testing.T) {
+	config.GlobalConfig = config.Config{}
+	config.GlobalConfig.SignIn.MaxFailures = 3
+	mr := miniredis.RunT(t)
+	redis.Client = goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	db := newTestDB(t)
+	s := NewRegistrationService(db, repository.NewActivityRepository(db), repository.NewRegistrationRepository(db),
+		repository.NewAuditLogRepository(db), repository.NewAllowlistRepository(db), repository.NewNotificationRepository(db), nil)
+	ctx := context.Background()
+
+	secret := []byte("0123456789abcdefghij")
+	now := time.Now()
+	activity := &model.Activity{
+		AdminID:              1,
+		Title:                "讲座",
+		Type:                 "讲座",
+		StartTime:            now.Add(-time.Hour),
+		EndTime:              now.Add(time.Hour),
+		Location:             "A101",
+		Status:               model.ActivityStatusClosed,
+		RegistrationDeadline: now.Add(-2 * time.Hour),
+		SignInSecret:         hex.EncodeToString(secret),
+	}
+	if err := db.Create(activity).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, phone := range []string{"13800138001", "13800138002"} {
+		reg := &model.Registration{ActivityID: activity.ID, ParticipantName: "参与者", ParticipantPhone: phone, ParticipantCollege: "计算机学院", Status: model.RegistrationStatusConfirmed}
+		if err := db.Omit(clause.Associations).Create(reg).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	code := utils.GenerateTOTP(secret, utils.TOTPCounter(time.Now(), SignInCodePeriod()))
+	wrong := "000000"
+	if wrong == code {
+		wrong = "111111"
+	}
+
+	// 错误两次后输入正确的动态码，计数清零
+	for range 2 {
+		if err := s.SignIn(ctx, activity.ID, "13800138001", wrong, "10.0.0.1"); !errors.Is(err, ErrInvalidSignInCode) {
+			t.Fatalf("SignIn with wrong code = %v, want ErrInvalidSignInCode", err)
+		}
+	}
+	if err := s.SignIn(ctx, activity.ID, "13800138001", code, "10.0.0.1"); err != nil {
+		t.Fatalf("SignIn with valid code = %v", err)
+	}
+
+	// 另一手机号连续错误达到阈值 (第三次错误即被锁定)，之后正确的动态码也被拒绝
+	for range 2 {
+		if err := s.SignIn(ctx, activity.ID, "13800138002", wrong, "10.0.0.2"); !errors.Is(err, ErrInvalidSignInCode) {
+			t.Fatalf("SignIn with wrong code = %v, want ErrInvalidSignInCode", err)
+		}
+	}
+	var locked *AttendanceLockedError
+	if err := s.SignIn(ctx, activity.ID, "13800138002", wrong, "10.0.0.2"); !errors.As(err, &locked) {
+		t.Fatalf("third wrong code = %v, want AttendanceLockedError", err)
+	}
+	if err := s.SignIn(ctx, activity.ID, "13800138002", code, "10.0.0.3"); !errors.As(err, &locked) || locked.RetryAfter <= 0 {
+		t.Fatalf("SignIn while locked = %v, want AttendanceLockedError", err)
+	}
+
+	// 锁定到期后可以正常签到
+	mr.FastForward(time.Hour)
+	if err := s.SignIn(ctx, activity.ID, "13800138002", code, "10.0.0.2"); err != nil {
+		t.Fatalf("SignIn after lockout expired = %v", err)
+	}
+}
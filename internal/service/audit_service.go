@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+
+	"github.com/frozenf1sh/gostudent/internal/model"
+	"github.com/frozenf1sh/gostudent/internal/repository"
+)
+
+// AuditActor 发起修改操作的管理员及请求信息，由认证中间件写入请求 Context
+type AuditActor struct {
+	AdminID   uint
+	IP        string
+	RequestID string
+}
+
+type auditActorKey struct{}
+
+// WithAuditActor 将操作者信息写入 Context
+func WithAuditActor(ctx context.Context, actor AuditActor) context.Context {
+	return context.WithValue(ctx, auditActorKey{}, actor)
+}
+
+// auditActorFromContext 读取操作者信息，没有时 (如系统任务) 返回零值
+func auditActorFromContext(ctx context.Context) AuditActor {
+	actor, _ := ctx.Value(auditActorKey{}).(AuditActor)
+	return actor
+}
+
+// AuditService 审计日志查询接口
+type AuditService interface {
+	ListAuditLogs(ctx context.Context, params *model.ListAuditLogsParams) ([]*model.AuditLog, int64, error)
+}
+
+type auditServiceImpl struct {
+	auditRepo repository.AuditLogRepository
+}
+
+// NewAuditService 创建 AuditService 实例
+func NewAuditService(repo repository.AuditLogRepository) AuditService {
+	return &auditServiceImpl{auditRepo: repo}
+}
+
+// ListAuditLogs 多条件查询审计日志
+func (s *auditServiceImpl) ListAuditLogs(ctx context.Context, params *model.ListAuditLogsParams) ([]*model.AuditLog, int64, error) {
+	return s.auditRepo.List(ctx, params)
+}
+
+// auditIgnoredFields 不参与差异比较的字段 (自动维护或关联对象)
+var auditIgnoredFields = map[string]bool{
+	"updated_at": true,
+	"admin":      true,
+}
+
+// auditSnapshot 将对象序列化为字段映射，用于记录修改前后的状态
+// 只包含 JSON 可见字段，密码哈希、密钥等 json:"-" 字段不会进入审计日志
+func auditSnapshot(v any) map[string]any {
+	if v == nil {
+		return nil
+	}
+	if m, ok := v.(map[string]any); ok {
+		return m
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil
+	}
+	return m
+}
+
+// auditDiff 比较前后两个快照，返回 {"字段": {"before": 旧值, "after": 新值}} 形式的 JSON
+func auditDiff(before, after map[string]any) string {
+	diff := make(map[string]map[string]any)
+	for key, oldValue := range before {
+		if auditIgnoredFields[key] {
+			continue
+		}
+		newValue, ok := after[key]
+		if !ok || !reflect.DeepEqual(oldValue, newValue) {
+			diff[key] = map[string]any{"before": oldValue, "after": newValue}
+		}
+	}
+	for key, newValue := range after {
+		if auditIgnoredFields[key] {
+			continue
+		}
+		if _, ok := before[key]; !ok {
+			diff[key] = map[string]any{"before": nil, "after": newValue}
+		}
+	}
+	data, err := json.Marshal(diff)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+// recordAudit 写入一条审计日志，before/after 为修改前后的快照 (auditSnapshot 的结果)
+// auditRepo 需绑定与数据修改相同的事务，确保两者同时提交或回滚
+func recordAudit(ctx context.Context, auditRepo repository.AuditLogRepository, action, targetType string, targetID uint, before, after map[string]any) error {
+	actor := auditActorFromContext(ctx)
+	return auditRepo.Create(ctx, &model.AuditLog{
+		AdminID:    actor.AdminID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Diff:       auditDiff(before, after),
+		IP:         actor.IP,
+		RequestID:  actor.RequestID,
+	})
+}
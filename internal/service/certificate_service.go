@@ -0,0 +1,320 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/frozenf1sh/gostudent/internal/config"
+	"github.com/frozenf1sh/gostudent/internal/model"
+	"github.com/frozenf1sh/gostudent/internal/repository"
+	"github.com/frozenf1sh/gostudent/pkg/pdf"
+	"github.com/frozenf1sh/gostudent/pkg/utils"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCertificateNotAvailable    = errors.New("certificates are only available for finished activities")
+	ErrCertificateNotFound        = errors.New("certificate not found")
+	ErrInvalidCertificateTemplate = errors.New("invalid certificate template")
+)
+
+// 未配置 certificate 时使用的默认模板
+const (
+	defaultCertificateTitle = "活动参与证明"
+	defaultCertificateBody  = "兹证明 {{.Name}}（{{.College}}）于 {{.Date}} 参加「{{.ActivityTitle}}」活动，参与时长 {{.Duration}}。"
+)
+
+// certificateCodeBytes 验证码的随机字节数，编码为 16 位十六进制大写字符
+const certificateCodeBytes = 8
+
+// CertificateFields 正文模板 (certificate.body) 可以使用的字段
+type CertificateFields struct {
+	Name          string // 参与者姓名
+	College       string // 参与者学院
+	ActivityTitle string // 活动名称
+	Date          string // 活动日期，如 2023年11月15日
+	Duration      string // 参与时长，如 1 小时 30 分钟
+	Code          string // 验证码
+}
+
+// CertificateService 活动参与证明的签发、导出和查验
+type CertificateService interface {
+	// 为已结束活动中已签到的报名签发证明 (已签发的不重复签发)，返回该活动的全部证明
+	IssueCertificates(ctx context.Context, activityID uint) ([]*model.Certificate, error)
+	// 将证明渲染为 PDF 并打包为 zip 写入 w
+	WriteCertificatesZip(w io.Writer, certificates []*model.Certificate) error
+	// 通过验证码查验证明
+	VerifyCertificate(ctx context.Context, code string) (*model.Certificate, error)
+}
+
+type certificateServiceImpl struct {
+	db               *gorm.DB // 用于事务
+	activityRepo     repository.ActivityRepository
+	registrationRepo repository.RegistrationRepository
+	certificateRepo  repository.CertificateRepository
+	auditRepo        repository.AuditLogRepository // 记录签发操作
+}
+
+// NewCertificateService 创建 CertificateService 实例
+func NewCertificateService(db *gorm.DB, activityRepo repository.ActivityRepository, registrationRepo repository.RegistrationRepository, certificateRepo repository.CertificateRepository, auditRepo repository.AuditLogRepository) CertificateService {
+	return &certificateServiceImpl{
+		db:               db,
+		activityRepo:     activityRepo,
+		registrationRepo: registrationRepo,
+		certificateRepo:  certificateRepo,
+		auditRepo:        auditRepo,
+	}
+}
+
+// IssueCertificates 签发证明：活动必须已结束，为已确认且已签到、尚未签发的报名生成证明并保存快照
+// 在活动行锁内执行，多个管理员同时导出时不会重复签发
+func (s *certificateServiceImpl) IssueCertificates(ctx context.Context, activityID uint) ([]*model.Certificate, error) {
+	var certificates []*model.Certificate
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		activity, err := s.activityRepo.WithTx(tx).FindByIDForUpdate(ctx, activityID)
+		if err != nil {
+			return ErrActivityNotFound
+		}
+		if activity.Status != model.ActivityStatusFinished {
+			return ErrCertificateNotAvailable
+		}
+
+		// 1. 已签发的证明
+		certificateRepo := s.certificateRepo.WithTx(tx)
+		certificates, err = certificateRepo.ListByActivityID(ctx, activityID)
+		if err != nil {
+			return err
+		}
+		issued := make(map[uint]bool, len(certificates))
+		for _, certificate := range certificates {
+			issued[certificate.RegistrationID] = true
+		}
+
+		// 2. 为新增的已签到报名签发
+		registrations, err := s.registrationRepo.WithTx(tx).ListAttended(ctx, activityID, 0)
+		if err != nil {
+			return err
+		}
+		var created []*model.Certificate
+		for _, reg := range registrations {
+			if issued[reg.ID] {
+				continue
+			}
+			code, err := utils.GenerateRandomToken(certificateCodeBytes)
+			if err != nil {
+				return err
+			}
+			created = append(created, &model.Certificate{
+				Code:               strings.ToUpper(code),
+				ActivityID:         activityID,
+				RegistrationID:     reg.ID,
+				ParticipantName:    reg.ParticipantName,
+				ParticipantCollege: reg.ParticipantCollege,
+				ActivityTitle:      activity.Title,
+				ActivityStartTime:  activity.StartTime,
+				AttendedSeconds:    int64(certificateDuration(activity, reg) / time.Second),
+			})
+		}
+		if len(created) == 0 {
+			return nil
+		}
+		if err := certificateRepo.CreateBatch(ctx, created); err != nil {
+			return err
+		}
+		certificates = append(certificates, created...)
+		return recordAudit(ctx, s.auditRepo.WithTx(tx), model.AuditActionCertificatesIssue, model.AuditTargetActivity, activityID, nil,
+			map[string]any{"issued": len(created), "total": len(certificates)})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return certificates, nil
+}
+
+// certificateDuration 证明上的参与时长：已签退的使用签退时记录的时长，未签退的按签到至活动结束计算
+func certificateDuration(activity *model.Activity, reg *model.Registration) time.Duration {
+	switch {
+	case reg.SignedOutAt != nil:
+		return time.Duration(reg.AttendedSeconds) * time.Second
+	case reg.SignedInAt != nil:
+		return attendedDuration(activity, *reg.SignedInAt, activity.EndTime)
+	default:
+		return 0
+	}
+}
+
+// WriteCertificatesZip 每张证明渲染为一个 PDF 文件，文件名为 报名ID_姓名.pdf
+func (s *certificateServiceImpl) WriteCertificatesZip(w io.Writer, certificates []*model.Certificate) error {
+	body, err := certificateBodyTemplate()
+	if err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	for _, certificate := range certificates {
+		f, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     fmt.Sprintf("%d_%s.pdf", certificate.RegistrationID, sanitizeFileName(certificate.ParticipantName)),
+			Method:   zip.Deflate,
+			Modified: certificate.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+		doc, err := renderCertificate(body, certificate)
+		if err != nil {
+			return err
+		}
+		if _, err := doc.WriteTo(f); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// VerifyCertificate 查验证明，验证码不区分大小写，忽略空格和连字符
+func (s *certificateServiceImpl) VerifyCertificate(ctx context.Context, code string) (*model.Certificate, error) {
+	code = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	if len(code) != certificateCodeBytes*2 {
+		return nil, ErrCertificateNotFound
+	}
+	certificate, err := s.certificateRepo.FindByCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if certificate == nil {
+		return nil, ErrCertificateNotFound
+	}
+	return certificate, nil
+}
+
+// certificateBodyTemplate 解析配置的正文模板
+func certificateBodyTemplate() (*template.Template, error) {
+	body := config.GlobalConfig.Certificate.Body
+	if body == "" {
+		body = defaultCertificateBody
+	}
+	tmpl, err := template.New("certificate").Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCertificateTemplate, err)
+	}
+	// 先用空字段试渲染一次，引用了不存在的字段时在开始输出 zip 之前报错
+	if err := tmpl.Execute(io.Discard, CertificateFields{}); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCertificateTemplate, err)
+	}
+	return tmpl, nil
+}
+
+// 证明版面 (A4 横向，单位 pt)
+const (
+	certificateMargin     = 110.0 // 正文左右边距
+	certificateBodySize   = 18.0  // 正文字号
+	certificateLineHeight = 34.0  // 正文行高
+)
+
+// renderCertificate 按模板渲染一张证明：标题、正文、落款、验证码和查验地址
+func renderCertificate(body *template.Template, certificate *model.Certificate) (*pdf.Document, error) {
+	var text strings.Builder
+	err := body.Execute(&text, CertificateFields{
+		Name:          certificate.ParticipantName,
+		College:       certificate.ParticipantCollege,
+		ActivityTitle: certificate.ActivityTitle,
+		Date:          certificate.ActivityStartTime.Format("2006年1月2日"),
+		Duration:      formatAttendedDuration(certificate.AttendedSeconds),
+		Code:          certificate.Code,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCertificateTemplate, err)
+	}
+
+	cfg := config.GlobalConfig.Certificate
+	title := cfg.Title
+	if title == "" {
+		title = defaultCertificateTitle
+	}
+
+	doc := pdf.New()
+	page := doc.AddPage(pdf.A4Height, pdf.A4Width)
+
+	// 边框
+	page.SetColor(0.55, 0.1, 0.1)
+	page.Rect(28, 28, page.Width-56, page.Height-56, 2)
+	page.Rect(36, 36, page.Width-72, page.Height-72, 0.6)
+
+	// 标题
+	page.TextCenter(page.Height-140, 36, title)
+
+	// 正文：每段首行缩进两个字，按版面宽度换行
+	page.SetColor(0.1, 0.1, 0.1)
+	y := page.Height - 230
+	for _, paragraph := range strings.Split(text.String(), "\n") {
+		for _, line := range wrapText("　　"+strings.TrimSpace(paragraph), certificateBodySize, page.Width-2*certificateMargin) {
+			page.Text(certificateMargin, y, certificateBodySize, line)
+			y -= certificateLineHeight
+		}
+	}
+
+	// 落款和签发日期 (右对齐)
+	right := page.Width - certificateMargin
+	if cfg.Issuer != "" {
+		page.Text(right-pdf.TextWidth(cfg.Issuer, 16), 170, 16, cfg.Issuer)
+	}
+	issuedAt := certificate.CreatedAt.Format("2006年1月2日")
+	page.Text(right-pdf.TextWidth(issuedAt, 16), 140, 16, issuedAt)
+
+	// 验证码和查验地址
+	page.SetColor(0.4, 0.4, 0.4)
+	page.Text(60, 70, 11, "验证码："+certificate.Code)
+	if cfg.VerifyURL != "" {
+		page.Text(60, 52, 11, "查验地址："+cfg.VerifyURL)
+	}
+	return doc, nil
+}
+
+// wrapText 按宽度把一段文本拆分为多行
+func wrapText(text string, size, width float64) []string {
+	var lines []string
+	var line []rune
+	for _, r := range text {
+		if len(line) > 0 && pdf.TextWidth(string(line)+string(r), size) > width {
+			lines = append(lines, string(line))
+			line = line[:0]
+		}
+		if len(line) == 0 && len(lines) > 0 && r == ' ' {
+			continue // 换行后行首的空格
+		}
+		line = append(line, r)
+	}
+	if len(line) > 0 {
+		lines = append(lines, string(line))
+	}
+	return lines
+}
+
+// formatAttendedDuration 将参与时长格式化为 "X 小时 Y 分钟"
+func formatAttendedDuration(seconds int64) string {
+	hours, minutes := seconds/3600, seconds%3600/60
+	switch {
+	case hours > 0 && minutes > 0:
+		return fmt.Sprintf("%d 小时 %d 分钟", hours, minutes)
+	case hours > 0:
+		return fmt.Sprintf("%d 小时", hours)
+	default:
+		return fmt.Sprintf("%d 分钟", minutes)
+	}
+}
+
+// sanitizeFileName 去掉文件名中的路径分隔符等特殊字符
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || r < 0x20 {
+			return '_'
+		}
+		return r
+	}, name)
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+
+	"github.com/frozenf1sh/gostudent/internal/model"
+	"github.com/frozenf1sh/gostudent/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCreditStudentRequired = errors.New("phone or student ID is required")
+	ErrInvalidCreditType     = errors.New("invalid credit type")
+	ErrInvalidCreditAmount   = errors.New("credit adjustment must be a non-zero amount between -1000 and 1000")
+	ErrCreditReasonRequired  = errors.New("credit adjustment requires a reason")
+)
+
+// CreditService 学分流水查询和手动调整
+type CreditService interface {
+	// 查询学生在各活动中获得的学分汇总及流水
+	GetStudentCredits(ctx context.Context, params *model.CreditQueryParams) (*model.CreditSummaryResponse, error)
+	// 手动调整学分 (追加一条调整流水并记录审计日志)
+	AdjustCredits(ctx context.Context, req *model.CreditAdjustmentRequest) (*model.CreditEntry, error)
+}
+
+type creditServiceImpl struct {
+	db         *gorm.DB // 用于事务
+	creditRepo repository.CreditRepository
+	auditRepo  repository.AuditLogRepository // 记录手动调整
+}
+
+// NewCreditService 创建 CreditService 实例
+func NewCreditService(db *gorm.DB, creditRepo repository.CreditRepository, auditRepo repository.AuditLogRepository) CreditService {
+	return &creditServiceImpl{
+		db:         db,
+		creditRepo: creditRepo,
+		auditRepo:  auditRepo,
+	}
+}
+
+// GetStudentCredits 按手机号或学号汇总学生的学分，两者都填时合并两者的流水
+func (s *creditServiceImpl) GetStudentCredits(ctx context.Context, params *model.CreditQueryParams) (*model.CreditSummaryResponse, error) {
+	phone, studentID := strings.TrimSpace(params.Phone), strings.TrimSpace(params.StudentID)
+	if phone == "" && studentID == "" {
+		return nil, ErrCreditStudentRequired
+	}
+
+	entries, err := s.creditRepo.ListByStudent(ctx, phone, studentID)
+	if err != nil {
+		return nil, err
+	}
+	totals := make(map[model.CreditType]float64)
+	for _, entry := range entries {
+		totals[entry.CreditType] += entry.Amount
+	}
+	for creditType, total := range totals {
+		totals[creditType] = roundCredit(total)
+	}
+
+	return &model.CreditSummaryResponse{
+		Phone:     phone,
+		StudentID: studentID,
+		Totals:    totals,
+		Entries:   entries,
+	}, nil
+}
+
+// AdjustCredits 手动调整学分：流水只追加，扣减学分时追加负数流水
+func (s *creditServiceImpl) AdjustCredits(ctx context.Context, req *model.CreditAdjustmentRequest) (*model.CreditEntry, error) {
+	phone, studentID := strings.TrimSpace(req.Phone), strings.TrimSpace(req.StudentID)
+	if phone == "" && studentID == "" {
+		return nil, ErrCreditStudentRequired
+	}
+	if !model.IsValidCreditType(req.CreditType) {
+		return nil, ErrInvalidCreditType
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, ErrCreditReasonRequired
+	}
+	amount := roundCredit(req.Amount)
+	if math.IsNaN(req.Amount) || amount == 0 || math.Abs(amount) > maxCreditValue {
+		return nil, ErrInvalidCreditAmount
+	}
+
+	entry := &model.CreditEntry{
+		Phone:           phone,
+		StudentID:       studentID,
+		ParticipantName: strings.TrimSpace(req.ParticipantName),
+		CreditType:      req.CreditType,
+		Amount:          amount,
+		Source:          model.CreditSourceAdjustment,
+		Reason:          reason,
+		AdminID:         auditActorFromContext(ctx).AdminID,
+	}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := s.creditRepo.WithTx(tx).Create(ctx, entry); err != nil {
+			return err
+		}
+		return recordAudit(ctx, s.auditRepo.WithTx(tx), model.AuditActionCreditAdjust, model.AuditTargetCredit, entry.ID, nil, auditSnapshot(entry))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
@@ -0,0 +1,468 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/frozenf1sh/gostudent/internal/config"
+	"github.com/frozenf1sh/gostudent/internal/model"
+	"github.com/frozenf1sh/gostudent/internal/repository"
+	"github.com/frozenf1sh/gostudent/pkg/redis"
+	"github.com/frozenf1sh/gostudent/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// FastRegistrationService 快速报名：热门活动的名额预加载到 Redis，
+// 报名请求用 Lua 脚本原子预占名额后立即返回，由后台任务批量写入 MySQL，避免所有请求排队等待活动行锁。
+//
+// Redis 中的剩余名额 = 人数上限 - 已落库的已确认人数 - 待落库的预占数。
+// 数据库通道 (名额已满转候补、取消报名、修改人数上限等) 提交后通过 SeatOccupied / SeatReleased / AdjustSeats 同步增量，
+// 校对任务定期用数据库数据重置 Redis 并修复 registered_count 的漂移。
+type FastRegistrationService interface {
+	// Reserve 尝试走快速通道报名；handled 为 false 时调用方应继续走数据库通道
+	// (活动未开启快速报名、需要审核、设置了学院名额、名额已满需要候补、名额尚未加载到 Redis)
+	Reserve(ctx context.Context, activityID uint, req *model.CreateRegistrationRequest) (reg *model.Registration, handled bool, err error)
+	// FillPendingSeats 在数据库通道判断空余名额前，读取快速通道已预占但尚未落库的名额数
+	FillPendingSeats(ctx context.Context, activity *model.Activity) error
+	// SeatOccupied 数据库通道报名提交后同步到 Redis：记录手机号并扣减 seats 个名额 (候补为 0)
+	SeatOccupied(ctx context.Context, activity *model.Activity, phone string, seats int)
+	// SeatReleased 取消报名提交后同步到 Redis：移除手机号并归还 seats 个名额 (已被候补递补的不归还)
+	SeatReleased(ctx context.Context, activity *model.Activity, phone string, seats int)
+	// AdjustSeats 修改人数上限等操作提交后调整剩余名额
+	AdjustSeats(ctx context.Context, activity *model.Activity, delta int)
+	// Sync 用数据库数据校对活动的 Redis 名额：报名中的活动重新加载，其余状态清除
+	Sync(ctx context.Context, activityID uint) error
+	// StartWorkers 启动落库任务和校对任务 (建议在 main.go 初始化时调用)
+	StartWorkers(ctx context.Context)
+}
+
+// fastWorkerLease 落库实例的租约时长，每三分之一租约续期一次；
+// 租约过期的实例视为已退出，其处理中的预占记录由其他实例放回队列
+const fastWorkerLease = 30 * time.Second
+
+// fastReservation 快速通道的预占记录，序列化后进入 Redis 落库队列
+type fastReservation struct {
+	ActivityID      uint                            `json:"activity_id"`
+	Request         model.CreateRegistrationRequest `json:"request"`
+	ManageTokenHash string                          `json:"manage_token_hash"`
+	RegisteredAt    time.Time                       `json:"registered_at"`
+}
+
+type fastRegistrationServiceImpl struct {
+	db                *gorm.DB
+	activityRepo      repository.ActivityRepository
+	registrationRepo  repository.RegistrationRepository
+	allowlistRepo     repository.AllowlistRepository
+	notificationRepo  repository.NotificationRepository // 落库时通知报名者
+	workerID          string                            // 本实例的落库实例ID，StartWorkers 时生成
+	batchSize         int
+	pollTimeout       time.Duration
+	reconcileInterval time.Duration
+}
+
+// NewFastRegistrationService 创建 FastRegistrationService 实例，未配置的项使用默认值
+func NewFastRegistrationService(db *gorm.DB, aRepo repository.ActivityRepository, rRepo repository.RegistrationRepository, allowlistRepo repository.AllowlistRepository, notificationRepo repository.NotificationRepository) FastRegistrationService {
+	cfg := config.GlobalConfig.FastRegistration
+	s := &fastRegistrationServiceImpl{
+		db:                db,
+		activityRepo:      aRepo,
+		registrationRepo:  rRepo,
+		allowlistRepo:     allowlistRepo,
+		notificationRepo:  notificationRepo,
+		batchSize:         cfg.BatchSize,
+		pollTimeout:       cfg.PollTimeout,
+		reconcileInterval: cfg.ReconcileInterval,
+	}
+	if s.batchSize <= 0 {
+		s.batchSize = 50
+	}
+	if s.pollTimeout <= 0 {
+		s.pollTimeout = time.Second
+	}
+	if s.reconcileInterval <= 0 {
+		s.reconcileInterval = time.Minute
+	}
+	return s
+}
+
+// Reserve 快速通道报名：不加数据库锁，在 Redis 中原子地校验重复报名并扣减名额
+func (s *fastRegistrationServiceImpl) Reserve(ctx context.Context, activityID uint, req *model.CreateRegistrationRequest) (*model.Registration, bool, error) {
+	// 1. 只读查询活动，未开启快速报名或活动不存在时交给数据库通道处理
+	// 需要审核和抽签的报名不占用名额；学院名额需要按学院统计已确认人数，同样交给数据库通道在活动行锁内处理
+	activity, err := s.activityRepo.FindByID(ctx, activityID)
+	if err != nil || !activity.FastRegistration || activity.RequiresApproval || activity.Lottery || hasCollegeQuotas(activity) {
+		return nil, false, nil
+	}
+
+	// 2. 状态和时间校验
+	if activity.Status != model.ActivityStatusPublished {
+		return nil, true, ErrRegistrationNotOpen
+	}
+	now := time.Now()
+	if activity.RegistrationStateAt(now) == model.RegistrationStateUpcoming {
+		return nil, true, ErrRegistrationNotOpenYet
+	}
+	if now.After(activity.RegistrationDeadline) {
+		return nil, true, ErrActivityRegistrationOver
+	}
+
+	// 3. 校验报名表答案，队列中保存规范化后的答案
+	answers, err := validateFormAnswers(activity.FormSchema, req.Answers)
+	if err != nil {
+		return nil, true, err
+	}
+	request := *req
+	request.Answers = answers
+
+	// 4. 报名资格校验：学院和报名名单
+	if err := checkEligibility(ctx, s.allowlistRepo, activity, req); err != nil {
+		return nil, true, err
+	}
+
+	// 5. 生成自助管理凭证，预占记录只携带哈希
+	manageToken, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return nil, true, err
+	}
+	reservation := fastReservation{
+		ActivityID:      activityID,
+		Request:         request,
+		ManageTokenHash: utils.HashToken(manageToken),
+		RegisteredAt:    now,
+	}
+	payload, err := json.Marshal(reservation)
+	if err != nil {
+		return nil, true, err
+	}
+
+	// 6. 原子预占名额
+	result, err := redis.ReserveSeat(ctx, activityID, req.ParticipantPhone, string(payload))
+	if err != nil {
+		return nil, true, err
+	}
+	switch result {
+	case redis.SeatReserved:
+		// 记录尚未落库，ID 为 0
+		return &model.Registration{
+			ActivityID:           activityID,
+			ParticipantName:      req.ParticipantName,
+			ParticipantPhone:     req.ParticipantPhone,
+			ParticipantCollege:   strings.TrimSpace(req.ParticipantCollege),
+			ParticipantStudentID: strings.TrimSpace(req.ParticipantStudentID),
+			ParticipantEmail:     strings.TrimSpace(req.ParticipantEmail),
+			Answers:              answers,
+			RegisteredAt:         now,
+			Status:               model.RegistrationStatusConfirmed,
+			ManageTokenHash:      reservation.ManageTokenHash,
+			ManageToken:          manageToken,
+		}, true, nil
+	case redis.SeatDuplicate:
+		return nil, true, ErrRegistrationDuplicate
+	default:
+		// 名额已满 (由数据库通道加入候补) 或名额尚未加载 (等待校对任务加载)
+		return nil, false, nil
+	}
+}
+
+// FillPendingSeats 读取待落库的预占数，hasFreeSeat 会把它计入已占用名额
+func (s *fastRegistrationServiceImpl) FillPendingSeats(ctx context.Context, activity *model.Activity) error {
+	if !activity.FastRegistration {
+		return nil
+	}
+	pending, err := redis.PendingSeats(ctx, activity.ID)
+	if err != nil {
+		return err
+	}
+	activity.PendingSeats = pending
+	return nil
+}
+
+// SeatOccupied 同步失败只记录日志，由校对任务修复
+func (s *fastRegistrationServiceImpl) SeatOccupied(ctx context.Context, activity *model.Activity, phone string, seats int) {
+	if !activity.FastRegistration {
+		return
+	}
+	if err := redis.OccupySeat(ctx, activity.ID, phone, seats); err != nil {
+		slog.Warn("快速报名名额同步失败", "activity_id", activity.ID, "err", err)
+	}
+}
+
+// SeatReleased 同步失败只记录日志，由校对任务修复
+func (s *fastRegistrationServiceImpl) SeatReleased(ctx context.Context, activity *model.Activity, phone string, seats int) {
+	if !activity.FastRegistration {
+		return
+	}
+	if err := redis.ReleaseSeat(ctx, activity.ID, phone, seats); err != nil {
+		slog.Warn("快速报名名额同步失败", "activity_id", activity.ID, "err", err)
+	}
+}
+
+// AdjustSeats 同步失败只记录日志，由校对任务修复
+func (s *fastRegistrationServiceImpl) AdjustSeats(ctx context.Context, activity *model.Activity, delta int) {
+	if !activity.FastRegistration || delta == 0 {
+		return
+	}
+	if err := redis.AdjustSeats(ctx, activity.ID, delta); err != nil {
+		slog.Warn("快速报名名额同步失败", "activity_id", activity.ID, "err", err)
+	}
+}
+
+// Sync 校对活动的 Redis 名额
+// 先读取预占序号再读取数据库，期间有新的预占、落库或同步时放弃写入 (返回 redis.ErrSeatsBusy)，避免用旧数据覆盖
+func (s *fastRegistrationServiceImpl) Sync(ctx context.Context, activityID uint) error {
+	seq, err := redis.SeatSequence(ctx, activityID)
+	if err != nil {
+		return err
+	}
+
+	var (
+		activity *model.Activity
+		phones   []string
+		load     bool
+	)
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		activity, err = s.activityRepo.WithTx(tx).FindByIDForUpdate(ctx, activityID)
+		if err != nil {
+			return err
+		}
+		if !activity.FastRegistration {
+			return nil
+		}
+
+		// 修复已报名人数的漂移：以已确认的报名记录数为准
+		confirmed, err := s.registrationRepo.WithTx(tx).CountConfirmed(ctx, activityID)
+		if err != nil {
+			return err
+		}
+		if int(confirmed) != activity.RegisteredCount {
+			slog.Warn("修复活动已报名人数", "activity_id", activityID, "registered_count", activity.RegisteredCount, "confirmed", confirmed)
+			activity.RegisteredCount = int(confirmed)
+			if err := s.activityRepo.WithTx(tx).Update(ctx, activity); err != nil {
+				return err
+			}
+		}
+
+		if activity.Status != model.ActivityStatusPublished {
+			return nil
+		}
+		load = true
+		phones, err = s.registrationRepo.WithTx(tx).ListActivePhones(ctx, activityID)
+		return err
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return redis.ClearSeats(ctx, activityID)
+	}
+	if err != nil {
+		return err
+	}
+	if !load {
+		return redis.ClearSeats(ctx, activityID)
+	}
+
+	remaining := redis.UnlimitedSeats
+	if activity.MaxParticipants > 0 {
+		remaining = max(activity.MaxParticipants-activity.RegisteredCount, 0)
+	}
+	// 名额键保留到活动结束后一天，之后由状态流转清除或自然过期
+	ttl := max(time.Until(activity.EndTime)+24*time.Hour, time.Hour)
+	return redis.ResyncSeats(ctx, activityID, seq, remaining, phones, ttl)
+}
+
+// StartWorkers 启动落库任务、租约续期任务和校对任务
+// 每个实例使用自己的处理中列表，启动时和每次校对时把已退出实例 (租约过期) 未处理完的预占记录放回队列，
+// 多实例部署时不会放回仍在运行的实例正在处理的记录
+func (s *fastRegistrationServiceImpl) StartWorkers(ctx context.Context) {
+	workerID, err := utils.GenerateRandomToken(8)
+	if err != nil {
+		panic(err)
+	}
+	s.workerID = workerID
+	if err := redis.RegisterSeatWorker(ctx, s.workerID, fastWorkerLease); err != nil {
+		slog.Error("注册快速报名落库实例失败，将在续期时重试", "worker", s.workerID, "err", err)
+	}
+	s.requeueExpiredWorkers(ctx)
+
+	go func() {
+		ticker := time.NewTicker(fastWorkerLease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				renewed, err := redis.RenewSeatWorker(ctx, s.workerID, fastWorkerLease)
+				if err != nil {
+					slog.Error("快速报名落库实例续期失败", "worker", s.workerID, "err", err)
+				} else if !renewed {
+					// 处理中的记录可能已被其他实例放回队列，重复落库时按管理凭证识别并跳过
+					slog.Warn("快速报名落库实例的租约曾过期，已重新注册", "worker", s.workerID)
+				}
+			}
+		}
+	}()
+
+	slog.Info("快速报名落库任务已启动")
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				slog.Info("快速报名落库任务已停止")
+				return
+			default:
+			}
+			items, err := redis.TakeSeatReservations(ctx, s.workerID, s.batchSize, s.pollTimeout)
+			if err != nil {
+				if ctx.Err() == nil {
+					slog.Error("读取快速报名队列失败", "err", err)
+					time.Sleep(s.pollTimeout)
+				}
+			}
+			if len(items) > 0 {
+				s.persistReservations(ctx, items)
+			}
+		}
+	}()
+
+	slog.Info("快速报名名额校对任务已启动")
+	go func() {
+		ticker := time.NewTicker(s.reconcileInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				slog.Info("快速报名名额校对任务已停止")
+				return
+			case <-ticker.C:
+				s.reconcile(ctx)
+			}
+		}
+	}()
+}
+
+// requeueExpiredWorkers 把已退出实例未处理完的预占记录放回队列
+func (s *fastRegistrationServiceImpl) requeueExpiredWorkers(ctx context.Context) {
+	if n, err := redis.RequeueExpiredSeatWorkers(ctx); err != nil {
+		slog.Error("恢复未落库的快速报名记录失败", "err", err)
+	} else if n > 0 {
+		slog.Info("已恢复未落库的快速报名记录", "count", n)
+	}
+}
+
+// reconcile 放回已退出实例的预占记录，并校对所有报名中的快速报名活动
+func (s *fastRegistrationServiceImpl) reconcile(ctx context.Context) {
+	s.requeueExpiredWorkers(ctx)
+
+	activities, err := s.activityRepo.ListFastRegistrationOpen(ctx)
+	if err != nil {
+		slog.Error("快速报名名额校对失败", "err", err)
+		return
+	}
+	for _, activity := range activities {
+		err := s.Sync(ctx, activity.ID)
+		if errors.Is(err, redis.ErrSeatsBusy) {
+			slog.Debug("快速报名名额正在变化，跳过本次校对", "activity_id", activity.ID)
+			continue
+		}
+		if err != nil {
+			slog.Error("快速报名名额校对失败", "activity_id", activity.ID, "err", err)
+		}
+	}
+}
+
+// persistReservations 按活动分组，每个活动一个事务批量落库
+func (s *fastRegistrationServiceImpl) persistReservations(ctx context.Context, items []string) {
+	groups := make(map[uint][]string)
+	reservations := make(map[uint][]fastReservation)
+	var order []uint
+	for _, item := range items {
+		var r fastReservation
+		if err := json.Unmarshal([]byte(item), &r); err != nil {
+			slog.Error("丢弃无法解析的快速报名记录", "item", item, "err", err)
+			if err := redis.DiscardSeatReservation(ctx, s.workerID, item); err != nil {
+				slog.Error("删除快速报名记录失败", "err", err)
+			}
+			continue
+		}
+		if _, ok := groups[r.ActivityID]; !ok {
+			order = append(order, r.ActivityID)
+		}
+		groups[r.ActivityID] = append(groups[r.ActivityID], item)
+		reservations[r.ActivityID] = append(reservations[r.ActivityID], r)
+	}
+
+	for _, activityID := range order {
+		released, err := s.persistActivityReservations(ctx, activityID, reservations[activityID])
+		if err != nil {
+			slog.Error("快速报名记录落库失败，稍后重试", "activity_id", activityID, "count", len(groups[activityID]), "err", err)
+			if err := redis.RetrySeatReservations(ctx, s.workerID, groups[activityID]); err != nil {
+				slog.Error("快速报名记录放回队列失败", "activity_id", activityID, "err", err)
+			}
+			continue
+		}
+		if err := redis.FinishSeatReservations(ctx, s.workerID, activityID, groups[activityID]); err != nil {
+			slog.Error("快速报名落库状态更新失败", "activity_id", activityID, "err", err)
+		}
+		if released > 0 {
+			if err := redis.AdjustSeats(ctx, activityID, released); err != nil {
+				slog.Warn("快速报名名额同步失败", "activity_id", activityID, "err", err)
+			}
+		}
+	}
+}
+
+// persistActivityReservations 在活动行锁内写入一批预占记录，返回因冲突未写入而需要归还的名额数
+// 名额已在 Redis 中扣减，这里不再校验人数上限
+func (s *fastRegistrationServiceImpl) persistActivityReservations(ctx context.Context, activityID uint, reservations []fastReservation) (int, error) {
+	var released int
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		activity, err := s.activityRepo.WithTx(tx).FindByIDForUpdate(ctx, activityID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil // 活动已删除，预占记录直接丢弃
+		}
+		if err != nil {
+			return err
+		}
+
+		persisted := 0
+		for _, r := range reservations {
+			existing, err := s.registrationRepo.WithTx(tx).FindByActivityAndPhone(ctx, activityID, r.Request.ParticipantPhone)
+			if err != nil {
+				return err
+			}
+			if existing != nil && existing.Status != model.RegistrationStatusCancelled {
+				// 凭证相同说明是进程重启后重放的已落库记录；否则手机号已通过数据库通道报名，归还名额
+				if existing.ManageTokenHash != r.ManageTokenHash {
+					slog.Warn("快速报名记录与已有报名冲突", "activity_id", activityID, "phone", r.Request.ParticipantPhone)
+					released++
+				}
+				continue
+			}
+
+			registration := prepareRegistration(existing, activityID, &r.Request, r.RegisteredAt)
+			registration.ManageTokenHash = r.ManageTokenHash
+			if registration.ID == 0 {
+				err = s.registrationRepo.WithTx(tx).Create(ctx, registration)
+			} else {
+				err = s.registrationRepo.WithTx(tx).Update(ctx, registration)
+			}
+			if err != nil {
+				return err
+			}
+			if err := enqueueNotifications(ctx, s.notificationRepo.WithTx(tx), model.NotificationEventRegistered, activity, registration); err != nil {
+				return err
+			}
+			persisted++
+		}
+
+		if persisted == 0 {
+			return nil
+		}
+		activity.RegisteredCount += persisted
+		return s.activityRepo.WithTx(tx).Update(ctx, activity)
+	})
+	return released, err
+}
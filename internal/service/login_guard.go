@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/frozenf1sh/gostudent/internal/config"
+	"github.com/frozenf1sh/gostudent/pkg/redis"
+)
+
+// LoginLockedError 登录因连续失败被临时锁定
+type LoginLockedError struct {
+	RetryAfter time.Duration // 距离解锁的剩余时长
+}
+
+func (e *LoginLockedError) Error() string {
+	return fmt.Sprintf("login temporarily locked, retry after %s", e.RetryAfter)
+}
+
+// 用户名在所有 IP 上的失败次数达到阈值后，每次登录前的延迟 (不锁定，避免他人恶意锁定账号)
+const (
+	loginSoftDelayBase = 250 * time.Millisecond
+	loginSoftDelayMax  = 3 * time.Second
+)
+
+// lockoutPolicy 失败计数与临时锁定的参数，登录和签到动态码 (见 attendance_guard.go) 共用
+// 计数和锁定标记保存在 Redis 中，各主体 (subject) 通过前缀区分
+type lockoutPolicy struct {
+	failureWindow time.Duration // 失败计数的保留时长
+	baseLockout   time.Duration // 首次锁定时长，之后每多失败一次翻倍
+	maxLockout    time.Duration // 最长锁定时长
+}
+
+// withDefaults 未配置的项使用默认值
+func (p lockoutPolicy) withDefaults() lockoutPolicy {
+	if p.failureWindow <= 0 {
+		p.failureWindow = time.Hour
+	}
+	if p.baseLockout <= 0 {
+		p.baseLockout = time.Minute
+	}
+	if p.maxLockout < p.baseLockout {
+		p.maxLockout = max(time.Hour, p.baseLockout)
+	}
+	return p
+}
+
+// lockoutSubject 参与锁定的主体及其失败次数阈值
+type lockoutSubject struct {
+	subject string
+	limit   int
+}
+
+// recordFailure 累加各主体的失败次数，达到阈值的主体按指数退避锁定，返回其中最长的锁定时长 (未锁定时为 0)
+func (p lockoutPolicy) recordFailure(ctx context.Context, subjects ...lockoutSubject) (time.Duration, error) {
+	var lockout time.Duration
+	for _, item := range subjects {
+		failures, err := redis.RecordLoginFailure(ctx, item.subject, p.failureWindow)
+		if err != nil {
+			return 0, err
+		}
+		if failures < int64(item.limit) {
+			continue
+		}
+		d := p.lockoutFor(failures - int64(item.limit))
+		if err := redis.LockLogin(ctx, item.subject, d); err != nil {
+			return 0, err
+		}
+		lockout = max(lockout, d)
+	}
+	return lockout, nil
+}
+
+// lockoutFor 计算锁定时长：首次为 baseLockout，之后每多失败一次翻倍，不超过 maxLockout
+func (p lockoutPolicy) lockoutFor(extraFailures int64) time.Duration {
+	d := p.baseLockout
+	for i := int64(0); i < extraFailures && d < p.maxLockout; i++ {
+		d *= 2
+	}
+	return min(d, p.maxLockout)
+}
+
+// loginGuard 按 (用户名, IP) 和 IP 统计登录失败次数，超过阈值后按指数退避临时锁定；
+// 用户名在所有 IP 上的失败次数只用于增加登录延迟，从其他 IP 发起的失败不会锁定该账号
+type loginGuard struct {
+	lockoutPolicy
+	userMaxFailures int
+	ipMaxFailures   int
+}
+
+// newLoginGuard 读取配置，未配置的项使用默认值
+func newLoginGuard() *loginGuard {
+	cfg := config.GlobalConfig.LoginProtection
+	g := &loginGuard{
+		lockoutPolicy: lockoutPolicy{
+			failureWindow: cfg.FailureWindow,
+			baseLockout:   cfg.BaseLockout,
+			maxLockout:    cfg.MaxLockout,
+		}.withDefaults(),
+		userMaxFailures: cfg.UserMaxFailures,
+		ipMaxFailures:   cfg.IPMaxFailures,
+	}
+	if g.userMaxFailures <= 0 {
+		g.userMaxFailures = 5
+	}
+	if g.ipMaxFailures <= 0 {
+		g.ipMaxFailures = 20
+	}
+	return g
+}
+
+func loginUserSubject(username string) string       { return "user:" + username }
+func loginUserIPSubject(username, ip string) string { return "user_ip:" + username + "|" + ip }
+func loginIPSubject(ip string) string               { return "ip:" + ip }
+
+// check 在校验密码前检查 (用户名, IP) 和 IP 是否处于锁定期，避免被锁定的请求消耗 bcrypt 计算；
+// 用户名在所有 IP 上失败过多时按 softDelay 等待后再继续
+func (g *loginGuard) check(ctx context.Context, username, ip string) error {
+	remaining, err := redis.LoginLockRemaining(ctx, loginUserIPSubject(username, ip), loginIPSubject(ip))
+	if err != nil {
+		return err
+	}
+	if remaining > 0 {
+		return &LoginLockedError{RetryAfter: remaining}
+	}
+
+	failures, err := redis.LoginFailures(ctx, loginUserSubject(username))
+	if err != nil {
+		return err
+	}
+	if delay := g.softDelay(failures); delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return nil
+}
+
+// recordFailure 记录一次失败，(用户名, IP) 或 IP 达到阈值时锁定并返回 LoginLockedError
+func (g *loginGuard) recordFailure(ctx context.Context, username, ip string) error {
+	if _, err := redis.RecordLoginFailure(ctx, loginUserSubject(username), g.failureWindow); err != nil {
+		return err
+	}
+
+	lockout, err := g.lockoutPolicy.recordFailure(ctx,
+		lockoutSubject{loginUserIPSubject(username, ip), g.userMaxFailures},
+		lockoutSubject{loginIPSubject(ip), g.ipMaxFailures},
+	)
+	if err != nil {
+		return err
+	}
+	if lockout > 0 {
+		return &LoginLockedError{RetryAfter: lockout}
+	}
+	return nil
+}
+
+// softDelay 计算用户名在所有 IP 上的失败次数对应的登录延迟：达到阈值后从 loginSoftDelayBase 开始翻倍，不超过 loginSoftDelayMax
+func (g *loginGuard) softDelay(failures int64) time.Duration {
+	if failures < int64(g.userMaxFailures) {
+		return 0
+	}
+	d := loginSoftDelayBase
+	for i := int64(g.userMaxFailures); i < failures && d < loginSoftDelayMax; i++ {
+		d *= 2
+	}
+	return min(d, loginSoftDelayMax)
+}
+
+// reset 登录成功后清除用户名及 (用户名, IP) 的失败计数 (IP 计数自然过期，避免用自己的账号为 IP 解锁)
+func (g *loginGuard) reset(ctx context.Context, username, ip string) error {
+	return redis.ClearLoginFailures(ctx, loginUserSubject(username), loginUserIPSubject(username, ip))
+}
@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/frozenf1sh/gostudent/internal/config"
+	"github.com/frozenf1sh/gostudent/internal/model"
+	"github.com/frozenf1sh/gostudent/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotificationNotFound  = errors.New("notification not found")
+	ErrNotificationNotFailed = errors.New("only failed notifications can be retried")
+)
+
+// 通知发送的默认参数，配置项未填写时使用
+const (
+	defaultNotificationPollInterval = 10 * time.Second
+	defaultNotificationBatchSize    = 20
+	defaultNotificationMaxAttempts  = 5
+	defaultNotificationRetryBackoff = time.Minute
+	maxNotificationRetryBackoff     = time.Hour
+	notificationSendTimeout         = 30 * time.Second // 单条通知的发送超时
+)
+
+// notificationChannels 生成通知时使用的渠道，每个渠道从报名记录中取收件人 (见 notificationRecipient)
+var notificationChannels = []model.NotificationChannel{model.NotificationChannelEmail}
+
+// notifiedStatuses 活动变更和取消时需要通知的报名状态
+var notifiedStatuses = []model.RegistrationStatus{
+	model.RegistrationStatusConfirmed,
+	model.RegistrationStatusWaitlisted,
+	model.RegistrationStatusPending,
+	model.RegistrationStatusApplied,
+}
+
+// NotificationSender 一个通知渠道的发送实现，新增渠道 (如短信) 时实现该接口并在 main.go 中注册
+type NotificationSender interface {
+	Send(ctx context.Context, recipient, subject, body string) error
+}
+
+// NotificationService 通知队列的发送任务和管理
+// 通知由各业务在事务内通过 enqueueNotifications 写入队列，发送失败不影响业务本身
+type NotificationService interface {
+	// StartWorker 启动发送任务，按 notification.poll_interval 发送到期的通知 (建议在 main.go 初始化时调用)
+	StartWorker(ctx context.Context)
+	// ListNotifications 多条件查询通知
+	ListNotifications(ctx context.Context, params *model.ListNotificationsParams) ([]*model.Notification, int64, error)
+	// RetryNotification 将发送失败的通知重新放回队列
+	RetryNotification(ctx context.Context, id uint) (*model.Notification, error)
+}
+
+type notificationServiceImpl struct {
+	db               *gorm.DB // 用于事务
+	notificationRepo repository.NotificationRepository
+	auditRepo        repository.AuditLogRepository // 记录手动重试
+	senders          map[model.NotificationChannel]NotificationSender
+
+	pollInterval time.Duration
+	batchSize    int
+	maxAttempts  int
+	retryBackoff time.Duration
+}
+
+// NewNotificationService 创建 NotificationService 实例，senders 为各渠道的发送实现
+func NewNotificationService(db *gorm.DB, notificationRepo repository.NotificationRepository, auditRepo repository.AuditLogRepository, senders map[model.NotificationChannel]NotificationSender) NotificationService {
+	cfg := config.GlobalConfig.Notification
+	s := &notificationServiceImpl{
+		db:               db,
+		notificationRepo: notificationRepo,
+		auditRepo:        auditRepo,
+		senders:          senders,
+		pollInterval:     cfg.PollInterval,
+		batchSize:        cfg.BatchSize,
+		maxAttempts:      cfg.MaxAttempts,
+		retryBackoff:     cfg.RetryBackoff,
+	}
+	if s.pollInterval <= 0 {
+		s.pollInterval = defaultNotificationPollInterval
+	}
+	if s.batchSize <= 0 {
+		s.batchSize = defaultNotificationBatchSize
+	}
+	if s.maxAttempts <= 0 {
+		s.maxAttempts = defaultNotificationMaxAttempts
+	}
+	if s.retryBackoff <= 0 {
+		s.retryBackoff = defaultNotificationRetryBackoff
+	}
+	return s
+}
+
+// StartWorker 启动通知发送任务
+func (s *notificationServiceImpl) StartWorker(ctx context.Context) {
+	slog.Info("通知发送任务已启动")
+	go func() {
+		ticker := time.NewTicker(s.pollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				slog.Info("通知发送任务已停止")
+				return
+			case <-ticker.C:
+				s.deliverDue(ctx)
+			}
+		}
+	}()
+}
+
+// deliverDue 逐批领取并发送到期的通知，直到队列中没有到期的通知
+// 领取时推迟下次发送时间 (租期按整批最长发送时间计算)，多个实例不会重复发送；
+// 发送后未能保存结果 (如进程退出) 的通知会在租期结束后重新发送
+func (s *notificationServiceImpl) deliverDue(ctx context.Context) {
+	lease := time.Duration(s.batchSize)*notificationSendTimeout + time.Minute
+	for ctx.Err() == nil {
+		notifications, err := s.notificationRepo.ClaimDue(ctx, time.Now(), lease, s.batchSize)
+		if err != nil {
+			slog.Error("领取待发送通知失败", "err", err)
+			return
+		}
+		for _, notification := range notifications {
+			s.deliver(ctx, notification)
+		}
+		if len(notifications) < s.batchSize {
+			return
+		}
+	}
+}
+
+// deliver 发送一条通知并保存结果，失败时按指数退避安排重试，尝试次数用尽后标记为失败
+func (s *notificationServiceImpl) deliver(ctx context.Context, notification *model.Notification) {
+	var err error
+	if sender, ok := s.senders[notification.Channel]; ok {
+		sendCtx, cancel := context.WithTimeout(ctx, notificationSendTimeout)
+		err = sender.Send(sendCtx, notification.Recipient, notification.Subject, notification.Body)
+		cancel()
+	} else {
+		err = fmt.Errorf("notification channel %s is not configured", notification.Channel)
+	}
+
+	now := time.Now()
+	notification.Attempts++
+	switch {
+	case err == nil:
+		notification.Status = model.NotificationStatusSent
+		notification.SentAt = &now
+		notification.LastError = ""
+	case notification.Attempts >= s.maxAttempts:
+		notification.Status = model.NotificationStatusFailed
+		notification.LastError = truncateError(err, 500)
+		slog.Warn("通知发送失败，不再重试", "id", notification.ID, "attempts", notification.Attempts, "err", err)
+	default:
+		notification.NextAttemptAt = now.Add(notificationRetryDelay(s.retryBackoff, notification.Attempts))
+		notification.LastError = truncateError(err, 500)
+		slog.Info("通知发送失败，稍后重试", "id", notification.ID, "attempts", notification.Attempts, "next_attempt_at", notification.NextAttemptAt, "err", err)
+	}
+
+	if err := s.notificationRepo.UpdateDelivery(ctx, notification); err != nil {
+		slog.Error("保存通知发送结果失败", "id", notification.ID, "err", err)
+	}
+}
+
+// notificationRetryDelay 第 attempts 次失败后的等待时间：backoff * 2^(attempts-1)，最长 1 小时
+func notificationRetryDelay(backoff time.Duration, attempts int) time.Duration {
+	delay := backoff
+	for i := 1; i < attempts && delay < maxNotificationRetryBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxNotificationRetryBackoff)
+}
+
+// truncateError 截断错误信息以适应字段长度
+func truncateError(err error, max int) string {
+	msg := []rune(err.Error())
+	if len(msg) > max {
+		msg = msg[:max]
+	}
+	return string(msg)
+}
+
+// ListNotifications 多条件查询通知
+func (s *notificationServiceImpl) ListNotifications(ctx context.Context, params *model.ListNotificationsParams) ([]*model.Notification, int64, error) {
+	return s.notificationRepo.List(ctx, params)
+}
+
+// RetryNotification 重置失败通知的尝试次数并立即放回队列，记录审计日志
+func (s *notificationServiceImpl) RetryNotification(ctx context.Context, id uint) (*model.Notification, error) {
+	var notification *model.Notification
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		notification, err = s.notificationRepo.WithTx(tx).FindByID(ctx, id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotificationNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if notification.Status != model.NotificationStatusFailed {
+			return ErrNotificationNotFailed
+		}
+
+		before := auditSnapshot(notification)
+		notification.Status = model.NotificationStatusPending
+		notification.Attempts = 0
+		notification.NextAttemptAt = time.Now()
+		if err := s.notificationRepo.WithTx(tx).UpdateDelivery(ctx, notification); err != nil {
+			return err
+		}
+		return recordAudit(ctx, s.auditRepo.WithTx(tx), model.AuditActionNotificationRetry, model.AuditTargetNotification, id, before, auditSnapshot(notification))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return notification, nil
+}
+
+// notificationRecipient 报名记录在某个渠道的收件人，为空表示不通过该渠道通知
+func notificationRecipient(channel model.NotificationChannel, reg *model.Registration) string {
+	switch channel {
+	case model.NotificationChannelEmail:
+		return reg.ParticipantEmail
+	default:
+		return ""
+	}
+}
+
+// enqueueNotifications 为报名者生成通知并写入队列 (未启用通知时不生成)
+// 应传入绑定业务事务的 repo (WithTx)，业务回滚时通知一并回滚；模板渲染失败只记录日志，不影响业务
+func enqueueNotifications(ctx context.Context, repo repository.NotificationRepository, event model.NotificationEvent, activity *model.Activity, registrations ...*model.Registration) error {
+	if !config.GlobalConfig.Notification.Enabled {
+		return nil
+	}
+
+	now := time.Now()
+	var notifications []*model.Notification
+	for _, reg := range registrations {
+		for _, channel := range notificationChannels {
+			recipient := notificationRecipient(channel, reg)
+			if recipient == "" {
+				continue
+			}
+			subject, body, err := renderNotification(event, activity, reg)
+			if err != nil {
+				slog.Error("通知模板渲染失败", "event", event, "registration_id", reg.ID, "err", err)
+				continue
+			}
+			notifications = append(notifications, &model.Notification{
+				Channel:        channel,
+				Event:          event,
+				Recipient:      recipient,
+				Subject:        subject,
+				Body:           body,
+				ActivityID:     activity.ID,
+				RegistrationID: reg.ID,
+				Status:         model.NotificationStatusPending,
+				NextAttemptAt:  now,
+			})
+		}
+	}
+	if len(notifications) == 0 {
+		return nil
+	}
+	return repo.CreateBatch(ctx, notifications)
+}
+
+// notifyRegistrants 通知活动的全部有效报名者 (已确认、候补、待审核、待抽签)，用于活动变更和取消
+// 调用约定同 enqueueNotifications，registrationRepo 需绑定同一事务
+func notifyRegistrants(ctx context.Context, registrationRepo repository.RegistrationRepository, notificationRepo repository.NotificationRepository, event model.NotificationEvent, activity *model.Activity) error {
+	if !config.GlobalConfig.Notification.Enabled {
+		return nil
+	}
+	registrations, err := registrationRepo.ListByStatuses(ctx, activity.ID, notifiedStatuses)
+	if err != nil {
+		return err
+	}
+	return enqueueNotifications(ctx, notificationRepo, event, activity, registrations...)
+}
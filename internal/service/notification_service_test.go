@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/frozenf1sh/gostudent/internal/config"
+	"github.com/frozenf1sh/gostudent/internal/model"
+	"github.com/frozenf1sh/gostudent/internal/repository"
+	"github.com/frozenf1sh/gostudent/pkg/mail"
+)
+
+// newNotificationTestService 使用 SQLite 内存数据库创建通知服务，sender 为邮件渠道的发送实现
+func newNotificationTestService(t *testing.T, sender NotificationSender) (*notificationServiceImpl, repository.NotificationRepository) {
+	t.Helper()
+	config.GlobalConfig = config.Config{}
+	config.GlobalConfig.Notification.Enabled = true
+	config.GlobalConfig.Notification.MaxAttempts = 3
+	config.GlobalConfig.Notification.RetryBackoff = time.Minute
+
+	db := newTestDB(t)
+
+	notificationRepo := repository.NewNotificationRepository(db)
+	svc := NewNotificationService(db, notificationRepo, repository.NewAuditLogRepository(db), map[model.NotificationChannel]NotificationSender{
+		model.NotificationChannelEmail: sender,
+	})
+	return svc.(*notificationServiceImpl), notificationRepo
+}
+
+// 每种事件按默认模板入队，经 SMTPSender 投递到本地 SMTP 服务器后，解码出的标题和正文与模板一致
+func TestNotificationTemplatesDeliveredOverSMTP(t *testing.T) {
+	received := make(chan mail.Message, 10)
+	srv, err := mail.StartLocalServer("127.0.0.1:0", func(m mail.Message) { received <- m })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	sender, err := mail.NewSMTPSender(mail.SMTPConfig{
+		Host: "127.0.0.1",
+		Port: srv.Addr().Port,
+		From: "活动报名系统 <noreply@example.com>",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc, notificationRepo := newNotificationTestService(t, sender)
+
+	start := time.Date(2026, 11, 15, 14, 0, 0, 0, time.Local)
+	activity := &model.Activity{
+		ID:           1,
+		Title:        "程序设计讲座",
+		StartTime:    start,
+		EndTime:      start.Add(2 * time.Hour),
+		Location:     "A101",
+		CancelReason: "场地维修",
+	}
+	confirmed := &model.Registration{ID: 1, ParticipantName: "张三", ParticipantEmail: "zhangsan@example.com", Status: model.RegistrationStatusConfirmed}
+	waitlisted := &model.Registration{ID: 2, ParticipantName: "张三", ParticipantEmail: "zhangsan@example.com", Status: model.RegistrationStatusWaitlisted, WaitlistPosition: 2}
+	schedule := "活动时间：2026年11月15日 14:00 至 2026年11月15日 16:00\n活动地点：A101\n"
+
+	tests := []struct {
+		event   model.NotificationEvent
+		reg     *model.Registration
+		subject string
+		body    string
+	}{
+		{
+			event:   model.NotificationEventRegistered,
+			reg:     waitlisted,
+			subject: "「程序设计讲座」报名已提交",
+			body:    "张三，您好：\n\n您已报名「程序设计讲座」，当前状态：候补中（候补第 2 位，有空余名额时将按顺序递补）。\n\n" + schedule,
+		},
+		{
+			event:   model.NotificationEventPromoted,
+			reg:     confirmed,
+			subject: "「程序设计讲座」候补已递补成功",
+			body:    "张三，您好：\n\n您在「程序设计讲座」的候补已递补为正式报名，请准时参加。\n\n" + schedule,
+		},
+		{
+			event:   model.NotificationEventActivityUpdated,
+			reg:     confirmed,
+			subject: "「程序设计讲座」活动信息变更",
+			body:    "张三，您好：\n\n您报名的「程序设计讲座」活动时间或地点有变更，最新信息如下：\n\n" + schedule,
+		},
+		{
+			event:   model.NotificationEventActivityCancelled,
+			reg:     confirmed,
+			subject: "「程序设计讲座」活动已取消",
+			body:    "张三，您好：\n\n很抱歉，您报名的「程序设计讲座」（原定 2026年11月15日 14:00）已取消。\n\n取消原因：场地维修\n",
+		},
+	}
+
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(string(tt.event), func(t *testing.T) {
+			if err := enqueueNotifications(ctx, notificationRepo, tt.event, activity, tt.reg); err != nil {
+				t.Fatal(err)
+			}
+			svc.deliverDue(ctx)
+
+			select {
+			case m := <-received:
+				if len(m.To) != 1 || m.To[0] != tt.reg.ParticipantEmail {
+					t.Errorf("To = %q, want %s", m.To, tt.reg.ParticipantEmail)
+				}
+				if m.Subject != tt.subject {
+					t.Errorf("Subject = %q, want %q", m.Subject, tt.subject)
+				}
+				if m.Body != tt.body {
+					t.Errorf("Body = %q, want %q", m.Body, tt.body)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("local server received no message")
+			}
+
+			notifications, _, err := notificationRepo.List(ctx, &model.ListNotificationsParams{Page: 1, PageSize: 10, Event: tt.event})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(notifications) != 1 || notifications[0].Status != model.NotificationStatusSent || notifications[0].Attempts != 1 {
+				t.Errorf("notifications for %s = %+v, want one SENT after 1 attempt", tt.event, notifications)
+			}
+		})
+	}
+}
+
+// failingSender 每次发送都失败
+type failingSender struct{ calls int }
+
+func (s *failingSender) Send(ctx context.Context, recipient, subject, body string) error {
+	s.calls++
+	return errors.New("connection refused")
+}
+
+// 发送失败后按 notificationRetryDelay 推迟下次发送，尝试 maxAttempts 次后标记为失败
+func TestNotificationRetryBackoff(t *testing.T) {
+	sender := &failingSender{}
+	svc, notificationRepo := newNotificationTestService(t, sender)
+	ctx := context.Background()
+
+	notification := &model.Notification{
+		Channel:       model.NotificationChannelEmail,
+		Event:         model.NotificationEventRegistered,
+		Recipient:     "zhangsan@example.com",
+		Subject:       "subject",
+		Body:          "body",
+		Status:        model.NotificationStatusPending,
+		NextAttemptAt: time.Now(),
+	}
+	if err := notificationRepo.CreateBatch(ctx, []*model.Notification{notification}); err != nil {
+		t.Fatal(err)
+	}
+
+	for attempt := 1; attempt <= svc.maxAttempts; attempt++ {
+		// 直接把下次发送时间拨到现在，模拟退避时间已过
+		due, err := notificationRepo.FindByID(ctx, notification.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		due.NextAttemptAt = time.Now()
+		if err := notificationRepo.UpdateDelivery(ctx, due); err != nil {
+			t.Fatal(err)
+		}
+
+		before := time.Now()
+		svc.deliverDue(ctx)
+		after := time.Now()
+
+		got, err := notificationRepo.FindByID(ctx, notification.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sender.calls != attempt || got.Attempts != attempt {
+			t.Fatalf("attempt %d: sender calls = %d, attempts = %d", attempt, sender.calls, got.Attempts)
+		}
+		if got.LastError != "connection refused" {
+			t.Errorf("attempt %d: last_error = %q", attempt, got.LastError)
+		}
+		if attempt < svc.maxAttempts {
+			delay := notificationRetryDelay(svc.retryBackoff, attempt)
+			if got.Status != model.NotificationStatusPending {
+				t.Errorf("attempt %d: status = %s, want PENDING", attempt, got.Status)
+			}
+			if got.NextAttemptAt.Before(before.Add(delay).Truncate(time.Microsecond)) || got.NextAttemptAt.After(after.Add(delay)) {
+				t.Errorf("attempt %d: next_attempt_at = %s, want about %s from now", attempt, got.NextAttemptAt, delay)
+			}
+			// 退避期间不会再次领取
+			svc.deliverDue(ctx)
+			if sender.calls != attempt {
+				t.Errorf("attempt %d: notification was resent before next_attempt_at", attempt)
+			}
+		} else if got.Status != model.NotificationStatusFailed {
+			t.Errorf("attempt %d: status = %s, want FAILED", attempt, got.Status)
+		}
+	}
+
+	// 标记为失败后不再发送
+	svc.deliverDue(ctx)
+	if sender.calls != svc.maxAttempts {
+		t.Errorf("failed notification was sent again: calls = %d", sender.calls)
+	}
+}
+
+func TestNotificationRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{6, 32 * time.Minute},
+		{7, time.Hour}, // 最长 1 小时
+		{20, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := notificationRetryDelay(time.Minute, tt.attempts); got != tt.want {
+			t.Errorf("notificationRetryDelay(1m, %d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
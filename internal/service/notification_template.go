@@ -0,0 +1,139 @@
+package service
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"text/template"
+	"unicode/utf8"
+
+	"github.com/frozenf1sh/gostudent/internal/config"
+	"github.com/frozenf1sh/gostudent/internal/model"
+)
+
+// NotificationFields 通知模板 (notification.templates) 可以使用的字段
+type NotificationFields struct {
+	Name             string // 报名者姓名
+	ActivityTitle    string // 活动名称
+	StartTime        string // 活动开始时间，如 2023年11月15日 14:00
+	EndTime          string // 活动结束时间
+	Location         string // 活动地点
+	Status           string // 报名状态，如 报名成功、候补中
+	WaitlistPosition int    // 候补序号 (非候补为 0)
+	CancelReason     string // 活动取消原因
+}
+
+// notificationTemplate 一个事件的标题和正文模板
+type notificationTemplate struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+// defaultNotificationTemplates 未配置模板的事件使用的默认模板
+var defaultNotificationTemplates = map[model.NotificationEvent]config.NotificationTemplate{
+	model.NotificationEventRegistered: {
+		Subject: "「{{.ActivityTitle}}」报名已提交",
+		Body: "{{.Name}}，您好：\n\n您已报名「{{.ActivityTitle}}」，当前状态：{{.Status}}" +
+			"{{if .WaitlistPosition}}（候补第 {{.WaitlistPosition}} 位，有空余名额时将按顺序递补）{{end}}。\n\n" +
+			"活动时间：{{.StartTime}} 至 {{.EndTime}}\n活动地点：{{.Location}}\n",
+	},
+	model.NotificationEventPromoted: {
+		Subject: "「{{.ActivityTitle}}」候补已递补成功",
+		Body: "{{.Name}}，您好：\n\n您在「{{.ActivityTitle}}」的候补已递补为正式报名，请准时参加。\n\n" +
+			"活动时间：{{.StartTime}} 至 {{.EndTime}}\n活动地点：{{.Location}}\n",
+	},
+	model.NotificationEventActivityUpdated: {
+		Subject: "「{{.ActivityTitle}}」活动信息变更",
+		Body: "{{.Name}}，您好：\n\n您报名的「{{.ActivityTitle}}」活动时间或地点有变更，最新信息如下：\n\n" +
+			"活动时间：{{.StartTime}} 至 {{.EndTime}}\n活动地点：{{.Location}}\n",
+	},
+	model.NotificationEventActivityCancelled: {
+		Subject: "「{{.ActivityTitle}}」活动已取消",
+		Body: "{{.Name}}，您好：\n\n很抱歉，您报名的「{{.ActivityTitle}}」（原定 {{.StartTime}}）已取消。\n" +
+			"{{if .CancelReason}}\n取消原因：{{.CancelReason}}\n{{end}}",
+	},
+}
+
+var (
+	notificationTemplatesOnce sync.Once
+	notificationTemplates     map[model.NotificationEvent]*notificationTemplate
+)
+
+// loadNotificationTemplates 解析各事件的模板，配置的模板有误时记录错误并使用默认模板，不影响触发通知的业务
+func loadNotificationTemplates() map[model.NotificationEvent]*notificationTemplate {
+	notificationTemplatesOnce.Do(func() {
+		notificationTemplates = make(map[model.NotificationEvent]*notificationTemplate, len(defaultNotificationTemplates))
+		for event, fallback := range defaultNotificationTemplates {
+			key := strings.ToLower(string(event))
+			if configured, ok := config.GlobalConfig.Notification.Templates[key]; ok {
+				// 只配置了标题或正文时，另一项使用默认模板
+				if configured.Subject == "" {
+					configured.Subject = fallback.Subject
+				}
+				if configured.Body == "" {
+					configured.Body = fallback.Body
+				}
+				tmpl, err := parseNotificationTemplate(key, configured)
+				if err == nil {
+					notificationTemplates[event] = tmpl
+					continue
+				}
+				slog.Error("通知模板有误，使用默认模板", "event", key, "err", err)
+			}
+			tmpl, err := parseNotificationTemplate(key, fallback)
+			if err != nil {
+				panic(err) // 默认模板由代码提供，不会出错
+			}
+			notificationTemplates[event] = tmpl
+		}
+	})
+	return notificationTemplates
+}
+
+// parseNotificationTemplate 解析模板，并用空字段试渲染一次，引用了不存在的字段时报错
+func parseNotificationTemplate(name string, t config.NotificationTemplate) (*notificationTemplate, error) {
+	subject, err := template.New(name + ".subject").Parse(t.Subject)
+	if err != nil {
+		return nil, err
+	}
+	body, err := template.New(name + ".body").Parse(t.Body)
+	if err != nil {
+		return nil, err
+	}
+	for _, tmpl := range []*template.Template{subject, body} {
+		if err := tmpl.Execute(io.Discard, NotificationFields{}); err != nil {
+			return nil, err
+		}
+	}
+	return &notificationTemplate{subject: subject, body: body}, nil
+}
+
+// renderNotification 按事件模板渲染一条通知的标题和正文
+func renderNotification(event model.NotificationEvent, activity *model.Activity, reg *model.Registration) (string, string, error) {
+	tmpl := loadNotificationTemplates()[event]
+	fields := NotificationFields{
+		Name:             reg.ParticipantName,
+		ActivityTitle:    activity.Title,
+		StartTime:        activity.StartTime.Format("2006年1月2日 15:04"),
+		EndTime:          activity.EndTime.Format("2006年1月2日 15:04"),
+		Location:         activity.Location,
+		Status:           registrationStatusLabels[reg.Status],
+		WaitlistPosition: reg.WaitlistPosition,
+		CancelReason:     activity.CancelReason,
+	}
+
+	var subject, body strings.Builder
+	if err := tmpl.subject.Execute(&subject, fields); err != nil {
+		return "", "", err
+	}
+	if err := tmpl.body.Execute(&body, fields); err != nil {
+		return "", "", err
+	}
+	// 标题只保留一行，并按字段长度截断
+	title := strings.Join(strings.Fields(subject.String()), " ")
+	if utf8.RuneCountInString(title) > 255 {
+		title = string([]rune(title)[:255])
+	}
+	return title, body.String(), nil
+}
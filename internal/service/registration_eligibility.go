@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/frozenf1sh/gostudent/internal/model"
+	"github.com/frozenf1sh/gostudent/internal/repository"
+)
+
+// 报名资格规则限制
+const (
+	maxAllowedColleges = 100   // 允许报名的学院数上限
+	maxAllowlistSize   = 20000 // 报名名单的条目数上限
+	maxAllowlistValue  = 64    // 名单中手机号或学号的最大长度
+)
+
+// 报名资格规则的名称，EligibilityError.Rule 取这些值
+const (
+	EligibilityRuleCollege      = "college"       // 学院不在允许范围内
+	EligibilityRuleCollegeQuota = "college_quota" // 学院名额已满
+	EligibilityRuleAllowlist    = "allowlist"     // 手机号和学号都不在报名名单中
+)
+
+// AllowlistCSVError 报名名单 CSV 格式错误，Line 为出错的行号 (从 1 开始，表头错误为 1)
+type AllowlistCSVError struct {
+	Line    int
+	Message string
+}
+
+func (e *AllowlistCSVError) Error() string {
+	return fmt.Sprintf("第 %d 行: %s", e.Line, e.Message)
+}
+
+// EligibilityError 报名者不满足活动的报名资格规则
+// Rule 为未通过的规则，Message 可以直接展示给报名者
+type EligibilityError struct {
+	Rule    string
+	Message string
+}
+
+func (e *EligibilityError) Error() string {
+	return fmt.Sprintf("not eligible (%s): %s", e.Rule, e.Message)
+}
+
+// EligibilityRulesError 组织者提交的报名资格规则不合法
+type EligibilityRulesError struct {
+	Message string
+}
+
+func (e *EligibilityRulesError) Error() string {
+	return e.Message
+}
+
+// normalizeEligibilityRules 校验并规范化报名资格规则 (去除空白和重复学院)，没有任何限制时返回 nil
+func normalizeEligibilityRules(rules *model.EligibilityRules) (*model.EligibilityRules, error) {
+	if rules == nil {
+		return nil, nil
+	}
+
+	normalized := &model.EligibilityRules{RequireAllowlist: rules.RequireAllowlist}
+	if len(rules.AllowedColleges) > maxAllowedColleges {
+		return nil, &EligibilityRulesError{Message: fmt.Sprintf("允许报名的学院最多 %d 个", maxAllowedColleges)}
+	}
+	for _, college := range rules.AllowedColleges {
+		college = strings.TrimSpace(college)
+		if college == "" || utf8.RuneCountInString(college) > 100 {
+			return nil, &EligibilityRulesError{Message: "学院名称不能为空且不超过 100 个字符"}
+		}
+		if !slices.Contains(normalized.AllowedColleges, college) {
+			normalized.AllowedColleges = append(normalized.AllowedColleges, college)
+		}
+	}
+
+	if len(rules.CollegeQuotas) > maxAllowedColleges {
+		return nil, &EligibilityRulesError{Message: fmt.Sprintf("学院名额最多设置 %d 个", maxAllowedColleges)}
+	}
+	for college, quota := range rules.CollegeQuotas {
+		college = strings.TrimSpace(college)
+		if college == "" {
+			return nil, &EligibilityRulesError{Message: "学院名称不能为空"}
+		}
+		if quota <= 0 {
+			return nil, &EligibilityRulesError{Message: college + "的名额必须大于 0"}
+		}
+		if len(normalized.AllowedColleges) > 0 && !slices.Contains(normalized.AllowedColleges, college) {
+			return nil, &EligibilityRulesError{Message: college + "不在允许报名的学院中"}
+		}
+		if normalized.CollegeQuotas == nil {
+			normalized.CollegeQuotas = make(map[string]int, len(rules.CollegeQuotas))
+		}
+		normalized.CollegeQuotas[college] = quota
+	}
+
+	if len(normalized.AllowedColleges) == 0 && len(normalized.CollegeQuotas) == 0 && !normalized.RequireAllowlist {
+		return nil, nil
+	}
+	return normalized, nil
+}
+
+// checkEligibility 校验报名者是否满足活动的学院和报名名单规则
+// 学院名额与是否有空余名额相关，由 checkCollegeQuota 在活动行锁内单独校验
+func checkEligibility(ctx context.Context, allowlistRepo repository.AllowlistRepository, activity *model.Activity, req *model.CreateRegistrationRequest) error {
+	rules := activity.Eligibility
+	if rules == nil {
+		return nil
+	}
+
+	if len(rules.AllowedColleges) > 0 && !slices.Contains(rules.AllowedColleges, strings.TrimSpace(req.ParticipantCollege)) {
+		return &EligibilityError{
+			Rule:    EligibilityRuleCollege,
+			Message: "该活动仅限以下学院报名: " + strings.Join(rules.AllowedColleges, "、"),
+		}
+	}
+
+	if rules.RequireAllowlist {
+		ok, err := allowlistRepo.Contains(ctx, activity.ID, strings.TrimSpace(req.ParticipantPhone), strings.TrimSpace(req.ParticipantStudentID))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return &EligibilityError{
+				Rule:    EligibilityRuleAllowlist,
+				Message: "您的手机号或学号不在该活动的报名名单中",
+			}
+		}
+	}
+	return nil
+}
+
+// checkCollegeQuota 校验报名者所在学院的名额是否已满
+// 必须在已锁定活动行的事务中调用，registrationRepo 需绑定同一事务
+func checkCollegeQuota(ctx context.Context, registrationRepo repository.RegistrationRepository, activity *model.Activity, college string) error {
+	full, err := collegeQuotaFull(ctx, registrationRepo, activity, strings.TrimSpace(college))
+	if err != nil {
+		return err
+	}
+	if full {
+		return &EligibilityError{
+			Rule:    EligibilityRuleCollegeQuota,
+			Message: college + "的报名名额已满",
+		}
+	}
+	return nil
+}
+
+// collegeQuotaFull 判断学院已确认的人数是否达到名额上限，未设置名额的学院返回 false
+func collegeQuotaFull(ctx context.Context, registrationRepo repository.RegistrationRepository, activity *model.Activity, college string) (bool, error) {
+	if activity.Eligibility == nil {
+		return false, nil
+	}
+	quota, ok := activity.Eligibility.CollegeQuotas[college]
+	if !ok {
+		return false, nil
+	}
+	confirmed, err := registrationRepo.CountConfirmedByCollege(ctx, activity.ID, college)
+	if err != nil {
+		return false, err
+	}
+	return confirmed >= int64(quota), nil
+}
+
+// hasCollegeQuotas 判断活动是否设置了学院名额
+func hasCollegeQuotas(activity *model.Activity) bool {
+	return activity.Eligibility != nil && len(activity.Eligibility.CollegeQuotas) > 0
+}
+
+// ParseAllowlistCSV 解析报名名单 CSV
+// 第一行为表头，识别 phone / 手机号 和 student_id / 学号 两列 (至少一列)，每行任一列有值即为一个条目；
+// 空值和重复值会被跳过
+func ParseAllowlistCSV(r io.Reader) ([]*model.AllowlistEntry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, &AllowlistCSVError{Line: 1, Message: "无法读取表头"}
+	}
+	columns := make(map[int]model.AllowlistKind)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))) // 兼容 Excel 导出的 BOM
+		switch name {
+		case "phone", "手机号":
+			columns[i] = model.AllowlistKindPhone
+		case "student_id", "学号":
+			columns[i] = model.AllowlistKindStudentID
+		}
+	}
+	if len(columns) == 0 {
+		return nil, &AllowlistCSVError{Line: 1, Message: "表头需要包含 phone 或 student_id 列"}
+	}
+
+	var entries []*model.AllowlistEntry
+	seen := make(map[model.AllowlistKind]map[string]bool)
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, &AllowlistCSVError{Line: line, Message: "格式错误"}
+		}
+		for i, kind := range columns {
+			if i >= len(record) {
+				continue
+			}
+			value := strings.TrimSpace(record[i])
+			if value == "" {
+				continue
+			}
+			if len(value) > maxAllowlistValue {
+				return nil, &AllowlistCSVError{Line: line, Message: fmt.Sprintf("值不能超过 %d 个字符", maxAllowlistValue)}
+			}
+			if seen[kind] == nil {
+				seen[kind] = make(map[string]bool)
+			}
+			if seen[kind][value] {
+				continue
+			}
+			seen[kind][value] = true
+			entries = append(entries, &model.AllowlistEntry{Kind: kind, Value: value})
+			if len(entries) > maxAllowlistSize {
+				return nil, &AllowlistCSVError{Line: line, Message: fmt.Sprintf("名单最多 %d 条", maxAllowlistSize)}
+			}
+		}
+	}
+	return entries, nil
+}
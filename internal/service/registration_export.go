@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/frozenf1sh/gostudent/internal/model"
+	"github.com/frozenf1sh/gostudent/pkg/xlsx"
+)
+
+var ErrInvalidExportFormat = errors.New("export format must be csv or xlsx")
+
+// exportBatchSize 导出时每批从数据库读取的报名数，内存占用只与批大小有关
+const exportBatchSize = 500
+
+// registrationStatusLabels 导出文件中报名状态的中文名称
+var registrationStatusLabels = map[model.RegistrationStatus]string{
+	model.RegistrationStatusConfirmed:  "报名成功",
+	model.RegistrationStatusWaitlisted: "候补中",
+	model.RegistrationStatusCancelled:  "已取消",
+	model.RegistrationStatusPending:    "待审核",
+	model.RegistrationStatusRejected:   "审核未通过",
+	model.RegistrationStatusApplied:    "待抽签",
+}
+
+// exportRowWriter 导出文件的逐行写入接口，CSV 和 Excel 各有一个实现
+type exportRowWriter interface {
+	WriteRow(cells []string) error
+	Flush() error
+	Close() error
+}
+
+// ExportRegistrations 按与 ListRegistrations 相同的过滤条件 (忽略分页) 导出某活动的全部报名记录
+// 分批读取并逐行写入 w，包含签到、签退信息和报名表自定义字段的答案
+// 活动不存在或格式无效时在写入任何内容之前返回错误
+func (s *registrationServiceImpl) ExportRegistrations(ctx context.Context, params *model.ListRegistrationsParams, format model.RegistrationExportFormat, w io.Writer) error {
+	if format != model.RegistrationExportCSV && format != model.RegistrationExportXLSX {
+		return ErrInvalidExportFormat
+	}
+	activity, err := s.activityRepo.FindByID(ctx, params.ActivityID)
+	if err != nil {
+		return ErrActivityNotFound
+	}
+
+	var rw exportRowWriter
+	if format == model.RegistrationExportXLSX {
+		if rw, err = xlsx.NewWriter(w, "报名表"); err != nil {
+			return err
+		}
+	} else {
+		if rw, err = newCSVRowWriter(w); err != nil {
+			return err
+		}
+	}
+
+	if err := rw.WriteRow(registrationExportHeader(activity.FormSchema)); err != nil {
+		return err
+	}
+	err = s.registrationRepo.ListInBatches(ctx, params, exportBatchSize, func(batch []*model.Registration) error {
+		for _, reg := range batch {
+			if err := rw.WriteRow(registrationExportRow(reg, activity.FormSchema)); err != nil {
+				return err
+			}
+		}
+		return rw.Flush()
+	})
+	if err != nil {
+		return err
+	}
+	return rw.Close()
+}
+
+// registrationExportHeader 表头：固定列之后按报名表顺序排列自定义字段
+func registrationExportHeader(schema model.FormSchema) []string {
+	header := []string{"报名ID", "姓名", "手机号", "学院", "学号", "报名状态", "报名时间",
+		"是否签到", "签到时间", "是否迟到", "签退时间", "是否早退", "参与时长(分钟)"}
+	for _, field := range schema {
+		header = append(header, field.Label)
+	}
+	return header
+}
+
+// registrationExportRow 一条报名记录对应的一行
+func registrationExportRow(reg *model.Registration, schema model.FormSchema) []string {
+	status := registrationStatusLabels[reg.Status]
+	if status == "" {
+		status = string(reg.Status)
+	}
+	row := []string{
+		strconv.FormatUint(uint64(reg.ID), 10),
+		reg.ParticipantName,
+		reg.ParticipantPhone,
+		reg.ParticipantCollege,
+		reg.ParticipantStudentID,
+		status,
+		formatExportTime(&reg.RegisteredAt),
+		formatExportBool(reg.IsSignedIn),
+		formatExportTime(reg.SignedInAt),
+		formatExportBool(reg.IsLate),
+		formatExportTime(reg.SignedOutAt),
+		formatExportBool(reg.LeftEarly),
+		"",
+	}
+	if reg.SignedOutAt != nil {
+		row[len(row)-1] = strconv.FormatInt(reg.AttendedSeconds/60, 10)
+	}
+	for _, field := range schema {
+		row = append(row, formatExportAnswer(reg.Answers[field.Key]))
+	}
+	return row
+}
+
+// formatExportTime 导出时间使用服务器本地时区，空值为空字符串
+func formatExportTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
+
+func formatExportBool(v bool) string {
+	if v {
+		return "是"
+	}
+	return "否"
+}
+
+// formatExportAnswer 自定义字段答案：多选用顿号连接，数字不使用科学计数法
+func formatExportAnswer(answer any) string {
+	switch v := answer.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = formatExportAnswer(item)
+		}
+		return strings.Join(items, "、")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// csvRowWriter CSV 导出：UTF-8 BOM 开头以便 Excel 正确识别中文
+type csvRowWriter struct {
+	w *csv.Writer
+}
+
+func newCSVRowWriter(w io.Writer) (*csvRowWriter, error) {
+	if _, err := io.WriteString(w, "\xef\xbb\xbf"); err != nil {
+		return nil, err
+	}
+	return &csvRowWriter{w: csv.NewWriter(w)}, nil
+}
+
+// WriteRow 写入一行，以 = + - @ 开头的单元格前加单引号，防止在电子表格中被当作公式执行
+func (c *csvRowWriter) WriteRow(cells []string) error {
+	for i, cell := range cells {
+		if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+			cells[i] = "'" + cell
+		}
+	}
+	return c.w.Write(cells)
+}
+
+func (c *csvRowWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvRowWriter) Close() error {
+	return c.Flush()
+}
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/frozenf1sh/gostudent/internal/model"
+)
+
+// 报名表限制
+const (
+	maxFormFields       = 30   // 每个活动最多的自定义字段数
+	maxFormLabelLength  = 100  // 问题的最大长度
+	maxFormPattern      = 200  // 校验正则的最大长度
+	maxFormTextLength   = 200  // 单行文本答案的最大长度
+	maxFormTextareaSize = 2000 // 多行文本答案的最大长度
+)
+
+var formFieldKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+// FormValidationError 报名表定义或答案不合法
+// Field 为出错字段的 key (定义中的字段尚无合法 key 时为其序号)，Message 可以直接展示给用户
+type FormValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *FormValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// validateFormSchema 校验组织者提交的报名表定义
+func validateFormSchema(schema model.FormSchema) error {
+	if len(schema) > maxFormFields {
+		return &FormValidationError{Field: "fields", Message: fmt.Sprintf("最多 %d 个自定义字段", maxFormFields)}
+	}
+
+	keys := make(map[string]bool, len(schema))
+	for i, field := range schema {
+		name := field.Key
+		if !formFieldKeyPattern.MatchString(field.Key) {
+			return &FormValidationError{Field: "#" + strconv.Itoa(i+1), Message: "key 只能包含小写字母、数字和下划线，以字母开头，最长 32 个字符"}
+		}
+		if keys[field.Key] {
+			return &FormValidationError{Field: name, Message: "key 重复"}
+		}
+		keys[field.Key] = true
+
+		if strings.TrimSpace(field.Label) == "" || utf8.RuneCountInString(field.Label) > maxFormLabelLength {
+			return &FormValidationError{Field: name, Message: fmt.Sprintf("label 不能为空且不超过 %d 个字符", maxFormLabelLength)}
+		}
+
+		switch field.Type {
+		case model.FormFieldSelect, model.FormFieldMultiSelect:
+			if len(field.Options) == 0 {
+				return &FormValidationError{Field: name, Message: "选择题至少需要一个选项"}
+			}
+			seen := make(map[string]bool, len(field.Options))
+			for _, option := range field.Options {
+				if strings.TrimSpace(option) == "" || seen[option] {
+					return &FormValidationError{Field: name, Message: "选项不能为空或重复"}
+				}
+				seen[option] = true
+			}
+		case model.FormFieldText, model.FormFieldTextarea, model.FormFieldNumber:
+			if len(field.Options) > 0 {
+				return &FormValidationError{Field: name, Message: "只有选择题可以设置选项"}
+			}
+		default:
+			return &FormValidationError{Field: name, Message: "不支持的字段类型 " + string(field.Type)}
+		}
+
+		if field.Pattern != "" {
+			if field.Type != model.FormFieldText && field.Type != model.FormFieldTextarea {
+				return &FormValidationError{Field: name, Message: "只有文本字段可以设置校验正则"}
+			}
+			if len(field.Pattern) > maxFormPattern {
+				return &FormValidationError{Field: name, Message: fmt.Sprintf("校验正则不能超过 %d 个字符", maxFormPattern)}
+			}
+			if _, err := regexp.Compile(field.Pattern); err != nil {
+				return &FormValidationError{Field: name, Message: "校验正则不合法: " + err.Error()}
+			}
+		}
+	}
+	return nil
+}
+
+// validateFormAnswers 按报名表定义校验报名者的答案，返回规范化后的答案 (没有答案时为 nil)
+// 未定义的字段、类型不符、必填未填、不在选项内或不匹配校验正则都会返回 FormValidationError
+func validateFormAnswers(schema model.FormSchema, answers model.FormAnswers) (model.FormAnswers, error) {
+	fields := make(map[string]bool, len(schema))
+	for _, field := range schema {
+		fields[field.Key] = true
+	}
+	for key := range answers {
+		if !fields[key] {
+			return nil, &FormValidationError{Field: key, Message: "报名表中没有该字段"}
+		}
+	}
+
+	normalized := make(model.FormAnswers, len(schema))
+	for _, field := range schema {
+		value, err := normalizeFormAnswer(field, answers[field.Key])
+		if err != nil {
+			return nil, err
+		}
+		if value == nil {
+			if field.Required {
+				return nil, &FormValidationError{Field: field.Key, Message: field.Label + "为必填项"}
+			}
+			continue
+		}
+		normalized[field.Key] = value
+	}
+	if len(normalized) == 0 {
+		return nil, nil
+	}
+	return normalized, nil
+}
+
+// normalizeFormAnswer 校验单个答案，空答案返回 nil
+func normalizeFormAnswer(field model.FormField, raw any) (any, error) {
+	invalid := func(message string) error {
+		return &FormValidationError{Field: field.Key, Message: field.Label + message}
+	}
+
+	switch field.Type {
+	case model.FormFieldText, model.FormFieldTextarea, model.FormFieldSelect:
+		if raw == nil {
+			return nil, nil
+		}
+		s, ok := raw.(string)
+		if !ok {
+			return nil, invalid("应为文本")
+		}
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return nil, nil
+		}
+		if field.Type == model.FormFieldSelect {
+			if !slices.Contains(field.Options, s) {
+				return nil, invalid("的选项不存在")
+			}
+			return s, nil
+		}
+		limit := maxFormTextLength
+		if field.Type == model.FormFieldTextarea {
+			limit = maxFormTextareaSize
+		}
+		if utf8.RuneCountInString(s) > limit {
+			return nil, invalid(fmt.Sprintf("不能超过 %d 个字符", limit))
+		}
+		if field.Pattern != "" {
+			re, err := regexp.Compile(field.Pattern)
+			if err != nil || !re.MatchString(s) {
+				return nil, invalid("格式不正确")
+			}
+		}
+		return s, nil
+
+	case model.FormFieldNumber:
+		switch v := raw.(type) {
+		case nil:
+			return nil, nil
+		case float64:
+			return v, nil
+		case int:
+			return float64(v), nil
+		case string:
+			// 兼容以文本提交的数字 (如表格导入)
+			if strings.TrimSpace(v) == "" {
+				return nil, nil
+			}
+			n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+				return nil, invalid("应为数字")
+			}
+			return n, nil
+		default:
+			return nil, invalid("应为数字")
+		}
+
+	case model.FormFieldMultiSelect:
+		var items []string
+		switch v := raw.(type) {
+		case nil:
+			return nil, nil
+		case []string:
+			items = v
+		case []any:
+			for _, item := range v {
+				s, ok := item.(string)
+				if !ok {
+					return nil, invalid("应为选项列表")
+				}
+				items = append(items, s)
+			}
+		default:
+			return nil, invalid("应为选项列表")
+		}
+		selected := make([]string, 0, len(items))
+		for _, item := range items {
+			item = strings.TrimSpace(item)
+			if !slices.Contains(field.Options, item) {
+				return nil, invalid("的选项不存在")
+			}
+			if !slices.Contains(selected, item) {
+				selected = append(selected, item)
+			}
+		}
+		if len(selected) == 0 {
+			return nil, nil
+		}
+		return selected, nil
+	}
+	return nil, invalid("的字段类型不受支持")
+}
@@ -0,0 +1,468 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	netmail "net/mail"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/frozenf1sh/gostudent/internal/model"
+	"gorm.io/gorm"
+)
+
+// maxImportRows 单个导入文件的数据行数上限
+const maxImportRows = 5000
+
+// errImportRollback 试运行或整体导入有失败行时回滚事务，不作为错误返回
+var errImportRollback = errors.New("import rolled back")
+
+// ImportCSVError 导入文件格式错误，Line 为出错的行号 (从 1 开始，表头错误为 1)
+type ImportCSVError struct {
+	Line    int
+	Message string
+}
+
+func (e *ImportCSVError) Error() string {
+	return fmt.Sprintf("第 %d 行: %s", e.Line, e.Message)
+}
+
+// RegistrationImportRow 报名导入文件中的一行
+// Extra 为固定列之外的列 (表头 -> 值)，按报名表字段的 key 或 label 匹配为自定义字段答案
+type RegistrationImportRow struct {
+	Line    int
+	Request model.CreateRegistrationRequest
+	Extra   map[string]string
+}
+
+// SignInImportRow 签到导入文件中的一行，SignedInAt 为空时使用导入时间
+type SignInImportRow struct {
+	Line       int
+	Phone      string
+	SignedInAt string
+}
+
+// ParseRegistrationImportCSV 解析报名导入文件
+// 表头需包含 name (姓名)、phone (手机号)、college (学院) 列，可选 student_id (学号)、email (邮箱) 列，其余列作为报名表自定义字段
+func ParseRegistrationImportCSV(r io.Reader) ([]*RegistrationImportRow, error) {
+	reader, header, err := newImportCSVReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[int]string)
+	extras := make(map[int]string)
+	for i, name := range header {
+		switch strings.ToLower(name) {
+		case "name", "姓名":
+			fields[i] = "name"
+		case "phone", "手机号":
+			fields[i] = "phone"
+		case "college", "学院":
+			fields[i] = "college"
+		case "student_id", "学号":
+			fields[i] = "student_id"
+		case "email", "邮箱":
+			fields[i] = "email"
+		default:
+			if name != "" {
+				extras[i] = name
+			}
+		}
+	}
+	for _, required := range []string{"name", "phone", "college"} {
+		if !containsValue(fields, required) {
+			return nil, &ImportCSVError{Line: 1, Message: "表头需要包含 name、phone 和 college 列"}
+		}
+	}
+
+	var rows []*RegistrationImportRow
+	err = readImportRecords(reader, func(line int, record []string) {
+		row := &RegistrationImportRow{Line: line, Extra: make(map[string]string)}
+		for i, value := range record {
+			value = strings.TrimSpace(value)
+			switch fields[i] {
+			case "name":
+				row.Request.ParticipantName = value
+			case "phone":
+				row.Request.ParticipantPhone = value
+			case "college":
+				row.Request.ParticipantCollege = value
+			case "student_id":
+				row.Request.ParticipantStudentID = value
+			case "email":
+				row.Request.ParticipantEmail = value
+			default:
+				if name, ok := extras[i]; ok && value != "" {
+					row.Extra[name] = value
+				}
+			}
+		}
+		rows = append(rows, row)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// ParseSignInImportCSV 解析签到导入文件
+// 表头需包含 phone (手机号) 列，可选 signed_in_at (签到时间) 列
+func ParseSignInImportCSV(r io.Reader) ([]*SignInImportRow, error) {
+	reader, header, err := newImportCSVReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	phoneCol, timeCol := -1, -1
+	for i, name := range header {
+		switch strings.ToLower(name) {
+		case "phone", "手机号":
+			phoneCol = i
+		case "signed_in_at", "签到时间":
+			timeCol = i
+		}
+	}
+	if phoneCol < 0 {
+		return nil, &ImportCSVError{Line: 1, Message: "表头需要包含 phone 列"}
+	}
+
+	var rows []*SignInImportRow
+	err = readImportRecords(reader, func(line int, record []string) {
+		row := &SignInImportRow{Line: line}
+		if phoneCol < len(record) {
+			row.Phone = strings.TrimSpace(record[phoneCol])
+		}
+		if timeCol >= 0 && timeCol < len(record) {
+			row.SignedInAt = strings.TrimSpace(record[timeCol])
+		}
+		rows = append(rows, row)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// newImportCSVReader 读取表头，去除空白和 Excel 导出的 BOM
+func newImportCSVReader(r io.Reader) (*csv.Reader, []string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, &ImportCSVError{Line: 1, Message: "无法读取表头"}
+	}
+	for i, name := range header {
+		header[i] = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+	}
+	return reader, header, nil
+}
+
+// readImportRecords 逐行读取数据，跳过全部为空的行，行数超过上限时报错
+func readImportRecords(reader *csv.Reader, fn func(line int, record []string)) error {
+	count := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		if err != nil {
+			return &ImportCSVError{Line: line, Message: "格式错误"}
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+		if count++; count > maxImportRows {
+			return &ImportCSVError{Line: line, Message: fmt.Sprintf("最多导入 %d 行", maxImportRows)}
+		}
+		fn(line, record)
+	}
+	if count == 0 {
+		return &ImportCSVError{Line: 2, Message: "文件中没有数据"}
+	}
+	return nil
+}
+
+func containsValue(m map[int]string, value string) bool {
+	for _, v := range m {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// importJob 一次导入：在活动行锁内逐行处理，按选项决定是否提交
+type importJob struct {
+	rows   int
+	check  func(activity *model.Activity) error                                              // 导入前对活动的校验，失败时整体返回错误
+	row    func(tx *gorm.DB, activity *model.Activity, i int) (model.ImportRowResult, error) // 处理一行，返回的错误为数据库等错误，会中止导入
+	commit func(tx *gorm.DB, activity *model.Activity, report *model.ImportReport) error     // 可选：提交前执行 (如记录审计日志)
+}
+
+// runImport 执行导入：每行的业务校验都在写入之前完成，失败的行不会留下数据；
+// 试运行时事务最终回滚，结果与实际导入一致 (包括文件内重复和名额变化)；整体导入时有失败行则全部回滚
+func (s *registrationServiceImpl) runImport(ctx context.Context, activityID uint, opts *model.ImportOptions, job importJob) (*model.ImportReport, *model.Activity, error) {
+	report := &model.ImportReport{DryRun: opts.DryRun, Atomic: opts.Atomic, Rows: make([]model.ImportRowResult, 0, job.rows)}
+	var activity *model.Activity
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		activity, err = s.activityRepo.WithTx(tx).FindByIDForUpdate(ctx, activityID)
+		if err != nil {
+			return ErrActivityNotFound
+		}
+		if err := job.check(activity); err != nil {
+			return err
+		}
+
+		for i := 0; i < job.rows; i++ {
+			result, err := job.row(tx, activity, i)
+			if err != nil {
+				return err
+			}
+			report.Rows = append(report.Rows, result)
+			switch result.Result {
+			case model.ImportRowSuccess:
+				report.Succeeded++
+			case model.ImportRowDuplicate:
+				report.Duplicates++
+			default:
+				report.Failed++
+			}
+		}
+		report.Total = len(report.Rows)
+
+		if opts.DryRun || (opts.Atomic && report.Failed > 0) {
+			return errImportRollback
+		}
+		if job.commit != nil {
+			return job.commit(tx, activity, report)
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, errImportRollback):
+		return report, activity, nil
+	case err != nil:
+		return nil, nil, err
+	}
+	report.Committed = true
+	return report, activity, nil
+}
+
+// ImportRegistrations 批量导入线下收集的报名，每行按 Register 相同的规则校验 (报名表答案、重复报名、报名资格、学院名额)，
+// 名额已满时进入候补队列，需要审核或抽签的活动进入待审核或待抽签状态。导入的报名没有自助管理凭证
+func (s *registrationServiceImpl) ImportRegistrations(ctx context.Context, activityID uint, rows []*RegistrationImportRow, opts *model.ImportOptions) (*model.ImportReport, error) {
+	occupied := make([]bool, len(rows))
+	report, activity, err := s.runImport(ctx, activityID, opts, importJob{
+		rows: len(rows),
+		check: func(activity *model.Activity) error {
+			return checkRegistrationOpen(activity, time.Now())
+		},
+		row: func(tx *gorm.DB, activity *model.Activity, i int) (model.ImportRowResult, error) {
+			row := rows[i]
+			result := model.ImportRowResult{Line: row.Line, Phone: row.Request.ParticipantPhone}
+			if message := checkImportedParticipant(&row.Request); message != "" {
+				result.Result, result.Error = model.ImportRowFailed, message
+				return result, nil
+			}
+
+			req := row.Request
+			req.Answers = importFormAnswers(activity.FormSchema, row.Extra)
+			reg, occupiesSeat, err := s.registerInTx(ctx, tx, activity, &req)
+			if err != nil {
+				outcome, message, ok := importRegistrationFailure(err)
+				if !ok {
+					return result, err
+				}
+				result.Result, result.Error = outcome, message
+				return result, nil
+			}
+			occupied[i] = occupiesSeat
+			result.Result, result.Status = model.ImportRowSuccess, reg.Status
+			return result, nil
+		},
+		commit: func(tx *gorm.DB, activity *model.Activity, report *model.ImportReport) error {
+			if report.Succeeded == 0 {
+				return nil
+			}
+			return recordAudit(ctx, s.auditRepo.WithTx(tx), model.AuditActionRegistrationsImport, model.AuditTargetActivity, activity.ID, nil,
+				map[string]any{"imported": report.Succeeded, "duplicates": report.Duplicates, "failed": report.Failed})
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 同步到快速报名的 Redis 名额
+	if report.Committed {
+		for i, result := range report.Rows {
+			if result.Result != model.ImportRowSuccess {
+				continue
+			}
+			seats := 0
+			if occupied[i] {
+				seats = 1
+			}
+			s.fastRegistration.SeatOccupied(ctx, activity, result.Phone, seats)
+		}
+	}
+	return report, nil
+}
+
+// checkImportedParticipant 校验导入行的必填项和长度 (报名接口由请求绑定校验)，返回失败原因
+func checkImportedParticipant(req *model.CreateRegistrationRequest) string {
+	switch {
+	case req.ParticipantName == "" || req.ParticipantPhone == "" || req.ParticipantCollege == "":
+		return "姓名、手机号和学院不能为空"
+	case utf8.RuneCountInString(req.ParticipantName) > 100 || utf8.RuneCountInString(req.ParticipantCollege) > 100:
+		return "姓名和学院不能超过 100 个字符"
+	case len(req.ParticipantPhone) > 20:
+		return "手机号不能超过 20 个字符"
+	case len(req.ParticipantStudentID) > 32:
+		return "学号不能超过 32 个字符"
+	case req.ParticipantEmail != "" && !validImportEmail(req.ParticipantEmail):
+		return "邮箱格式错误"
+	}
+	return ""
+}
+
+// validImportEmail 与报名接口的 email 校验一致：不超过 255 个字符的单个邮箱地址 (不含显示名)
+func validImportEmail(email string) bool {
+	if len(email) > 255 {
+		return false
+	}
+	addr, err := netmail.ParseAddress(email)
+	return err == nil && addr.Address == email
+}
+
+// importFormAnswers 将自定义字段列转换为报名表答案，列名可以是字段的 key 或 label
+// 数字字段解析为数字，多选字段按 | 或顿号拆分；无法转换的值原样保留，由 validateFormAnswers 报告错误
+func importFormAnswers(schema model.FormSchema, extra map[string]string) model.FormAnswers {
+	answers := make(model.FormAnswers)
+	for _, field := range schema {
+		value, ok := extra[field.Key]
+		if !ok {
+			value, ok = extra[field.Label]
+		}
+		if !ok {
+			continue
+		}
+		switch field.Type {
+		case model.FormFieldNumber:
+			if n, err := strconv.ParseFloat(value, 64); err == nil {
+				answers[field.Key] = n
+			} else {
+				answers[field.Key] = value
+			}
+		case model.FormFieldMultiSelect:
+			var items []any
+			for _, item := range strings.FieldsFunc(value, func(r rune) bool { return r == '|' || r == '、' }) {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+			answers[field.Key] = items
+		default:
+			answers[field.Key] = value
+		}
+	}
+	return answers
+}
+
+// importRegistrationFailure 将报名校验错误转换为导入结果，ok 为 false 表示数据库等错误
+func importRegistrationFailure(err error) (model.ImportRowOutcome, string, bool) {
+	var formErr *FormValidationError
+	var eligibilityErr *EligibilityError
+	switch {
+	case errors.Is(err, ErrRegistrationDuplicate):
+		return model.ImportRowDuplicate, "已报名该活动", true
+	case errors.Is(err, ErrRegistrationRejected):
+		return model.ImportRowFailed, "报名申请未通过审核，不能重新报名", true
+	case errors.As(err, &formErr):
+		return model.ImportRowFailed, "报名表填写有误: " + formErr.Message, true
+	case errors.As(err, &eligibilityErr):
+		return model.ImportRowFailed, "不符合报名条件: " + eligibilityErr.Message, true
+	}
+	return "", "", false
+}
+
+// importTimeLayouts 签到时间支持的格式 (服务器本地时区)
+var importTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006/1/2 15:04:05",
+	"2006/1/2 15:04",
+}
+
+// parseImportTime 解析签到时间
+func parseImportTime(value string) (time.Time, bool) {
+	for _, layout := range importTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// ImportSignIns 按手机号批量标记签到，每行按管理员修改签到状态的规则处理并记录审计日志
+// 只有已确认的报名可以签到，已签到的报名跳过；签到时间列为空时使用导入时间，并据此判断是否迟到
+func (s *registrationServiceImpl) ImportSignIns(ctx context.Context, activityID uint, rows []*SignInImportRow, opts *model.ImportOptions) (*model.ImportReport, error) {
+	now := time.Now()
+	report, _, err := s.runImport(ctx, activityID, opts, importJob{
+		rows:  len(rows),
+		check: checkSignInAllowed,
+		row: func(tx *gorm.DB, activity *model.Activity, i int) (model.ImportRowResult, error) {
+			row := rows[i]
+			result := model.ImportRowResult{Line: row.Line, Phone: row.Phone, Result: model.ImportRowFailed}
+			if row.Phone == "" {
+				result.Error = "手机号不能为空"
+				return result, nil
+			}
+			signedInAt := now
+			if row.SignedInAt != "" {
+				t, ok := parseImportTime(row.SignedInAt)
+				if !ok {
+					result.Error = "签到时间格式错误，应为 2006-01-02 15:04:05"
+					return result, nil
+				}
+				signedInAt = t
+			}
+
+			reg, err := s.registrationRepo.WithTx(tx).FindByActivityAndPhone(ctx, activityID, row.Phone)
+			if err != nil {
+				return result, err
+			}
+			switch {
+			case reg == nil || reg.Status == model.RegistrationStatusCancelled:
+				result.Error = "未找到该手机号的报名记录"
+				return result, nil
+			case reg.Status != model.RegistrationStatusConfirmed:
+				result.Error = "报名尚未确认，不能签到"
+				return result, nil
+			case reg.IsSignedIn:
+				result.Result, result.Error = model.ImportRowDuplicate, "已签到"
+				return result, nil
+			}
+
+			if err := s.updateSignInInTx(ctx, tx, activity, reg, true, signedInAt); err != nil {
+				return result, err
+			}
+			result.Result, result.Status = model.ImportRowSuccess, reg.Status
+			return result, nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
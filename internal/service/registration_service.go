@@ -3,18 +3,31 @@ package service
 import (
 	"context"
 	"errors"
+	"io"
+	"strings"
 	"time"
 
+	"github.com/frozenf1sh/gostudent/internal/config"
 	"github.com/frozenf1sh/gostudent/internal/model"
 	"github.com/frozenf1sh/gostudent/internal/repository"
+	"github.com/frozenf1sh/gostudent/pkg/utils"
 	"gorm.io/gorm"
 )
 
 var (
-	ErrRegistrationDuplicate = errors.New("you have already registered for this activity")
-	ErrRegistrationMaxed     = errors.New("registration count has reached the maximum limit")
-	ErrRegistrationNotOpen   = errors.New("registration is not currently open")
-	ErrRegistrationNotFound  = errors.New("registration record not found") // 新增错误：报名记录未找到
+	ErrRegistrationDuplicate  = errors.New("you have already registered for this activity")
+	ErrRegistrationMaxed      = errors.New("registration count has reached the maximum limit")
+	ErrRegistrationNotOpen    = errors.New("registration is not currently open")
+	ErrRegistrationNotOpenYet = errors.New("registration has not opened yet")
+	ErrRegistrationNotFound   = errors.New("registration record not found") // 新增错误：报名记录未找到
+	ErrWaitlistMismatch       = errors.New("registration ids do not match the current waitlist")
+	ErrRegistrationCancelled  = errors.New("registration has already been cancelled")
+	ErrInvalidManageToken     = errors.New("invalid phone or manage token")
+	ErrCancellationClosed     = errors.New("cancellation is no longer allowed for this activity")
+	ErrInvalidSignInCode      = errors.New("invalid or expired sign-in code")
+	ErrRegistrationRejected   = errors.New("registration has been rejected")
+	ErrReviewNotAllowed       = errors.New("registrations can only be reviewed while the activity is open or closed for registration")
+	ErrSignInNotAllowed       = errors.New("sign-ins can only be updated in bulk for published, closed or finished activities")
 )
 
 // 接口：报名业务逻辑接口
@@ -25,93 +38,241 @@ type RegistrationService interface {
 	ListRegistrationsByActivityID(ctx context.Context, activityID uint, page, pageSize int) ([]*model.Registration, int64, error)
 	// 多条件查询报名记录
 	ListRegistrations(ctx context.Context, params *model.ListRegistrationsParams) ([]*model.Registration, int64, error)
+	// 按相同的过滤条件导出全部报名记录 (CSV 或 Excel)，逐批写入 w
+	ExportRegistrations(ctx context.Context, params *model.ListRegistrationsParams, format model.RegistrationExportFormat, w io.Writer) error
 	// SignIn 签到逻辑
-	SignIn(ctx context.Context, activityID uint, phone string, token string) error
+	SignIn(ctx context.Context, activityID uint, phone string, token string, clientIP string) error
+	// SignOut 签退逻辑，计算参与时长 (见 attendance.go)
+	SignOut(ctx context.Context, activityID uint, phone string, token string, clientIP string) error
 	// 获取单条报名记录详情 (新增)
 	GetRegistrationByID(ctx context.Context, registrationID uint) (*model.Registration, error)
 	// UpdateSignInStatusByAdmin 管理员更新签到状态 (新增)
 	UpdateSignInStatusByAdmin(ctx context.Context, registrationID uint, isSignedIn bool) error
+	// BulkUpdateSignInStatus 管理员按报名ID或手机号批量修改签到状态，返回每条的处理结果
+	BulkUpdateSignInStatus(ctx context.Context, activityID uint, req *model.BulkUpdateSignInRequest) ([]model.BulkSignInResult, error)
+	// ImportRegistrations 从 CSV 批量导入报名 (见 registration_import.go)，支持试运行和整体导入
+	ImportRegistrations(ctx context.Context, activityID uint, rows []*RegistrationImportRow, opts *model.ImportOptions) (*model.ImportReport, error)
+	// ImportSignIns 从 CSV 按手机号批量标记签到，支持试运行和整体导入
+	ImportSignIns(ctx context.Context, activityID uint, rows []*SignInImportRow, opts *model.ImportOptions) (*model.ImportReport, error)
+	// CancelRegistration 参与者凭手机号和管理凭证自助取消报名
+	CancelRegistration(ctx context.Context, activityID uint, phone, token string) error
+	// RemoveRegistrationByAdmin 管理员移除报名记录，空出的名额由候补递补
+	RemoveRegistrationByAdmin(ctx context.Context, registrationID uint) error
+	// ListWaitlist 按递补顺序列出活动的候补队列
+	ListWaitlist(ctx context.Context, activityID uint) ([]*model.Registration, error)
+	// ReorderWaitlist 管理员调整候补队列顺序
+	ReorderWaitlist(ctx context.Context, activityID uint, registrationIDs []uint) error
+	// ReviewRegistrations 管理员批量审核待审核的报名，返回每条记录的处理结果
+	ReviewRegistrations(ctx context.Context, activityID uint, registrationIDs []uint, approve bool, reason string) ([]model.ReviewResult, error)
+	// GetMyRegistration 参与者凭手机号和管理凭证查询自己的报名记录
+	GetMyRegistration(ctx context.Context, activityID uint, phone, token string) (*model.Registration, error)
 }
 
 type registrationServiceImpl struct {
 	db               *gorm.DB // 用于启动事务
 	activityRepo     repository.ActivityRepository
 	registrationRepo repository.RegistrationRepository
+	auditRepo        repository.AuditLogRepository     // 记录管理员的修改操作
+	allowlistRepo    repository.AllowlistRepository    // 校验报名名单
+	notificationRepo repository.NotificationRepository // 报名成功、候补递补时通知报名者
+	fastRegistration FastRegistrationService           // 快速报名的 Redis 名额
+	attendanceGuard  *attendanceGuard                  // 签到/签退动态码的错误次数限制
 }
 
 // NewRegistrationService 创建 RegistrationService 实例
-func NewRegistrationService(db *gorm.DB, aRepo repository.ActivityRepository, rRepo repository.RegistrationRepository) RegistrationService {
+func NewRegistrationService(db *gorm.DB, aRepo repository.ActivityRepository, rRepo repository.RegistrationRepository, auditRepo repository.AuditLogRepository, allowlistRepo repository.AllowlistRepository, notificationRepo repository.NotificationRepository, fastRegistration FastRegistrationService) RegistrationService {
 	return &registrationServiceImpl{
 		db:               db,
 		activityRepo:     aRepo,
 		registrationRepo: rRepo,
+		auditRepo:        auditRepo,
+		allowlistRepo:    allowlistRepo,
+		notificationRepo: notificationRepo,
+		fastRegistration: fastRegistration,
+		attendanceGuard:  newAttendanceGuard(),
 	}
 }
 
 // Register 处理参与者报名活动的核心事务逻辑
+// 开启快速报名的活动优先在 Redis 中预占名额，名额已满 (需要候补) 等情况再走数据库通道
 func (s *registrationServiceImpl) Register(ctx context.Context, activityID uint, req *model.CreateRegistrationRequest) (*model.Registration, error) {
+	if registration, handled, err := s.fastRegistration.Reserve(ctx, activityID, req); handled {
+		return registration, err
+	}
+
 	// 用于返回报名响应
-	var newRegistration *model.Registration
+	var (
+		newRegistration *model.Registration
+		activity        *model.Activity
+		occupiesSeat    bool
+	)
 	// 使用自动事务确保报名和人数更新的原子性
 	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		// 1. 获取活动信息 (使用事务锁)
-		activity, err := s.activityRepo.WithTx(tx).FindByIDForUpdate(ctx, activityID)
+		var err error
+		activity, err = s.activityRepo.WithTx(tx).FindByIDForUpdate(ctx, activityID)
 		if err != nil {
 			// 如果活动不存在或数据库错误，事务回滚
 			return ErrActivityNotFound
 		}
 
-		// 2. 状态校验：检查活动是否处于报名中状态
-		if activity.Status != model.ActivityStatusPublished {
-			return ErrRegistrationNotOpen
+		// 2. 状态和时间校验
+		if err := checkRegistrationOpen(activity, time.Now()); err != nil {
+			return err
 		}
 
-		// 3. 时间校验：检查是否过了报名截止时间
-		if time.Now().After(activity.RegistrationDeadline) {
-			return ErrActivityRegistrationOver
-		}
+		// 3. 校验并保存报名记录
+		newRegistration, occupiesSeat, err = s.registerInTx(ctx, tx, activity, req)
+		return err
+	})
 
-		// 4. 重复报名校验：检查该手机号是否已报名
-		existingReg, err := s.registrationRepo.WithTx(tx).FindByActivityAndPhone(ctx, activityID, req.ParticipantPhone)
-		if err != nil {
-			return err
-		}
-		if existingReg != nil {
-			return ErrRegistrationDuplicate
-		}
+	if err != nil {
+		return nil, err
+	}
+
+	// 同步到快速报名的 Redis 名额
+	seats := 0
+	if occupiesSeat {
+		seats = 1
+	}
+	s.fastRegistration.SeatOccupied(ctx, activity, req.ParticipantPhone, seats)
+
+	return newRegistration, nil
+}
+
+// checkRegistrationOpen 检查活动是否处于报名中状态、是否到了报名开放时间、是否过了报名截止时间
+func checkRegistrationOpen(activity *model.Activity, now time.Time) error {
+	if activity.Status != model.ActivityStatusPublished {
+		return ErrRegistrationNotOpen
+	}
+	if activity.RegistrationStateAt(now) == model.RegistrationStateUpcoming {
+		return ErrRegistrationNotOpenYet
+	}
+	if now.After(activity.RegistrationDeadline) {
+		return ErrActivityRegistrationOver
+	}
+	return nil
+}
+
+// registerInTx 报名的事务内实现：校验报名表答案、重复报名和报名资格，按活动设置确定报名状态 (已确认、候补、待审核或待抽签)，
+// 保存报名记录并更新活动已报名人数。返回报名记录以及是否占用了名额。
+// 必须在已通过 FindByIDForUpdate 锁定活动行、且已通过 checkRegistrationOpen 校验的事务中调用
+func (s *registrationServiceImpl) registerInTx(ctx context.Context, tx *gorm.DB, activity *model.Activity, req *model.CreateRegistrationRequest) (*model.Registration, bool, error) {
+	activityID := activity.ID
+
+	// 1. 校验报名表答案
+	answers, err := validateFormAnswers(activity.FormSchema, req.Answers)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// 2. 重复报名校验：已取消的记录允许重新报名 (复用原记录以满足唯一索引)
+	existingReg, err := s.registrationRepo.WithTx(tx).FindByActivityAndPhone(ctx, activityID, req.ParticipantPhone)
+	if err != nil {
+		return nil, false, err
+	}
+	if existingReg != nil && existingReg.Status == model.RegistrationStatusRejected {
+		return nil, false, ErrRegistrationRejected
+	}
+	if existingReg != nil && existingReg.Status != model.RegistrationStatusCancelled {
+		return nil, false, ErrRegistrationDuplicate
+	}
+
+	// 3. 报名资格校验：学院和报名名单
+	if err := checkEligibility(ctx, s.allowlistRepo.WithTx(tx), activity, req); err != nil {
+		return nil, false, err
+	}
+
+	// 4. 构造报名记录
+	registration := prepareRegistration(existingReg, activityID, req, time.Now())
+	registration.Answers = answers
+
+	// 生成自助管理凭证，数据库只保存哈希
+	manageToken, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return nil, false, err
+	}
+	registration.ManageTokenHash = utils.HashToken(manageToken)
+	registration.ManageToken = manageToken
 
-		// 5. 人数上限校验
-		if activity.MaxParticipants > 0 && activity.RegisteredCount >= activity.MaxParticipants {
-			return ErrRegistrationMaxed
+	// 5. 需要审核的活动：进入待审核状态，不占用名额，审核通过时再校验学院名额和人数上限
+	// 尚未开奖的抽签活动：进入待抽签状态，截止报名时统一抽签分配名额
+	occupiesSeat := false
+	if activity.RequiresApproval {
+		registration.Status = model.RegistrationStatusPending
+	} else if activity.Lottery && activity.LotteryDrawnAt == nil {
+		registration.Status = model.RegistrationStatusApplied
+	} else {
+		// 学院名额校验：学院名额已满时直接拒绝，不进入候补队列
+		if err := checkCollegeQuota(ctx, s.registrationRepo.WithTx(tx), activity, registration.ParticipantCollege); err != nil {
+			return nil, false, err
 		}
 
-		// 6. 创建报名记录
-		registration := &model.Registration{
-			ActivityID:         activityID,
-			ParticipantName:    req.ParticipantName,
-			ParticipantPhone:   req.ParticipantPhone,
-			ParticipantCollege: req.ParticipantCollege,
+		// 6. 人数上限校验：名额已满时进入候补队列 (快速报名活动需计入尚未落库的预占名额)
+		if err := s.fastRegistration.FillPendingSeats(ctx, activity); err != nil {
+			return nil, false, err
 		}
-		if err := s.registrationRepo.WithTx(tx).Create(ctx, registration); err != nil {
-			return err
+		occupiesSeat = hasFreeSeat(activity)
+		if !occupiesSeat {
+			position, err := s.registrationRepo.WithTx(tx).NextWaitlistPosition(ctx, activityID)
+			if err != nil {
+				return nil, false, err
+			}
+			registration.Status = model.RegistrationStatusWaitlisted
+			registration.WaitlistPosition = position
 		}
-		newRegistration = registration // 记录新创建的报名对象以便返回
+	}
 
-		// 7. 更新活动已报名人数 (核心更新)
-		activity.RegisteredCount += 1
-		if err := s.activityRepo.WithTx(tx).Update(ctx, activity); err != nil {
-			return err
-		}
+	// 7. 保存报名记录 (新建或复用已取消的记录)
+	if registration.ID == 0 {
+		err = s.registrationRepo.WithTx(tx).Create(ctx, registration)
+	} else {
+		err = s.registrationRepo.WithTx(tx).Update(ctx, registration)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if err := enqueueNotifications(ctx, s.notificationRepo.WithTx(tx), model.NotificationEventRegistered, activity, registration); err != nil {
+		return nil, false, err
+	}
 
-		// 事务提交
-		return nil
-	})
+	// 候补、待审核和待抽签不占用名额，无需更新活动人数
+	if !occupiesSeat {
+		return registration, false, nil
+	}
 
-	if err != nil {
-		return nil, err
+	// 8. 更新活动已报名人数 (核心更新)
+	activity.RegisteredCount += 1
+	if err := s.activityRepo.WithTx(tx).Update(ctx, activity); err != nil {
+		return nil, false, err
 	}
+	return registration, true, nil
+}
 
-	return newRegistration, nil
+// prepareRegistration 用报名请求填充报名记录 (新建或复用已取消的记录)，状态为已确认
+// 报名表答案须已通过 validateFormAnswers 校验，管理凭证由调用方设置
+func prepareRegistration(existing *model.Registration, activityID uint, req *model.CreateRegistrationRequest, registeredAt time.Time) *model.Registration {
+	registration := existing
+	if registration == nil {
+		registration = &model.Registration{ActivityID: activityID}
+	}
+	registration.ParticipantName = req.ParticipantName
+	registration.ParticipantPhone = req.ParticipantPhone
+	registration.ParticipantCollege = strings.TrimSpace(req.ParticipantCollege)
+	registration.ParticipantStudentID = strings.TrimSpace(req.ParticipantStudentID)
+	registration.ParticipantEmail = strings.TrimSpace(req.ParticipantEmail)
+	registration.Answers = req.Answers
+	registration.Status = model.RegistrationStatusConfirmed
+	registration.WaitlistPosition = 0
+	registration.RegisteredAt = registeredAt
+	registration.IsSignedIn = false
+	registration.SignedInAt = nil
+	registration.CancelledAt = nil
+	registration.CancelledBy = ""
+	registration.ReviewedAt = nil
+	registration.ReviewedBy = 0
+	registration.ReviewReason = ""
+	return registration
 }
 
 // ListRegistrationsByActivityID 列出某个活动的报名记录
@@ -143,26 +304,156 @@ func (s *registrationServiceImpl) GetRegistrationByID(ctx context.Context, regis
 
 // UpdateSignInStatusByAdmin 管理员更新签到状态实现
 func (s *registrationServiceImpl) UpdateSignInStatusByAdmin(ctx context.Context, registrationID uint, isSignedIn bool) error {
-	// 1. 检查报名记录是否存在
-	reg, err := s.registrationRepo.FindByID(ctx, registrationID)
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return ErrRegistrationNotFound
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 1. 检查报名记录是否存在
+		reg, err := s.registrationRepo.WithTx(tx).FindByID(ctx, registrationID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrRegistrationNotFound
+			}
+			return err
 		}
-		return err
-	}
 
-	// 2. 如果已经是目标状态，无需更新
-	if reg.IsSignedIn == isSignedIn {
+		// 2. 如果已经是目标状态，无需更新
+		if reg.IsSignedIn == isSignedIn {
+			return nil
+		}
+
+		// 3. 更新签到状态 (取消签到时同时清除签退记录)
+		activity, err := s.activityRepo.WithTx(tx).FindByID(ctx, reg.ActivityID)
+		if err != nil {
+			return ErrActivityNotFound
+		}
+		return s.updateSignInInTx(ctx, tx, activity, reg, isSignedIn, time.Now())
+	})
+}
+
+// BulkUpdateSignInStatus 在一个事务中批量修改签到状态：先处理报名ID，再处理手机号 (对应到同一条报名的只处理一次)
+// 签到时所有记录使用同一个签到时间；只有已确认的报名可以签到，取消签到不限报名状态；已是目标状态的记录不做修改
+func (s *registrationServiceImpl) BulkUpdateSignInStatus(ctx context.Context, activityID uint, req *model.BulkUpdateSignInRequest) ([]model.BulkSignInResult, error) {
+	isSignedIn := *req.IsSignedIn
+	now := time.Now()
+	var results []model.BulkSignInResult
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 1. 锁定活动行，与同一活动的其他批量操作串行执行
+		activity, err := s.activityRepo.WithTx(tx).FindByIDForUpdate(ctx, activityID)
+		if err != nil {
+			return ErrActivityNotFound
+		}
+		if err := checkSignInAllowed(activity); err != nil {
+			return err
+		}
+
+		registrationRepo := s.registrationRepo.WithTx(tx)
+		results = make([]model.BulkSignInResult, 0, len(req.RegistrationIDs)+len(req.Phones))
+		seen := make(map[uint]bool)
+		update := func(result model.BulkSignInResult, reg *model.Registration) error {
+			if seen[reg.ID] {
+				return nil
+			}
+			seen[reg.ID] = true
+			result.RegistrationID = reg.ID
+
+			// 2. 已是目标状态的记录视为成功，不修改签到时间
+			switch {
+			case reg.IsSignedIn == isSignedIn:
+			case isSignedIn && reg.Status != model.RegistrationStatusConfirmed:
+				result.Error = "报名尚未确认，不能签到"
+				results = append(results, result)
+				return nil
+			default:
+				if err := s.updateSignInInTx(ctx, tx, activity, reg, isSignedIn, now); err != nil {
+					return err
+				}
+				result.Changed = true
+			}
+			result.Success = true
+			result.IsSignedIn = reg.IsSignedIn
+			result.SignedInAt = reg.SignedInAt
+			results = append(results, result)
+			return nil
+		}
+
+		// 3. 按报名ID
+		for _, id := range req.RegistrationIDs {
+			if seen[id] {
+				continue
+			}
+			reg, err := registrationRepo.FindByID(ctx, id)
+			if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && reg.ActivityID != activityID) {
+				seen[id] = true
+				results = append(results, model.BulkSignInResult{RegistrationID: id, Error: "报名记录不存在"})
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if err := update(model.BulkSignInResult{}, reg); err != nil {
+				return err
+			}
+		}
+
+		// 4. 按手机号
+		seenPhones := make(map[string]bool, len(req.Phones))
+		for _, phone := range req.Phones {
+			phone = strings.TrimSpace(phone)
+			if seenPhones[phone] {
+				continue
+			}
+			seenPhones[phone] = true
+			reg, err := registrationRepo.FindByActivityAndPhone(ctx, activityID, phone)
+			if err != nil {
+				return err
+			}
+			if reg == nil || reg.Status == model.RegistrationStatusCancelled {
+				results = append(results, model.BulkSignInResult{Phone: phone, Error: "未找到该手机号的报名记录"})
+				continue
+			}
+			if err := update(model.BulkSignInResult{Phone: phone}, reg); err != nil {
+				return err
+			}
+		}
 		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return results, nil
+}
 
-	// 3. 更新签到状态
-	now := time.Now()
-	return s.registrationRepo.UpdateSignInStatus(ctx, registrationID, isSignedIn, now)
+// checkSignInAllowed 批量修改或导入签到只适用于已发布、已截止报名或已结束的活动
+func checkSignInAllowed(activity *model.Activity) error {
+	if activity.Status == model.ActivityStatusDraft || activity.Status == model.ActivityStatusCancelled {
+		return ErrSignInNotAllowed
+	}
+	return nil
+}
+
+// updateSignInInTx 管理员修改签到状态并记录审计日志，签到时按 signedInAt 判断是否迟到
+// 取消签到时清空签到时间 (忽略 signedInAt)，同时清除签退记录
+func (s *registrationServiceImpl) updateSignInInTx(ctx context.Context, tx *gorm.DB, activity *model.Activity, reg *model.Registration, isSignedIn bool, signedInAt time.Time) error {
+	before := auditSnapshot(reg)
+	var at *time.Time
+	isLate := false
+	if isSignedIn {
+		at = &signedInAt
+		isLate = isLateSignIn(activity, signedInAt)
+	}
+	if err := s.registrationRepo.WithTx(tx).UpdateSignInStatus(ctx, reg.ID, isSignedIn, at, isLate); err != nil {
+		return err
+	}
+	reg.IsSignedIn = isSignedIn
+	reg.SignedInAt = at
+	reg.IsLate = isLate
+	if !isSignedIn {
+		reg.SignedOutAt = nil
+		reg.LeftEarly = false
+		reg.AttendedSeconds = 0
+	}
+	return recordAudit(ctx, s.auditRepo.WithTx(tx), model.AuditActionRegistrationSignIn, model.AuditTargetRegistration, reg.ID, before, auditSnapshot(reg))
 }
 
-func (s *registrationServiceImpl) SignIn(ctx context.Context, activityID uint, phone string, token string) error {
+func (s *registrationServiceImpl) SignIn(ctx context.Context, activityID uint, phone string, token string, clientIP string) error {
 	// 1. 检查活动是否正在进行中
 	activity, err := s.activityRepo.FindByID(ctx, activityID)
 	if err != nil {
@@ -172,11 +463,20 @@ func (s *registrationServiceImpl) SignIn(ctx context.Context, activityID uint, p
 		return errors.New("查询活动失败: " + err.Error())
 	}
 
+	if activity.Status == model.ActivityStatusCancelled {
+		return errors.New("活动已取消，无法签到")
+	}
+
 	now := time.Now()
 	if now.Before(activity.StartTime) || now.After(activity.EndTime) {
 		return errors.New("当前时间不在活动时间范围内，无法签到")
 	}
 
+	// 校验大屏二维码中的动态码，允许少量时钟偏差；错误次数过多时临时锁定
+	if err := s.checkAttendanceCode(ctx, activity.ID, phone, clientIP, activity.SignInSecret, token, now, ErrInvalidSignInCode); err != nil {
+		return err
+	}
+
 	// 2. 查找报名记录
 	reg, err := s.registrationRepo.FindByActivityAndPhone(ctx, activityID, phone)
 	if err != nil {
@@ -189,15 +489,404 @@ func (s *registrationServiceImpl) SignIn(ctx context.Context, activityID uint, p
 		return errors.New("报名记录未找到或手机号错误")
 	}
 
-	// 3. 检查是否已签到
+	// 3. 候补中的报名不能签到
+	if reg.Status != model.RegistrationStatusConfirmed {
+		return errors.New("您的报名尚未确认，无法签到")
+	}
+
+	// 4. 检查是否已签到
 	if reg.IsSignedIn {
 		return errors.New("您已签到，请勿重复操作")
 	}
 
-	// 4. 更新签到状态和时间
-	err = s.registrationRepo.UpdateSignInStatus(ctx, reg.ID, true, now)
+	// 5. 更新签到状态和时间
+	err = s.registrationRepo.UpdateSignInStatus(ctx, reg.ID, true, &now, isLateSignIn(activity, now))
 	if err != nil {
 		return errors.New("更新签到状态失败: " + err.Error())
 	}
 	return nil
 }
+
+// CancelRegistration 参与者自助取消报名
+// 校验手机号与管理凭证，活动开始前 CancelCutoff 之后不再允许取消
+func (s *registrationServiceImpl) CancelRegistration(ctx context.Context, activityID uint, phone, token string) error {
+	var (
+		activity *model.Activity
+		freed    int
+	)
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 1. 锁定活动行
+		var err error
+		activity, err = s.activityRepo.WithTx(tx).FindByIDForUpdate(ctx, activityID)
+		if err != nil {
+			return ErrActivityNotFound
+		}
+
+		// 2. 取消截止时间校验
+		cutoff := activity.StartTime.Add(-config.GlobalConfig.Registration.CancelCutoff)
+		if time.Now().After(cutoff) {
+			return ErrCancellationClosed
+		}
+
+		// 3. 校验报名归属：手机号 + 管理凭证
+		reg, err := s.registrationRepo.WithTx(tx).FindByActivityAndPhone(ctx, activityID, phone)
+		if err != nil {
+			return err
+		}
+		if reg == nil || !utils.CheckTokenHash(token, reg.ManageTokenHash) {
+			return ErrInvalidManageToken
+		}
+
+		// 4. 取消并释放名额
+		freed, err = s.cancelInTx(ctx, tx, activity, reg, model.CancelledByParticipant)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	s.fastRegistration.SeatReleased(ctx, activity, phone, freed)
+	return nil
+}
+
+// RemoveRegistrationByAdmin 管理员移除报名记录
+// 在活动行锁内将记录标记为已取消并扣减人数，随后由候补队列递补空位
+func (s *registrationServiceImpl) RemoveRegistrationByAdmin(ctx context.Context, registrationID uint) error {
+	// 1. 先查出报名记录，确定需要锁定的活动
+	reg, err := s.registrationRepo.FindByID(ctx, registrationID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrRegistrationNotFound
+		}
+		return err
+	}
+
+	var (
+		activity *model.Activity
+		freed    int
+	)
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 2. 锁定活动行
+		var err error
+		activity, err = s.activityRepo.WithTx(tx).FindByIDForUpdate(ctx, reg.ActivityID)
+		if err != nil {
+			return ErrActivityNotFound
+		}
+
+		// 3. 在锁内重新读取报名记录，避免并发修改
+		reg, err := s.registrationRepo.WithTx(tx).FindByID(ctx, registrationID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrRegistrationNotFound
+			}
+			return err
+		}
+
+		// 4. 取消并释放名额
+		before := auditSnapshot(reg)
+		if freed, err = s.cancelInTx(ctx, tx, activity, reg, model.CancelledByAdmin); err != nil {
+			return err
+		}
+		return recordAudit(ctx, s.auditRepo.WithTx(tx), model.AuditActionRegistrationRemove, model.AuditTargetRegistration, registrationID, before, auditSnapshot(reg))
+	})
+	if err != nil {
+		return err
+	}
+	s.fastRegistration.SeatReleased(ctx, activity, reg.ParticipantPhone, freed)
+	return nil
+}
+
+// cancelInTx 将报名记录标记为已取消，占用名额的记录会扣减人数并触发候补递补
+// 返回空出且未被候补递补的名额数；必须在已锁定活动行的事务中调用
+func (s *registrationServiceImpl) cancelInTx(ctx context.Context, tx *gorm.DB, activity *model.Activity, reg *model.Registration, cancelledBy string) (int, error) {
+	if reg.Status == model.RegistrationStatusCancelled {
+		return 0, ErrRegistrationCancelled
+	}
+	if reg.Status == model.RegistrationStatusRejected {
+		return 0, ErrRegistrationRejected
+	}
+
+	occupiedSeat := reg.Status == model.RegistrationStatusConfirmed
+
+	// 1. 保留记录，仅标记取消
+	now := time.Now()
+	reg.Status = model.RegistrationStatusCancelled
+	reg.WaitlistPosition = 0
+	reg.CancelledAt = &now
+	reg.CancelledBy = cancelledBy
+	if err := s.registrationRepo.WithTx(tx).Update(ctx, reg); err != nil {
+		return 0, err
+	}
+
+	// 2. 候补记录不占名额，直接结束
+	if !occupiedSeat {
+		return 0, nil
+	}
+
+	// 3. 释放名额并递补 (快速报名活动需计入尚未落库的预占名额)
+	activity.RegisteredCount -= 1
+	if err := s.fastRegistration.FillPendingSeats(ctx, activity); err != nil {
+		return 0, err
+	}
+	promoted, err := fillSeatsFromWaitlist(ctx, s.registrationRepo.WithTx(tx), activity)
+	if err != nil {
+		return 0, err
+	}
+	if err := enqueueNotifications(ctx, s.notificationRepo.WithTx(tx), model.NotificationEventPromoted, activity, promoted...); err != nil {
+		return 0, err
+	}
+	if err := s.activityRepo.WithTx(tx).Update(ctx, activity); err != nil {
+		return 0, err
+	}
+	return 1 - len(promoted), nil
+}
+
+// ListWaitlist 按递补顺序列出候补队列
+func (s *registrationServiceImpl) ListWaitlist(ctx context.Context, activityID uint) ([]*model.Registration, error) {
+	if _, err := s.activityRepo.FindByID(ctx, activityID); err != nil {
+		return nil, ErrActivityNotFound
+	}
+	return s.registrationRepo.ListWaitlist(ctx, activityID)
+}
+
+// ReorderWaitlist 按传入的ID顺序重排候补队列
+// registrationIDs 必须与当前候补队列完全一致 (仅顺序不同)，否则返回 ErrWaitlistMismatch
+func (s *registrationServiceImpl) ReorderWaitlist(ctx context.Context, activityID uint, registrationIDs []uint) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 1. 锁定活动行，防止重排期间发生递补
+		if _, err := s.activityRepo.WithTx(tx).FindByIDForUpdate(ctx, activityID); err != nil {
+			return ErrActivityNotFound
+		}
+
+		// 2. 校验传入的ID集合与当前候补队列一致
+		waitlist, err := s.registrationRepo.WithTx(tx).ListWaitlist(ctx, activityID)
+		if err != nil {
+			return err
+		}
+		if len(waitlist) != len(registrationIDs) {
+			return ErrWaitlistMismatch
+		}
+		byID := make(map[uint]*model.Registration, len(waitlist))
+		for _, reg := range waitlist {
+			byID[reg.ID] = reg
+		}
+
+		// 3. 按新顺序写入候补序号
+		oldOrder := make([]uint, len(waitlist))
+		for i, reg := range waitlist {
+			oldOrder[i] = reg.ID
+		}
+		for i, id := range registrationIDs {
+			reg, ok := byID[id]
+			if !ok {
+				return ErrWaitlistMismatch
+			}
+			delete(byID, id) // 防止重复ID
+			reg.WaitlistPosition = i + 1
+			if err := s.registrationRepo.WithTx(tx).Update(ctx, reg); err != nil {
+				return err
+			}
+		}
+		return recordAudit(ctx, s.auditRepo.WithTx(tx), model.AuditActionWaitlistReorder, model.AuditTargetActivity, activityID,
+			map[string]any{"waitlist": oldOrder}, map[string]any{"waitlist": registrationIDs})
+	})
+}
+
+// ReviewRegistrations 在活动行锁内逐条审核待审核的报名
+// 审核通过需要满足学院名额和人数上限，不满足的记录保持待审核状态并在结果中说明原因；
+// 单条记录失败不影响其他记录，整体只在数据库错误时回滚
+func (s *registrationServiceImpl) ReviewRegistrations(ctx context.Context, activityID uint, registrationIDs []uint, approve bool, reason string) ([]model.ReviewResult, error) {
+	var (
+		activity *model.Activity
+		results  []model.ReviewResult
+		approved int
+	)
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 1. 锁定活动行，审核期间人数不会被其他请求修改
+		var err error
+		activity, err = s.activityRepo.WithTx(tx).FindByIDForUpdate(ctx, activityID)
+		if err != nil {
+			return ErrActivityNotFound
+		}
+		if activity.Status != model.ActivityStatusPublished && activity.Status != model.ActivityStatusClosed {
+			return ErrReviewNotAllowed
+		}
+		if approve {
+			if err := s.fastRegistration.FillPendingSeats(ctx, activity); err != nil {
+				return err
+			}
+		}
+
+		action, status := model.AuditActionRegistrationReject, model.RegistrationStatusRejected
+		if approve {
+			action, status = model.AuditActionRegistrationApprove, model.RegistrationStatusConfirmed
+		}
+		reviewer := auditActorFromContext(ctx).AdminID
+
+		// 2. 逐条审核 (同一请求中重复的ID只处理一次)
+		results = make([]model.ReviewResult, 0, len(registrationIDs))
+		seen := make(map[uint]bool, len(registrationIDs))
+		for _, id := range registrationIDs {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			result := model.ReviewResult{RegistrationID: id}
+
+			reg, err := s.registrationRepo.WithTx(tx).FindByID(ctx, id)
+			if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && reg.ActivityID != activityID) {
+				result.Error = "报名记录不存在"
+				results = append(results, result)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if reg.Status != model.RegistrationStatusPending {
+				result.Error = "该报名不是待审核状态"
+				results = append(results, result)
+				continue
+			}
+
+			// 3. 审核通过需要占用名额
+			if approve {
+				full, err := collegeQuotaFull(ctx, s.registrationRepo.WithTx(tx), activity, reg.ParticipantCollege)
+				if err != nil {
+					return err
+				}
+				if full {
+					result.Error = reg.ParticipantCollege + "的报名名额已满"
+					results = append(results, result)
+					continue
+				}
+				if !hasFreeSeat(activity) {
+					result.Error = "活动名额已满"
+					results = append(results, result)
+					continue
+				}
+			}
+
+			// 4. 更新报名记录并记录审计日志
+			before := auditSnapshot(reg)
+			now := time.Now()
+			reg.Status = status
+			reg.ReviewedAt = &now
+			reg.ReviewedBy = reviewer
+			reg.ReviewReason = reason
+			if err := s.registrationRepo.WithTx(tx).Update(ctx, reg); err != nil {
+				return err
+			}
+			if err := recordAudit(ctx, s.auditRepo.WithTx(tx), action, model.AuditTargetRegistration, id, before, auditSnapshot(reg)); err != nil {
+				return err
+			}
+			if approve {
+				activity.RegisteredCount += 1
+				approved++
+			}
+			result.Success = true
+			result.Status = status
+			results = append(results, result)
+		}
+
+		// 5. 更新活动已报名人数
+		if approved == 0 {
+			return nil
+		}
+		return s.activityRepo.WithTx(tx).Update(ctx, activity)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 同步到快速报名的 Redis 名额
+	if activity.MaxParticipants > 0 {
+		s.fastRegistration.AdjustSeats(ctx, activity, -approved)
+	}
+	return results, nil
+}
+
+// GetMyRegistration 校验手机号与管理凭证后返回报名记录
+// 快速报名尚未落库的记录查询不到，返回 ErrInvalidManageToken
+func (s *registrationServiceImpl) GetMyRegistration(ctx context.Context, activityID uint, phone, token string) (*model.Registration, error) {
+	reg, err := s.registrationRepo.FindByActivityAndPhone(ctx, activityID, phone)
+	if err != nil {
+		return nil, err
+	}
+	if reg == nil || !utils.CheckTokenHash(token, reg.ManageTokenHash) {
+		return nil, ErrInvalidManageToken
+	}
+	return reg, nil
+}
+
+// hasFreeSeat 判断活动是否还有空余名额 (MaxParticipants 为 0 表示不限制)
+// 快速报名已预占但尚未落库的名额 (PendingSeats) 同样视为已占用
+func hasFreeSeat(activity *model.Activity) bool {
+	return activity.MaxParticipants == 0 || activity.RegisteredCount+activity.PendingSeats < activity.MaxParticipants
+}
+
+// fillSeatsFromWaitlist 按候补顺序递补空余名额
+// 必须在已通过 FindByIDForUpdate 锁定活动行的事务中调用，registrationRepo 需绑定同一事务。
+// 该函数只修改内存中的 activity.RegisteredCount，由调用方负责持久化活动记录。
+func fillSeatsFromWaitlist(ctx context.Context, registrationRepo repository.RegistrationRepository, activity *model.Activity) ([]*model.Registration, error) {
+	// 已结束的活动不再递补
+	if activity.Status != model.ActivityStatusPublished && activity.Status != model.ActivityStatusClosed {
+		return nil, nil
+	}
+	if hasCollegeQuotas(activity) {
+		return fillSeatsWithCollegeQuotas(ctx, registrationRepo, activity)
+	}
+
+	var promoted []*model.Registration
+	for hasFreeSeat(activity) {
+		next, err := registrationRepo.FindFirstWaitlisted(ctx, activity.ID)
+		if err != nil {
+			return nil, err
+		}
+		if next == nil {
+			break // 候补队列已空
+		}
+
+		next.Status = model.RegistrationStatusConfirmed
+		next.WaitlistPosition = 0
+		if err := registrationRepo.Update(ctx, next); err != nil {
+			return nil, err
+		}
+		activity.RegisteredCount += 1
+		promoted = append(promoted, next)
+	}
+	return promoted, nil
+}
+
+// fillSeatsWithCollegeQuotas 设置了学院名额的活动按候补顺序递补，跳过所在学院名额已满的候补记录
+// 调用约定同 fillSeatsFromWaitlist
+func fillSeatsWithCollegeQuotas(ctx context.Context, registrationRepo repository.RegistrationRepository, activity *model.Activity) ([]*model.Registration, error) {
+	if !hasFreeSeat(activity) {
+		return nil, nil
+	}
+	waitlist, err := registrationRepo.ListWaitlist(ctx, activity.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var promoted []*model.Registration
+	for _, next := range waitlist {
+		if !hasFreeSeat(activity) {
+			break
+		}
+		full, err := collegeQuotaFull(ctx, registrationRepo, activity, next.ParticipantCollege)
+		if err != nil {
+			return nil, err
+		}
+		if full {
+			continue // 保留候补，等待该学院空出名额
+		}
+
+		next.Status = model.RegistrationStatusConfirmed
+		next.WaitlistPosition = 0
+		if err := registrationRepo.Update(ctx, next); err != nil {
+			return nil, err
+		}
+		activity.RegisteredCount += 1
+		promoted = append(promoted, next)
+	}
+	return promoted, nil
+}
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/frozenf1sh/gostudent/internal/repository"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB 创建迁移好表结构的 SQLite 内存数据库，每个测试独立
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repository.AutoMigrate(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
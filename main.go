@@ -2,16 +2,19 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"os"
 	"strconv"
 
 	"github.com/frozenf1sh/gostudent/internal/config"
 	"github.com/frozenf1sh/gostudent/internal/handler"
+	"github.com/frozenf1sh/gostudent/internal/model"
 	"github.com/frozenf1sh/gostudent/internal/repository"
 	"github.com/frozenf1sh/gostudent/internal/router"
 	"github.com/frozenf1sh/gostudent/internal/service"
 	"github.com/frozenf1sh/gostudent/pkg/fishlogger"
+	"github.com/frozenf1sh/gostudent/pkg/mail"
 	"github.com/frozenf1sh/gostudent/pkg/redis"
 	"github.com/frozenf1sh/gostudent/pkg/utils"
 	"github.com/gin-gonic/gin"
@@ -55,17 +58,32 @@ func main() {
 	adminRepo := repository.NewAdminRepository(db)
 	activityRepo := repository.NewActivityRepository(db)
 	registrationRepo := repository.NewRegistrationRepository(db)
+	auditRepo := repository.NewAuditLogRepository(db)
+	allowlistRepo := repository.NewAllowlistRepository(db)
+	lotteryRepo := repository.NewLotteryRepository(db)
+	creditRepo := repository.NewCreditRepository(db)
+	certificateRepo := repository.NewCertificateRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db)
 
 	// 注入 Services
-	adminSvc := service.NewAdminService(adminRepo)
-	activitySvc := service.NewActivityService(db, activityRepo)                           // ActivityService 需要 db 来处理事务
-	registrationSvc := service.NewRegistrationService(db, activityRepo, registrationRepo) // RegistrationService 涉及活动和报名两个 Repo
+	fastRegistrationSvc := service.NewFastRegistrationService(db, activityRepo, registrationRepo, allowlistRepo, notificationRepo) // 快速报名的 Redis 名额，活动和报名两个 Service 共用
+	adminSvc := service.NewAdminService(db, adminRepo, auditRepo)
+	activitySvc := service.NewActivityService(db, activityRepo, registrationRepo, adminRepo, auditRepo, allowlistRepo, lotteryRepo, creditRepo, notificationRepo, fastRegistrationSvc) // ActivityService 需要 db 来处理事务，并在扩容时递补候补
+	registrationSvc := service.NewRegistrationService(db, activityRepo, registrationRepo, auditRepo, allowlistRepo, notificationRepo, fastRegistrationSvc)                             // RegistrationService 涉及活动和报名两个 Repo
+	auditSvc := service.NewAuditService(auditRepo)
+	creditSvc := service.NewCreditService(db, creditRepo, auditRepo)
+	certificateSvc := service.NewCertificateService(db, activityRepo, registrationRepo, certificateRepo, auditRepo)
+	notificationSvc := service.NewNotificationService(db, notificationRepo, auditRepo, initNotificationSenders())
 
 	// 注入 Handlers
 	adminH := handler.NewAdminHandler(adminSvc)
 	activityH := handler.NewActivityHandler(activitySvc)
 	registrationH := handler.NewRegistrationHandler(registrationSvc)
 	dashboardH := handler.NewDashboardHandler(db, activityRepo, registrationRepo)
+	auditH := handler.NewAuditHandler(auditSvc)
+	creditH := handler.NewCreditHandler(creditSvc)
+	certificateH := handler.NewCertificateHandler(certificateSvc)
+	notificationH := handler.NewNotificationHandler(notificationSvc)
 
 	// 初始化超级管理员
 	initSuperAdmin(adminSvc)
@@ -73,10 +91,18 @@ func main() {
 	// 启动活动状态自动更新任务
 	activitySvc.StartActivityStatusUpdater(context.Background(), config.GlobalConfig.ActivityStatusUpdateInterval)
 
+	// 启动快速报名的落库和名额校对任务
+	fastRegistrationSvc.StartWorkers(context.Background())
+
+	// 启动通知发送任务
+	if config.GlobalConfig.Notification.Enabled {
+		notificationSvc.StartWorker(context.Background())
+	}
+
 	// Web服务
 	gin.SetMode(gin.ReleaseMode)
 	// 创建路由
-	r = router.InitRouter(adminH, activityH, registrationH, dashboardH)
+	r = router.InitRouter(adminH, activityH, registrationH, dashboardH, auditH, creditH, certificateH, notificationH, adminSvc, activitySvc, registrationSvc)
 
 	// 监听host和端口
 	var (
@@ -92,7 +118,7 @@ func main() {
 func initSuperAdmin(adminSvc service.AdminService) {
 	defaultUsername := config.GlobalConfig.Admin.Username // 假设配置中有这个字段
 	defaultPassword := config.GlobalConfig.Admin.Password
-	admin, _ := adminSvc.GetByID(context.Background(), 1)
+	admin, _ := adminSvc.GetByID(context.Background(), service.DefaultSuperAdminID)
 	if admin == nil {
 		slog.Info("未找到任何管理员，开始创建默认超级管理员...")
 
@@ -102,13 +128,66 @@ func initSuperAdmin(adminSvc service.AdminService) {
 			os.Exit(1)
 		}
 
-		err := adminSvc.CreateAdmin(context.Background(), defaultUsername, defaultPassword)
+		_, err := adminSvc.CreateAdmin(context.Background(), defaultUsername, defaultPassword, model.AdminRoleSuper)
+		if errors.Is(err, service.ErrUsernameTaken) {
+			// 旧版本允许删除默认超级管理员，同名账号已存在时沿用该账号，不用默认密码覆盖
+			slog.Warn("默认超级管理员不存在，但已有同名账号，跳过创建", "username", defaultUsername)
+			return
+		}
 		if err != nil {
 			slog.Error("创建默认超级管理员失败", "reason", err)
 			os.Exit(1)
 		}
 		slog.Info("默认超级管理员创建成功", "username", defaultUsername)
+	} else if admin.Role != model.AdminRoleSuper {
+		// 引入角色之前创建的默认管理员迁移后为默认角色，这里将其升级为超级管理员
+		if err := adminSvc.SetRole(context.Background(), admin.ID, model.AdminRoleSuper); err != nil {
+			slog.Error("升级默认超级管理员角色失败", "reason", err)
+			os.Exit(1)
+		}
+		slog.Info("已将默认管理员升级为超级管理员", "username", admin.Username)
 	} else {
 		slog.Info("超级管理员已存在")
 	}
 }
+
+// initNotificationSenders 创建各通知渠道的发送实现
+// 未配置 SMTP 服务器时启动进程内的 SMTP 服务器替身，邮件内容只输出到日志，便于本地开发
+func initNotificationSenders() map[model.NotificationChannel]service.NotificationSender {
+	senders := make(map[model.NotificationChannel]service.NotificationSender)
+	if !config.GlobalConfig.Notification.Enabled {
+		return senders
+	}
+
+	smtpCfg := config.GlobalConfig.Notification.SMTP
+	cfg := mail.SMTPConfig{
+		Host:        smtpCfg.Host,
+		Port:        smtpCfg.Port,
+		Username:    smtpCfg.Username,
+		Password:    smtpCfg.Password,
+		From:        smtpCfg.From,
+		ImplicitTLS: smtpCfg.ImplicitTLS,
+	}
+	if cfg.Host == "" {
+		local, err := mail.StartLocalServer("127.0.0.1:0", func(msg mail.Message) {
+			slog.Info("本地 SMTP 收到邮件", "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+		})
+		if err != nil {
+			slog.Error("本地 SMTP 服务器启动失败", "reason", err)
+			os.Exit(1)
+		}
+		slog.Warn("未配置 SMTP 服务器，邮件通知只输出到日志", "addr", local.Addr().String())
+		cfg = mail.SMTPConfig{Host: "127.0.0.1", Port: local.Addr().Port, From: cfg.From}
+		if cfg.From == "" {
+			cfg.From = "noreply@localhost"
+		}
+	}
+
+	sender, err := mail.NewSMTPSender(cfg)
+	if err != nil {
+		slog.Error("SMTP 配置有误", "reason", err)
+		os.Exit(1)
+	}
+	senders[model.NotificationChannelEmail] = sender
+	return senders
+}
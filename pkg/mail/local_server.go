@@ -0,0 +1,168 @@
+package mail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"log/slog"
+	"mime"
+	"net"
+	netmail "net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+)
+
+// maxLocalMessageSize 本地服务器接收的单封邮件大小上限
+const maxLocalMessageSize = 10 << 20
+
+// Message 本地服务器收到的一封邮件，Subject 和 Body 为解码后的内容 (无法解析时为空)
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	Body    string
+	Raw     []byte
+}
+
+// LocalServer 进程内的最简 SMTP 服务器，只支持明文、无认证的投递，收到的邮件交给 handler 处理
+// 用于本地开发时替代真实的 SMTP 服务器，也可以在集成测试中检查发出的邮件
+type LocalServer struct {
+	listener net.Listener
+	handler  func(Message)
+	wg       sync.WaitGroup
+}
+
+// StartLocalServer 在 addr (如 127.0.0.1:0) 上启动服务器
+func StartLocalServer(addr string, handler func(Message)) (*LocalServer, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &LocalServer{listener: listener, handler: handler}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Addr 服务器实际监听的地址
+func (s *LocalServer) Addr() *net.TCPAddr {
+	return s.listener.Addr().(*net.TCPAddr)
+}
+
+// Close 停止接受新连接，并等待进行中的会话结束
+func (s *LocalServer) Close() error {
+	err := s.listener.Close()
+	s.wg.Wait()
+	return err
+}
+
+func (s *LocalServer) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return // 服务器已关闭
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			if err := s.session(conn); err != nil && err != io.EOF {
+				slog.Warn("本地 SMTP 会话异常结束", "err", err)
+			}
+		}()
+	}
+}
+
+// session 处理一个 SMTP 会话
+func (s *LocalServer) session(conn net.Conn) error {
+	tc := textproto.NewConn(conn)
+	defer tc.Close()
+
+	if err := tc.PrintfLine("220 localhost ESMTP ready"); err != nil {
+		return err
+	}
+	var msg Message
+	for {
+		line, err := tc.ReadLine()
+		if err != nil {
+			return err
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			err = tc.PrintfLine("250-localhost\r\n250-8BITMIME\r\n250 SIZE %d", maxLocalMessageSize)
+		case "HELO":
+			err = tc.PrintfLine("250 localhost")
+		case "MAIL":
+			msg = Message{From: trimPath(arg, "FROM:")}
+			err = tc.PrintfLine("250 OK")
+		case "RCPT":
+			msg.To = append(msg.To, trimPath(arg, "TO:"))
+			err = tc.PrintfLine("250 OK")
+		case "DATA":
+			if len(msg.To) == 0 {
+				err = tc.PrintfLine("503 need RCPT before DATA")
+				break
+			}
+			if err = tc.PrintfLine("354 end data with <CR><LF>.<CR><LF>"); err != nil {
+				return err
+			}
+			dot := tc.DotReader()
+			raw, readErr := io.ReadAll(io.LimitReader(dot, maxLocalMessageSize))
+			if readErr != nil {
+				return readErr
+			}
+			if _, err := io.Copy(io.Discard, dot); err != nil { // 超出上限的部分丢弃
+				return err
+			}
+			msg.Raw = raw
+			msg.Subject, msg.Body = parseMessage(raw)
+			s.handler(msg)
+			msg = Message{}
+			err = tc.PrintfLine("250 OK")
+		case "RSET":
+			msg = Message{}
+			err = tc.PrintfLine("250 OK")
+		case "NOOP":
+			err = tc.PrintfLine("250 OK")
+		case "QUIT":
+			return tc.PrintfLine("221 bye")
+		default:
+			err = tc.PrintfLine("502 command not implemented")
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// trimPath 从 "FROM:<a@b.c> SIZE=1" 中取出邮箱地址
+func trimPath(arg, prefix string) string {
+	if len(arg) >= len(prefix) && strings.EqualFold(arg[:len(prefix)], prefix) {
+		arg = arg[len(prefix):]
+	}
+	arg, _, _ = strings.Cut(strings.TrimSpace(arg), " ")
+	return strings.Trim(arg, "<>")
+}
+
+// parseMessage 解码邮件主题和纯文本正文 (支持 base64 和不编码的正文)
+func parseMessage(raw []byte) (string, string) {
+	m, err := netmail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return "", ""
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	if err != nil {
+		subject = m.Header.Get("Subject")
+	}
+	var body io.Reader = m.Body
+	if strings.EqualFold(m.Header.Get("Content-Transfer-Encoding"), "base64") {
+		body = base64.NewDecoder(base64.StdEncoding, m.Body) // 解码时忽略换行
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return subject, ""
+	}
+	return subject, strings.ReplaceAll(string(data), "\r\n", "\n")
+}
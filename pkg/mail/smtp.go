@@ -0,0 +1,153 @@
+// Package mail 通过 SMTP 发送纯文本邮件，并提供一个进程内的 SMTP 服务器替身 (见 local_server.go)
+package mail
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTPConfig SMTP 服务器配置
+type SMTPConfig struct {
+	Host        string
+	Port        int
+	Username    string // 为空时不认证
+	Password    string
+	From        string // 发件人，如 "活动报名系统 <noreply@example.com>"
+	ImplicitTLS bool   // 为 true 时连接建立即使用 TLS (通常为 465 端口)，否则在服务器支持时使用 STARTTLS
+}
+
+// SMTPSender 每封邮件建立一次 SMTP 连接发送
+type SMTPSender struct {
+	cfg  SMTPConfig
+	from *netmail.Address
+}
+
+// NewSMTPSender 创建 SMTPSender，发件人地址格式错误时返回错误
+func NewSMTPSender(cfg SMTPConfig) (*SMTPSender, error) {
+	from, err := netmail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from address %q: %w", cfg.From, err)
+	}
+	return &SMTPSender{cfg: cfg, from: from}, nil
+}
+
+// Send 发送一封纯文本邮件，ctx 的截止时间同时作为连接的读写超时
+func (s *SMTPSender) Send(ctx context.Context, to, subject, body string) error {
+	rcpt, err := netmail.ParseAddress(to)
+	if err != nil {
+		return fmt.Errorf("invalid recipient %q: %w", to, err)
+	}
+	msg, err := buildMessage(s.from, rcpt, subject, body)
+	if err != nil {
+		return err
+	}
+
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if !s.cfg.ImplicitTLS {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err := c.StartTLS(&tls.Config{ServerName: s.cfg.Host}); err != nil {
+				return err
+			}
+		}
+	}
+	if s.cfg.Username != "" {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("smtp server does not support AUTH")
+		}
+		if err := c.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(s.from.Address); err != nil {
+		return err
+	}
+	if err := c.Rcpt(rcpt.Address); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// dial 建立 TCP 连接 (ImplicitTLS 时为 TLS 连接)
+func (s *SMTPSender) dial(ctx context.Context) (net.Conn, error) {
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+	if s.cfg.ImplicitTLS {
+		dialer := &tls.Dialer{Config: &tls.Config{ServerName: s.cfg.Host}}
+		return dialer.DialContext(ctx, "tcp", addr)
+	}
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, "tcp", addr)
+}
+
+// buildMessage 生成邮件内容：主题按 RFC 2047 编码，正文为 UTF-8 纯文本并以 base64 传输
+func buildMessage(from, to *netmail.Address, subject, body string) ([]byte, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	domain := "localhost"
+	if at := strings.LastIndex(from.Address, "@"); at >= 0 {
+		domain = from.Address[at+1:]
+	}
+
+	var b strings.Builder
+	headers := [][2]string{
+		{"From", from.String()},
+		{"To", to.String()},
+		{"Subject", mime.BEncoding.Encode("UTF-8", subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", "<" + hex.EncodeToString(id) + "@" + domain + ">"},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "text/plain; charset=UTF-8"},
+		{"Content-Transfer-Encoding", "base64"},
+	}
+	for _, h := range headers {
+		b.WriteString(h[0] + ": " + h[1] + "\r\n")
+	}
+	b.WriteString("\r\n")
+
+	// base64 正文每行不超过 76 个字符
+	encoded := base64.StdEncoding.EncodeToString([]byte(strings.ReplaceAll(body, "\n", "\r\n")))
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded + "\r\n")
+	return []byte(b.String()), nil
+}
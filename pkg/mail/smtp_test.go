@@ -0,0 +1,74 @@
+package mail
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startTestServer 启动本地 SMTP 服务器，返回收件通道和指向它的 SMTPSender
+func startTestServer(t *testing.T) (<-chan Message, *SMTPSender) {
+	t.Helper()
+	received := make(chan Message, 10)
+	srv, err := StartLocalServer("127.0.0.1:0", func(m Message) { received <- m })
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Close() })
+
+	sender, err := NewSMTPSender(SMTPConfig{
+		Host: "127.0.0.1",
+		Port: srv.Addr().Port,
+		From: "活动报名系统 <noreply@example.com>",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return received, sender
+}
+
+func TestSMTPSenderLocalServer(t *testing.T) {
+	received, sender := startTestServer(t)
+
+	subject := "「程序设计讲座」报名已提交"
+	// 正文超过一行 base64 (76 个字符)，并包含多行
+	body := "张三，您好：\n\n" + strings.Repeat("您已报名「程序设计讲座」。", 10) + "\n活动地点：A101\n"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := sender.Send(ctx, "张三 <zhangsan@example.com>", subject, body); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case m := <-received:
+		if m.From != "noreply@example.com" {
+			t.Errorf("From = %q", m.From)
+		}
+		if len(m.To) != 1 || m.To[0] != "zhangsan@example.com" {
+			t.Errorf("To = %q", m.To)
+		}
+		if m.Subject != subject {
+			t.Errorf("Subject = %q, want %q", m.Subject, subject)
+		}
+		if m.Body != body {
+			t.Errorf("Body = %q, want %q", m.Body, body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("local server received no message")
+	}
+}
+
+func TestSMTPSenderInvalidRecipient(t *testing.T) {
+	received, sender := startTestServer(t)
+
+	if err := sender.Send(context.Background(), "not an address", "subject", "body"); err == nil {
+		t.Fatal("Send to invalid recipient succeeded")
+	}
+	select {
+	case m := <-received:
+		t.Fatalf("local server received %+v", m)
+	default:
+	}
+}
@@ -0,0 +1,148 @@
+// Package pdf 生成简单的 PDF 文档 (文字、线条、矩形)，纯 Go 实现，不依赖外部字体文件
+//
+// 中文使用 PDF 阅读器内置的 Adobe-GB1 字体 STSong-Light (不嵌入字体)，
+// 文本以 UTF-16BE 编码写入 (UniGB-UTF16-H)，主流阅读器均可正常显示
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"unicode/utf16"
+)
+
+// 常用纸张尺寸 (单位：pt，1pt = 1/72 英寸)
+const (
+	A4Width  = 595.28
+	A4Height = 841.89
+)
+
+// Document PDF 文档，按顺序添加页面后调用 WriteTo 输出
+type Document struct {
+	pages []*Page
+}
+
+// Page 一个页面，坐标原点在左下角
+type Page struct {
+	Width, Height float64
+	content       bytes.Buffer
+}
+
+// New 创建空文档
+func New() *Document {
+	return &Document{}
+}
+
+// AddPage 添加指定尺寸的页面
+func (d *Document) AddPage(width, height float64) *Page {
+	p := &Page{Width: width, Height: height}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+// TextWidth 计算文本在指定字号下的宽度：ASCII 字符为半角，其余为全角
+func TextWidth(text string, size float64) float64 {
+	var units float64
+	for _, r := range text {
+		if r < 0x80 {
+			units += 500
+		} else {
+			units += 1000
+		}
+	}
+	return units * size / 1000
+}
+
+// SetColor 设置后续文字和线条的颜色 (RGB 分量取值 0-1)
+func (p *Page) SetColor(r, g, b float64) {
+	fmt.Fprintf(&p.content, "%.3f %.3f %.3f rg %.3f %.3f %.3f RG\n", r, g, b, r, g, b)
+}
+
+// Text 以 (x, y) 为基线起点绘制一行文本
+func (p *Page) Text(x, y, size float64, text string) {
+	fmt.Fprintf(&p.content, "BT /F1 %.2f Tf %.2f %.2f Td <%s> Tj ET\n", size, x, y, encodeText(text))
+}
+
+// TextCenter 以 y 为基线绘制水平居中的一行文本
+func (p *Page) TextCenter(y, size float64, text string) {
+	p.Text((p.Width-TextWidth(text, size))/2, y, size, text)
+}
+
+// Line 绘制线段
+func (p *Page) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "%.2f w %.2f %.2f m %.2f %.2f l S\n", width, x1, y1, x2, y2)
+}
+
+// Rect 绘制矩形边框，(x, y) 为左下角
+func (p *Page) Rect(x, y, w, h, width float64) {
+	fmt.Fprintf(&p.content, "%.2f w %.2f %.2f %.2f %.2f re S\n", width, x, y, w, h)
+}
+
+// encodeText 将文本编码为 UTF-16BE 十六进制串
+func encodeText(text string) string {
+	var buf bytes.Buffer
+	for _, u := range utf16.Encode([]rune(text)) {
+		fmt.Fprintf(&buf, "%04X", u)
+	}
+	return buf.String()
+}
+
+// WriteTo 输出完整的 PDF 文件
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	// 对象编号：1 目录，2 页面树，3-5 字体，之后每页依次为页面对象和内容流
+	var objects [][]byte
+	addObject := func(body string) {
+		objects = append(objects, []byte(body))
+	}
+
+	kids := bytes.Buffer{}
+	for i := range d.pages {
+		fmt.Fprintf(&kids, "%d 0 R ", 6+2*i)
+	}
+	addObject("<< /Type /Catalog /Pages 2 0 R >>")
+	addObject(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", bytes.TrimSpace(kids.Bytes()), len(d.pages)))
+	addObject("<< /Type /Font /Subtype /Type0 /BaseFont /STSong-Light /Encoding /UniGB-UTF16-H /DescendantFonts [4 0 R] >>")
+	addObject("<< /Type /Font /Subtype /CIDFontType0 /BaseFont /STSong-Light " +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (GB1) /Supplement 4 >> " +
+		"/FontDescriptor 5 0 R /DW 1000 /W [1 95 500 814 939 500] >>")
+	addObject("<< /Type /FontDescriptor /FontName /STSong-Light /Flags 6 /FontBBox [-25 -254 1000 880] " +
+		"/ItalicAngle 0 /Ascent 880 /Descent -120 /CapHeight 880 /StemV 93 >>")
+
+	for i, p := range d.pages {
+		addObject(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			p.Width, p.Height, 7+2*i))
+
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		if _, err := zw.Write(p.content.Bytes()); err != nil {
+			return 0, err
+		}
+		if err := zw.Close(); err != nil {
+			return 0, err
+		}
+		stream := fmt.Appendf(nil, "<< /Length %d /Filter /FlateDecode >>\nstream\n", compressed.Len())
+		stream = append(stream, compressed.Bytes()...)
+		stream = append(stream, "\nendstream"...)
+		objects = append(objects, stream)
+	}
+
+	// 按顺序写出对象并记录偏移量，最后写交叉引用表
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n", i+1)
+		out.Write(obj)
+		out.WriteString("\nendobj\n")
+	}
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	return out.WriteTo(w)
+}
@@ -0,0 +1,127 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 管理员登录会话相关的 Redis 键
+// 一次登录产生一个令牌家族 (family)，家族内的刷新令牌每次使用后轮换；
+// 家族键存在即表示该会话有效，删除家族键即可吊销该会话签发的全部令牌
+const (
+	refreshTokenKeyPrefix   = "auth:refresh:"        // 刷新令牌哈希 -> 家族ID
+	usedRefreshKeyPrefix    = "auth:refresh_used:"   // 已轮换的刷新令牌哈希 -> 家族ID，用于重用检测
+	tokenFamilyKeyPrefix    = "auth:family:"         // 家族ID -> 管理员ID
+	adminFamiliesKeyPrefix  = "auth:admin_families:" // 管理员ID -> 家族ID集合
+	deniedAccessTokenPrefix = "auth:jti_denied:"     // 已注销的访问令牌 jti
+)
+
+var (
+	// ErrRefreshTokenNotFound 刷新令牌不存在或已过期
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	// ErrRefreshTokenReused 已轮换过的刷新令牌被再次使用
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+)
+
+// CreateTokenFamily 登录时创建令牌家族并保存首个刷新令牌
+func CreateTokenFamily(ctx context.Context, adminID uint, familyID, refreshHash string, ttl time.Duration) error {
+	pipe := Client.TxPipeline()
+	pipe.Set(ctx, tokenFamilyKeyPrefix+familyID, adminID, ttl)
+	pipe.Set(ctx, refreshTokenKeyPrefix+refreshHash, familyID, ttl)
+	setKey := adminFamiliesKeyPrefix + strconv.FormatUint(uint64(adminID), 10)
+	pipe.SAdd(ctx, setKey, familyID)
+	pipe.Expire(ctx, setKey, ttl)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// RotateRefreshToken 消费旧刷新令牌并写入新刷新令牌，返回所属家族ID和管理员ID
+// 旧令牌被原子取出后记为已使用；再次提交已使用的令牌视为泄露，整个家族被吊销
+func RotateRefreshToken(ctx context.Context, oldHash, newHash string, ttl time.Duration) (string, uint, error) {
+	familyID, err := Client.GetDel(ctx, refreshTokenKeyPrefix+oldHash).Result()
+	if errors.Is(err, redis.Nil) {
+		// 令牌已被轮换过：重用检测
+		usedFamilyID, usedErr := Client.Get(ctx, usedRefreshKeyPrefix+oldHash).Result()
+		if usedErr == nil {
+			if err := RevokeTokenFamily(ctx, usedFamilyID); err != nil {
+				return "", 0, err
+			}
+			return "", 0, ErrRefreshTokenReused
+		}
+		if !errors.Is(usedErr, redis.Nil) {
+			return "", 0, usedErr
+		}
+		return "", 0, ErrRefreshTokenNotFound
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	// 家族已被吊销 (注销、停用等)
+	adminIDStr, err := Client.Get(ctx, tokenFamilyKeyPrefix+familyID).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", 0, ErrRefreshTokenNotFound
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	adminID, err := strconv.ParseUint(adminIDStr, 10, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid admin id in token family: %w", err)
+	}
+
+	pipe := Client.TxPipeline()
+	pipe.Set(ctx, usedRefreshKeyPrefix+oldHash, familyID, ttl)
+	pipe.Set(ctx, refreshTokenKeyPrefix+newHash, familyID, ttl)
+	pipe.Expire(ctx, tokenFamilyKeyPrefix+familyID, ttl)
+	pipe.Expire(ctx, adminFamiliesKeyPrefix+adminIDStr, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", 0, err
+	}
+	return familyID, uint(adminID), nil
+}
+
+// RevokeTokenFamily 吊销令牌家族，该会话下的访问令牌和刷新令牌全部失效
+func RevokeTokenFamily(ctx context.Context, familyID string) error {
+	return Client.Del(ctx, tokenFamilyKeyPrefix+familyID).Err()
+}
+
+// RevokeAdminTokens 吊销管理员的全部会话 (停用、重置密码、删除账号时使用)
+func RevokeAdminTokens(ctx context.Context, adminID uint) error {
+	setKey := adminFamiliesKeyPrefix + strconv.FormatUint(uint64(adminID), 10)
+	familyIDs, err := Client.SMembers(ctx, setKey).Result()
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(familyIDs)+1)
+	for _, familyID := range familyIDs {
+		keys = append(keys, tokenFamilyKeyPrefix+familyID)
+	}
+	keys = append(keys, setKey)
+	return Client.Del(ctx, keys...).Err()
+}
+
+// DenyAccessToken 将访问令牌 jti 加入黑名单，保留到令牌自然过期为止
+func DenyAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	return Client.Set(ctx, deniedAccessTokenPrefix+jti, 1, ttl).Err()
+}
+
+// IsAccessTokenRevoked 检查访问令牌是否已被注销或所属家族已被吊销
+func IsAccessTokenRevoked(ctx context.Context, jti, familyID string) (bool, error) {
+	pipe := Client.Pipeline()
+	denied := pipe.Exists(ctx, deniedAccessTokenPrefix+jti)
+	family := pipe.Exists(ctx, tokenFamilyKeyPrefix+familyID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, err
+	}
+	return denied.Val() > 0 || family.Val() == 0, nil
+}
@@ -0,0 +1,68 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 幂等键相关的 Redis 键：幂等键 -> 请求指纹和首次响应
+const idempotencyKeyPrefix = "idem:"
+
+// IdempotentRecord 幂等键对应的记录
+// Completed 为 false 表示首次请求仍在处理中，此时只有 Fingerprint 有效
+type IdempotentRecord struct {
+	Fingerprint string `json:"fingerprint"`
+	Completed   bool   `json:"completed"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// BeginIdempotentRequest 尝试占用幂等键，处理中的占位记录只保留 lease 时长 (进程在处理期间退出时自动释放)
+// 占用成功返回 (nil, nil)；键已存在时返回已有记录，由调用方判断是重放、指纹不符还是仍在处理中
+func BeginIdempotentRequest(ctx context.Context, key, fingerprint string, lease time.Duration) (*IdempotentRecord, error) {
+	data, err := json.Marshal(IdempotentRecord{Fingerprint: fingerprint})
+	if err != nil {
+		return nil, err
+	}
+	ok, err := Client.SetNX(ctx, idempotencyKeyPrefix+key, data, lease).Result()
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return nil, nil
+	}
+
+	raw, err := Client.Get(ctx, idempotencyKeyPrefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		// 刚好过期，重新占用
+		return BeginIdempotentRequest(ctx, key, fingerprint, lease)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var record IdempotentRecord
+	if err := json.Unmarshal(raw, &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// CompleteIdempotentRequest 保存首次请求的响应并将保留时长延长为 ttl，窗口期内相同请求直接重放
+func CompleteIdempotentRequest(ctx context.Context, key string, record *IdempotentRecord, ttl time.Duration) error {
+	record.Completed = true
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return Client.Set(ctx, idempotencyKeyPrefix+key, data, ttl).Err()
+}
+
+// AbortIdempotentRequest 首次请求未得到可重放的响应 (如服务端错误) 时释放幂等键，允许客户端重试
+func AbortIdempotentRequest(ctx context.Context, key string) error {
+	return Client.Del(ctx, idempotencyKeyPrefix+key).Err()
+}
@@ -0,0 +1,71 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 登录防爆破相关的 Redis 键，subject 形如 "user:<username>"、"user_ip:<username>|<ip>" 或 "ip:<ip>"；
+// 签到动态码的错误计数也使用这些键，subject 形如 "attend:<activity_id>|<phone>" 或 "attend_ip:<ip>"
+const (
+	loginFailureKeyPrefix = "auth:login_fail:" // 连续失败次数
+	loginLockKeyPrefix    = "auth:login_lock:" // 临时锁定标记
+)
+
+// LoginLockRemaining 返回多个主体中剩余锁定时间的最大值，未锁定时返回 0
+func LoginLockRemaining(ctx context.Context, subjects ...string) (time.Duration, error) {
+	pipe := Client.Pipeline()
+	cmds := make([]*redis.DurationCmd, len(subjects))
+	for i, subject := range subjects {
+		cmds[i] = pipe.PTTL(ctx, loginLockKeyPrefix+subject)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return 0, err
+	}
+
+	var remaining time.Duration
+	for _, cmd := range cmds {
+		// 键不存在时 PTTL 返回负值
+		if ttl := cmd.Val(); ttl > remaining {
+			remaining = ttl
+		}
+	}
+	return remaining, nil
+}
+
+// RecordLoginFailure 累加主体的失败次数并返回累加后的值，计数在 window 内没有新失败时自动清零
+func RecordLoginFailure(ctx context.Context, subject string, window time.Duration) (int64, error) {
+	pipe := Client.TxPipeline()
+	incr := pipe.Incr(ctx, loginFailureKeyPrefix+subject)
+	pipe.Expire(ctx, loginFailureKeyPrefix+subject, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+// LockLogin 临时锁定主体的登录
+func LockLogin(ctx context.Context, subject string, duration time.Duration) error {
+	return Client.Set(ctx, loginLockKeyPrefix+subject, 1, duration).Err()
+}
+
+// ClearLoginFailures 登录成功后清除主体的失败计数
+func ClearLoginFailures(ctx context.Context, subjects ...string) error {
+	keys := make([]string, len(subjects))
+	for i, subject := range subjects {
+		keys[i] = loginFailureKeyPrefix + subject
+	}
+	return Client.Del(ctx, keys...).Err()
+}
+
+// LoginFailures 返回主体当前的失败次数，没有失败记录时返回 0
+func LoginFailures(ctx context.Context, subject string) (int64, error) {
+	failures, err := Client.Get(ctx, loginFailureKeyPrefix+subject).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return failures, err
+}
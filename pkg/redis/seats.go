@@ -0,0 +1,343 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 快速报名相关的 Redis 键
+// 每个开启快速报名的活动有一组名额键：剩余名额、已报名手机号集合、待落库数量和预占序号；
+// 所有活动的预占结果进入同一个落库队列，由后台任务批量写入 MySQL
+const (
+	seatsKeyPrefix       = "reg:seats:"   // 活动ID -> 剩余名额
+	seatPhonesKeyPrefix  = "reg:phones:"  // 活动ID -> 已报名 (含待落库) 的手机号集合
+	seatPendingKeyPrefix = "reg:pending:" // 活动ID -> 已预占但尚未落库的数量
+	seatSeqKeyPrefix     = "reg:seq:"     // 活动ID -> 预占序号，每次预占加一，用于校对时检测并发
+	SeatQueueKey         = "reg:queue"    // 待落库队列
+
+	// 每个落库实例有自己的处理中列表，并通过租约表明仍在运行；租约过期的实例的处理中列表由其他实例放回队列
+	seatProcessingKeyPrefix  = "reg:queue:processing:" // 实例ID -> 该实例取出但尚未落库的记录
+	seatWorkerLeaseKeyPrefix = "reg:queue:lease:"      // 实例ID -> 租约
+	seatWorkersKey           = "reg:queue:workers"     // 已注册的实例ID集合
+	legacySeatProcessingKey  = "reg:queue:processing"  // 升级前所有实例共用的处理中列表
+
+	// UnlimitedSeats 不限人数的活动使用的剩余名额值
+	UnlimitedSeats = 1 << 40
+)
+
+// 名额预占结果
+const (
+	SeatReserved   = 1  // 预占成功
+	SeatDuplicate  = -1 // 手机号已报名
+	SeatSoldOut    = -2 // 名额已满
+	SeatNotLoaded  = -3 // 名额未加载到 Redis
+	seatResyncBusy = 0  // 校对时存在未落库或并发预占，本次跳过
+)
+
+// ErrSeatsBusy 校对名额时有预占尚未落库，稍后重试
+var ErrSeatsBusy = errors.New("seats have pending reservations")
+
+// reserveSeatScript 原子地校验手机号、扣减剩余名额并写入落库队列
+// KEYS: seats, phones, pending, seq, queue; ARGV: phone, payload
+var reserveSeatScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return -3
+end
+if redis.call('SISMEMBER', KEYS[2], ARGV[1]) == 1 then
+	return -1
+end
+if tonumber(redis.call('GET', KEYS[1])) <= 0 then
+	return -2
+end
+redis.call('DECR', KEYS[1])
+redis.call('SADD', KEYS[2], ARGV[1])
+redis.call('INCR', KEYS[3])
+redis.call('INCR', KEYS[4])
+redis.call('RPUSH', KEYS[5], ARGV[2])
+return 1
+`)
+
+// resyncSeatsScript 用数据库中的数据重置名额键
+// 只有在没有待落库记录、且读取数据库期间名额没有任何变化 (序号未变) 时才会写入；
+// 队列和所有实例的处理中列表都为空时，残留的待落库数量视为漂移直接清零
+// KEYS: seats, phones, pending, seq, queue, workers; ARGV: seq, remaining, ttl(秒), processing 前缀, phones...
+var resyncSeatsScript = redis.NewScript(`
+if tonumber(redis.call('GET', KEYS[3]) or '0') > 0 then
+	if redis.call('LLEN', KEYS[5]) > 0 then
+		return 0
+	end
+	for _, worker in ipairs(redis.call('SMEMBERS', KEYS[6])) do
+		if redis.call('LLEN', ARGV[4] .. worker) > 0 then
+			return 0
+		end
+	end
+	redis.call('DEL', KEYS[3])
+end
+if (redis.call('GET', KEYS[4]) or '0') ~= ARGV[1] then
+	return 0
+end
+redis.call('DEL', KEYS[2])
+redis.call('SET', KEYS[1], ARGV[2], 'EX', ARGV[3])
+for i = 5, #ARGV do
+	redis.call('SADD', KEYS[2], ARGV[i])
+end
+redis.call('EXPIRE', KEYS[2], ARGV[3])
+redis.call('SET', KEYS[4], ARGV[1], 'EX', ARGV[3])
+return 1
+`)
+
+// adjustSeatsScript 名额键存在时调整剩余名额并增删手机号，未加载时不做任何事
+// KEYS: seats, phones, seq; ARGV: delta, op (add/rem/空), phone
+var adjustSeatsScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+redis.call('INCRBY', KEYS[1], ARGV[1])
+if ARGV[2] == 'add' then
+	redis.call('SADD', KEYS[2], ARGV[3])
+elseif ARGV[2] == 'rem' then
+	redis.call('SREM', KEYS[2], ARGV[3])
+end
+redis.call('INCR', KEYS[3])
+return 1
+`)
+
+func seatKeys(activityID uint) (seats, phones, pending, seq string) {
+	id := strconv.FormatUint(uint64(activityID), 10)
+	return seatsKeyPrefix + id, seatPhonesKeyPrefix + id, seatPendingKeyPrefix + id, seatSeqKeyPrefix + id
+}
+
+// ReserveSeat 为手机号预占一个名额，成功时 payload 进入待落库队列
+// 返回值为 SeatReserved、SeatDuplicate、SeatSoldOut 或 SeatNotLoaded
+func ReserveSeat(ctx context.Context, activityID uint, phone, payload string) (int, error) {
+	seats, phones, pending, seq := seatKeys(activityID)
+	res, err := reserveSeatScript.Run(ctx, Client, []string{seats, phones, pending, seq, SeatQueueKey}, phone, payload).Int()
+	if err != nil {
+		return 0, err
+	}
+	return res, nil
+}
+
+// SeatSequence 读取活动的预占序号，校对名额前调用
+func SeatSequence(ctx context.Context, activityID uint) (string, error) {
+	_, _, _, seq := seatKeys(activityID)
+	val, err := Client.Get(ctx, seq).Result()
+	if errors.Is(err, redis.Nil) {
+		return "0", nil
+	}
+	return val, err
+}
+
+// PendingSeats 返回活动已预占但尚未落库的数量
+func PendingSeats(ctx context.Context, activityID uint) (int, error) {
+	_, _, pending, _ := seatKeys(activityID)
+	n, err := Client.Get(ctx, pending).Int()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return n, err
+}
+
+// ResyncSeats 用数据库中的剩余名额和手机号重置活动的名额键
+// seq 为读取数据库之前通过 SeatSequence 取得的序号；期间有新的预占或仍有待落库记录时返回 ErrSeatsBusy
+func ResyncSeats(ctx context.Context, activityID uint, seq string, remaining int, phoneList []string, ttl time.Duration) error {
+	seats, phones, pending, seqKey := seatKeys(activityID)
+	args := make([]any, 0, len(phoneList)+4)
+	args = append(args, seq, remaining, max(int64(ttl/time.Second), 1), seatProcessingKeyPrefix)
+	for _, phone := range phoneList {
+		args = append(args, phone)
+	}
+	res, err := resyncSeatsScript.Run(ctx, Client, []string{seats, phones, pending, seqKey, SeatQueueKey, seatWorkersKey}, args...).Int()
+	if err != nil {
+		return err
+	}
+	if res == seatResyncBusy {
+		return ErrSeatsBusy
+	}
+	return nil
+}
+
+// OccupySeat 走数据库通道报名后同步到已加载的名额键：扣减 seats 个名额并记录手机号
+func OccupySeat(ctx context.Context, activityID uint, phone string, seats int) error {
+	return adjustSeats(ctx, activityID, -seats, "add", phone)
+}
+
+// ReleaseSeat 取消报名后同步到已加载的名额键：归还 seats 个名额并移除手机号，允许其重新报名
+func ReleaseSeat(ctx context.Context, activityID uint, phone string, seats int) error {
+	return adjustSeats(ctx, activityID, seats, "rem", phone)
+}
+
+// AdjustSeats 调整已加载活动的剩余名额 (修改人数上限等)
+func AdjustSeats(ctx context.Context, activityID uint, delta int) error {
+	return adjustSeats(ctx, activityID, delta, "", "")
+}
+
+func adjustSeats(ctx context.Context, activityID uint, delta int, op, phone string) error {
+	seats, phones, _, seq := seatKeys(activityID)
+	return adjustSeatsScript.Run(ctx, Client, []string{seats, phones, seq}, delta, op, phone).Err()
+}
+
+// ClearSeats 删除活动的名额键 (活动截止、结束或取消后不再需要)，待落库数量保留给落库任务扣减
+func ClearSeats(ctx context.Context, activityID uint) error {
+	seats, phones, _, seq := seatKeys(activityID)
+	return Client.Del(ctx, seats, phones, seq).Err()
+}
+
+// ----- 落库队列 -----
+// 队列项取出时先移入当前实例的处理中列表，落库成功后再删除；
+// 实例异常退出后租约过期，其处理中列表由 RequeueExpiredSeatWorkers 放回队列
+
+// finishSeatsScript 从处理中列表删除记录，按实际删除的数量扣减待落库数量
+// 记录已被当作过期实例的记录放回队列时不会重复扣减 (由再次取出它的实例扣减)
+// KEYS: processing, pending, seq; ARGV: items...
+var finishSeatsScript = redis.NewScript(`
+local removed = 0
+for i = 1, #ARGV do
+	removed = removed + redis.call('LREM', KEYS[1], 1, ARGV[i])
+end
+if removed > 0 then
+	redis.call('DECRBY', KEYS[2], removed)
+	redis.call('INCR', KEYS[3])
+end
+return removed
+`)
+
+// retrySeatsScript 将仍在处理中列表的记录放回队列末尾 (已被放回的记录不会重复入队)
+// KEYS: processing, queue; ARGV: items...
+var retrySeatsScript = redis.NewScript(`
+for i = 1, #ARGV do
+	if redis.call('LREM', KEYS[1], 1, ARGV[i]) > 0 then
+		redis.call('RPUSH', KEYS[2], ARGV[i])
+	end
+end
+return 0
+`)
+
+// renewSeatWorkerScript 续期实例的租约，租约已过期时返回 0 并重新注册
+// KEYS: lease, workers; ARGV: worker, lease(毫秒)
+var renewSeatWorkerScript = redis.NewScript(`
+if redis.call('SET', KEYS[1], 1, 'PX', ARGV[2], 'XX') then
+	return 1
+end
+redis.call('SET', KEYS[1], 1, 'PX', ARGV[2])
+redis.call('SADD', KEYS[2], ARGV[1])
+return 0
+`)
+
+func seatProcessingKey(worker string) string { return seatProcessingKeyPrefix + worker }
+
+// RegisterSeatWorker 注册落库实例并设置租约
+func RegisterSeatWorker(ctx context.Context, worker string, lease time.Duration) error {
+	pipe := Client.TxPipeline()
+	pipe.Set(ctx, seatWorkerLeaseKeyPrefix+worker, 1, lease)
+	pipe.SAdd(ctx, seatWorkersKey, worker)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// RenewSeatWorker 续期实例的租约；返回 false 表示租约曾过期 (处理中的记录可能已被其他实例放回队列)，此时已重新注册
+func RenewSeatWorker(ctx context.Context, worker string, lease time.Duration) (bool, error) {
+	res, err := renewSeatWorkerScript.Run(ctx, Client, []string{seatWorkerLeaseKeyPrefix + worker, seatWorkersKey}, worker, lease.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return res == 1, nil
+}
+
+// TakeSeatReservations 阻塞等待最多 timeout，取出最多 n 条待落库的预占记录到 worker 的处理中列表
+func TakeSeatReservations(ctx context.Context, worker string, n int, timeout time.Duration) ([]string, error) {
+	processing := seatProcessingKey(worker)
+	first, err := Client.BLMove(ctx, SeatQueueKey, processing, "LEFT", "RIGHT", timeout).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	items := []string{first}
+	for len(items) < n {
+		item, err := Client.LMove(ctx, SeatQueueKey, processing, "LEFT", "RIGHT").Result()
+		if errors.Is(err, redis.Nil) {
+			break
+		}
+		if err != nil {
+			return items, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// FinishSeatReservations 一批记录落库后，从 worker 的处理中列表删除并扣减待落库数量
+func FinishSeatReservations(ctx context.Context, worker string, activityID uint, items []string) error {
+	_, _, pending, seq := seatKeys(activityID)
+	return finishSeatsScript.Run(ctx, Client, []string{seatProcessingKey(worker), pending, seq}, stringArgs(items)...).Err()
+}
+
+// DiscardSeatReservation 从 worker 的处理中列表删除无法处理的记录
+func DiscardSeatReservation(ctx context.Context, worker, item string) error {
+	return Client.LRem(ctx, seatProcessingKey(worker), 1, item).Err()
+}
+
+// RetrySeatReservations 落库失败后将记录放回队列末尾
+func RetrySeatReservations(ctx context.Context, worker string, items []string) error {
+	return retrySeatsScript.Run(ctx, Client, []string{seatProcessingKey(worker), SeatQueueKey}, stringArgs(items)...).Err()
+}
+
+// RequeueExpiredSeatWorkers 把租约已过期的实例的处理中记录放回队列头部，并注销这些实例
+// 每条记录通过 LMOVE 原子移动，多个实例同时执行也不会重复放回；升级前共用的处理中列表一并放回
+func RequeueExpiredSeatWorkers(ctx context.Context) (int, error) {
+	n, err := requeueProcessing(ctx, legacySeatProcessingKey)
+	if err != nil {
+		return n, err
+	}
+	workers, err := Client.SMembers(ctx, seatWorkersKey).Result()
+	if err != nil {
+		return n, err
+	}
+	for _, worker := range workers {
+		alive, err := Client.Exists(ctx, seatWorkerLeaseKeyPrefix+worker).Result()
+		if err != nil {
+			return n, err
+		}
+		if alive > 0 {
+			continue
+		}
+		moved, err := requeueProcessing(ctx, seatProcessingKey(worker))
+		n += moved
+		if err != nil {
+			return n, err
+		}
+		if err := Client.SRem(ctx, seatWorkersKey, worker).Err(); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// requeueProcessing 把处理中列表的记录逐条移回队列头部 (保持原有顺序)
+func requeueProcessing(ctx context.Context, processing string) (int, error) {
+	var n int
+	for {
+		_, err := Client.LMove(ctx, processing, SeatQueueKey, "RIGHT", "LEFT").Result()
+		if errors.Is(err, redis.Nil) {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+}
+
+func stringArgs(items []string) []any {
+	args := make([]any, len(items))
+	for i, item := range items {
+		args[i] = item
+	}
+	return args
+}
@@ -41,6 +41,12 @@ func GenerateGenericJWT(data map[string]any, expiresIn time.Duration) (string, e
 		return "", errors.New("expiresIn must be a positive duration")
 	}
 
+	// 每个令牌带唯一 jti，用于注销时加入黑名单
+	jti, err := GenerateRandomToken(16)
+	if err != nil {
+		return "", err
+	}
+
 	// 1. 创建 Claims
 	claims := MapClaims{
 		Data: data,
@@ -49,6 +55,7 @@ func GenerateGenericJWT(data map[string]any, expiresIn time.Duration) (string, e
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 			Issuer:    jwtIssuer,
+			ID:        jti,
 		},
 	}
 
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// TOTPDigits 动态码位数
+const TOTPDigits = 6
+
+// TOTPCounter 计算时间 t 所在的时间窗口序号 (RFC 6238)
+func TOTPCounter(t time.Time, period time.Duration) uint64 {
+	return uint64(t.Unix() / int64(period/time.Second))
+}
+
+// GenerateTOTP 根据密钥和时间窗口序号生成动态码 (RFC 4226 HOTP 截断算法)
+func GenerateTOTP(secret []byte, counter uint64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// 动态截断：取最后一个字节的低4位作为偏移量
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < TOTPDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", TOTPDigits, code%mod)
+}
+
+// ValidateTOTP 校验动态码，允许前后 skew 个时间窗口的时钟偏差
+func ValidateTOTP(secret []byte, code string, t time.Time, period time.Duration, skew int) bool {
+	if len(secret) == 0 || len(code) != TOTPDigits {
+		return false
+	}
+	counter := int64(TOTPCounter(t, period))
+	for i := -skew; i <= skew; i++ {
+		c := counter + int64(i)
+		if c < 0 {
+			continue
+		}
+		expected := GenerateTOTP(secret, uint64(c))
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return true
+		}
+	}
+	return false
+}